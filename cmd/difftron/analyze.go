@@ -1,11 +1,12 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
+	"sort"
 
 	"github.com/spf13/cobra"
 	"github.com/swantron/difftron/internal/analyzer"
@@ -16,13 +17,20 @@ import (
 
 var (
 	coverageFile      string
+	coverageFormat    string
 	diffFile          string
+	diffFormat        string
+	perPatch          bool
 	threshold         float64
 	thresholdNew      float64
 	thresholdModified float64
+	branchThreshold   float64
 	outputFormat      string
 	baseRef           string
 	headRef           string
+	oldTree           string
+	newTree           string
+	ignoreTrivial     bool
 )
 
 var analyzeCmd = &cobra.Command{
@@ -34,14 +42,21 @@ uncovered lines in your changes.`,
 }
 
 func init() {
-	analyzeCmd.Flags().StringVarP(&coverageFile, "coverage", "c", "", "Path to coverage file (LCOV format)")
-	analyzeCmd.Flags().StringVarP(&diffFile, "diff", "d", "", "Path to git diff file (optional, uses git diff if not provided)")
+	analyzeCmd.Flags().StringVarP(&coverageFile, "coverage", "c", "", "Path to coverage file or GOCOVERDIR (format is auto-detected); separate several Go profiles or gcov JSON files with commas to merge them")
+	analyzeCmd.Flags().StringVar(&coverageFormat, "coverage-format", "auto", coverageFormatUsage())
+	analyzeCmd.Flags().StringVarP(&diffFile, "diff", "d", "", "Path to a diff file, an mbox of patches or a directory of .patch files, or - for stdin (optional, reads piped stdin if --base and --head are not given, or else runs git diff)")
+	analyzeCmd.Flags().StringVar(&diffFormat, "diff-format", "auto", "Format of the diff read from --diff or stdin: auto, git, unified, hg, svn")
+	analyzeCmd.Flags().BoolVar(&perPatch, "per-patch", false, "Break the results down by the patch that introduced each line (requires an mbox or a directory of patches)")
 	analyzeCmd.Flags().Float64VarP(&threshold, "threshold", "t", 80.0, "Coverage threshold percentage (applies to both new and modified files)")
 	analyzeCmd.Flags().Float64Var(&thresholdNew, "threshold-new", 0, "Coverage threshold for new files (defaults to threshold if not set)")
 	analyzeCmd.Flags().Float64Var(&thresholdModified, "threshold-modified", 0, "Coverage threshold for modified files (defaults to threshold if not set)")
+	analyzeCmd.Flags().Float64Var(&branchThreshold, "branch-threshold", 0, "Branch coverage threshold for changed lines, if the coverage has branch data (0 disables the check)")
 	analyzeCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text, json, markdown")
 	analyzeCmd.Flags().StringVarP(&baseRef, "base", "b", "HEAD", "Base ref for git diff (default: HEAD)")
 	analyzeCmd.Flags().StringVarP(&headRef, "head", "", "HEAD", "Head ref for git diff (default: HEAD)")
+	analyzeCmd.Flags().StringVar(&oldTree, "old-tree", "", "Directory with the base sources, compared to --new-tree without git")
+	analyzeCmd.Flags().StringVar(&newTree, "new-tree", "", "Directory with the head sources, compared to --old-tree without git")
+	analyzeCmd.Flags().BoolVar(&ignoreTrivial, "ignore-trivial", false, "Leave blank, comment-only and (for Go) formatting-only changed lines out of the analysis")
 
 	rootCmd.AddCommand(analyzeCmd)
 }
@@ -52,11 +67,68 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("coverage file is required (use --coverage or -c)")
 	}
 
-	// Get git diff
-	var diffOutput string
-	var err error
+	parsedDiffFormat, err := hunk.ParseFormat(diffFormat)
+	if err != nil {
+		return err
+	}
+
+	if (oldTree == "") != (newTree == "") {
+		return fmt.Errorf("--old-tree and --new-tree must be used together")
+	}
+	if oldTree != "" && diffFile != "" {
+		return fmt.Errorf("--old-tree and --new-tree cannot be combined with --diff")
+	}
+
+	// Parse coverage, detecting its format unless --coverage-format names it
+	coverageReport, err := coverage.ParseCoverage(coveragePaths(coverageFile), coverageFormat)
+	if err != nil {
+		return fmt.Errorf("failed to read coverage: %w", err)
+	}
+
+	accumulator, err := analyzer.NewAccumulator(coverageReport, nil)
+	if err != nil {
+		return fmt.Errorf("failed to analyze: %w", err)
+	}
 
-	if diffFile != "" {
+	// The new versions of the files are in the new tree or the checkout
+	trivialRoot := newTree
+	if ignoreTrivial && trivialRoot == "" {
+		trivialRoot = repoRoot()
+	}
+	analyzeDiff := func(diffResult *hunk.ParseResult) {
+		if ignoreTrivial {
+			diffResult.ExcludeTrivialChanges(trivialRoot)
+		}
+		accumulator.Add(diffResult)
+	}
+
+	// Analyze the diff one file at a time as it is read from its source, so that
+	// large diffs are not held in memory. A patch series is composed into its
+	// net diff first.
+	var series *hunk.Series
+
+	switch {
+	case oldTree != "":
+		// Compare two checkouts with the built-in diff engine, e.g. in a sandbox without .git
+		diffResult, err := hunk.DiffTrees(oldTree, newTree)
+		if err != nil {
+			return fmt.Errorf("failed to diff trees: %w", err)
+		}
+		analyzeDiff(diffResult)
+	case diffFile == "-" || (diffFile == "" && !cmd.Flags().Changed("base") && !cmd.Flags().Changed("head") && isPipe(os.Stdin)):
+		// Read from stdin, e.g. git diff main...feature | difftron analyze
+		series, err = readDiffInput(os.Stdin, parsedDiffFormat, analyzeDiff)
+		if err != nil {
+			return fmt.Errorf("failed to parse diff from stdin: %w", err)
+		}
+	case isDir(diffFile):
+		// Read a directory of patches, e.g. from git format-patch -o
+		series, err = hunk.ParsePatchDir(diffFile, parsedDiffFormat)
+		if err != nil {
+			return fmt.Errorf("failed to parse patch series: %w", err)
+		}
+		analyzeDiff(series.Result)
+	case diffFile != "":
 		// Read from file
 		file, err := os.Open(diffFile)
 		if err != nil {
@@ -64,69 +136,28 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		}
 		defer file.Close()
 
-		content, err := io.ReadAll(file)
+		series, err = readDiffInput(file, parsedDiffFormat, analyzeDiff)
 		if err != nil {
-			return fmt.Errorf("failed to read diff file: %w", err)
+			return fmt.Errorf("failed to parse diff: %w", err)
 		}
-		diffOutput = string(content)
-	} else {
+	default:
 		// Get diff from git
-		diffOutput, err = getGitDiff(baseRef, headRef)
-		if err != nil {
+		if err := readGitDiff(baseRef, headRef, analyzeDiff); err != nil {
 			return fmt.Errorf("failed to get git diff: %w", err)
 		}
 	}
 
-	// Parse diff
-	diffResult, err := hunk.ParseGitDiff(diffOutput)
-	if err != nil {
-		return fmt.Errorf("failed to parse git diff: %w", err)
+	if perPatch && series == nil {
+		return fmt.Errorf("--per-patch requires an mbox or a directory of patches as --diff")
 	}
 
-	if !diffResult.HasChanges() {
+	analysisResult := accumulator.Result()
+	if len(analysisResult.FileResults) == 0 && len(analysisResult.SkippedFiles) == 0 && len(analysisResult.ExcludedLines) == 0 {
 		fmt.Println("No changes detected in diff.")
 		return nil
 	}
-
-	// Detect coverage format and parse
-	format, err := coverage.DetectCoverageFormat(coverageFile)
-	if err != nil {
-		return fmt.Errorf("failed to detect coverage format: %w", err)
-	}
-
-	var coverageReport *coverage.Report
-	switch format {
-	case "go":
-		// Parse Go's native coverage format
-		coverageReport, err = coverage.ParseGoCoverage(coverageFile)
-		if err != nil {
-			// Fallback to LCOV if Go parsing fails
-			fmt.Fprintf(os.Stderr, "Warning: Failed to parse as Go coverage, trying LCOV format: %v\n", err)
-			coverageReport, err = coverage.ParseLCOV(coverageFile)
-			if err != nil {
-				return fmt.Errorf("failed to parse coverage file (tried both Go and LCOV formats): %w", err)
-			}
-		}
-	case "cobertura":
-		// Parse Cobertura XML format
-		coverageReport, err = coverage.ParseCobertura(coverageFile)
-		if err != nil {
-			return fmt.Errorf("failed to parse Cobertura coverage file: %w", err)
-		}
-	case "lcov":
-		// Parse LCOV format
-		coverageReport, err = coverage.ParseLCOV(coverageFile)
-		if err != nil {
-			return fmt.Errorf("failed to parse LCOV coverage file: %w", err)
-		}
-	default:
-		return fmt.Errorf("unsupported coverage format: %s", format)
-	}
-
-	// Analyze
-	analysisResult, err := analyzer.Analyze(diffResult, coverageReport)
-	if err != nil {
-		return fmt.Errorf("failed to analyze: %w", err)
+	if perPatch {
+		analysisResult.Patches = analyzer.BreakdownByPatch(analysisResult, series)
 	}
 
 	// Set thresholds (use main threshold if specific ones not set)
@@ -152,29 +183,54 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 	}
 }
 
-func getGitDiff(base, head string) (string, error) {
-	// If base and head are the same, get diff of working directory
-	if base == head && base == "HEAD" {
-		cmd := exec.Command("git", "diff", "HEAD")
-		output, err := cmd.Output()
+// isPipe returns true if file is a pipe. Stdin redirected from a file or
+// /dev/null, as in many CI runners, is not read without --diff -.
+func isPipe(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeNamedPipe != 0
+}
+
+// isDir returns true if path names a directory
+func isDir(path string) bool {
+	if path == "" || path == "-" {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// readDiffInput parses a diff one file at a time, passing each file's changes
+// to visit, or composes a patch series if the input is an mbox and passes its
+// net diff. The series is nil for a plain diff.
+func readDiffInput(r io.Reader, format hunk.Format, visit func(*hunk.ParseResult)) (*hunk.Series, error) {
+	reader := bufio.NewReader(r)
+	if hunk.IsMbox(reader) {
+		series, err := hunk.ParseMbox(reader, format)
 		if err != nil {
-			// Try staged changes
-			cmd = exec.Command("git", "diff", "--cached")
-			output, err = cmd.Output()
-			if err != nil {
-				return "", fmt.Errorf("failed to get git diff: %w", err)
-			}
+			return nil, err
 		}
-		return string(output), nil
+		visit(series.Result)
+		return series, nil
 	}
 
-	// Get diff between two refs
-	cmd := exec.Command("git", "diff", base, head)
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("failed to get git diff: %w", err)
+	return nil, visitDiff(reader, format, visit)
+}
+
+// readGitDiff runs git diff between two refs, or of the working directory
+// against HEAD if both are HEAD, and passes the changes of each file to visit
+func readGitDiff(base, head string, visit func(*hunk.ParseResult)) error {
+	args := []string{"diff", base, head}
+	if base == head && base == "HEAD" {
+		args = []string{"diff", "HEAD"}
+		if err := newGitCommand("rev-parse", "--verify", "--quiet", "HEAD").Run(); err != nil {
+			// Without a commit there is no HEAD to compare against, so use the staged changes
+			args = []string{"diff", "--cached"}
+		}
 	}
-	return string(output), nil
+
+	return runGitDiff(func(r io.Reader) error {
+		return visitDiff(r, hunk.FormatGit, visit)
+	}, args...)
 }
 
 func outputText(result *analyzer.AnalysisResult, thresholdNew, thresholdModified float64) error {
@@ -184,6 +240,8 @@ func outputText(result *analyzer.AnalysisResult, thresholdNew, thresholdModified
 
 	if result.TotalChangedLines == 0 {
 		fmt.Println("No changed lines to analyze.")
+		printExcludedLines(result)
+		printSkippedFiles(result.SkippedFiles)
 		return nil
 	}
 
@@ -191,6 +249,15 @@ func outputText(result *analyzer.AnalysisResult, thresholdNew, thresholdModified
 		result.CoveragePercentage,
 		result.CoveredLines,
 		result.TotalChangedLines)
+	if result.PartiallyCoveredLines > 0 {
+		fmt.Printf("Line States: %d covered, %d partly covered, %d uncovered\n",
+			result.FullyCoveredLines(),
+			result.PartiallyCoveredLines,
+			result.UncoveredLines)
+	}
+	if result.IgnoredLines > 0 {
+		fmt.Printf("Ignored Lines: %d (excluded from coverage measurement)\n", result.IgnoredLines)
+	}
 	fmt.Println()
 
 	// Show new vs modified breakdown if available
@@ -210,8 +277,25 @@ func outputText(result *analyzer.AnalysisResult, thresholdNew, thresholdModified
 	}
 	fmt.Println()
 
+	if result.TotalBranches > 0 {
+		fmt.Printf("Branch Coverage: %.1f%% (%d/%d branches taken)\n",
+			result.BranchCoveragePercentage,
+			result.CoveredBranches,
+			result.TotalBranches)
+		fmt.Println()
+	}
+
+	if result.ChangedFunctions > 0 {
+		fmt.Printf("Changed Functions Never Executed: %d of %d\n", result.UnexecutedChangedFunctions, result.ChangedFunctions)
+		for _, function := range result.UnexecutedFunctions() {
+			fmt.Printf("  %s:%d %s\n", function.FilePath, function.StartLine, function.Name)
+		}
+		fmt.Println()
+	}
+
 	// Check thresholds
 	meetsThresholds := result.MeetsThresholds(thresholdNew, thresholdModified)
+	meetsBranchThreshold := result.MeetsBranchThreshold(branchThreshold)
 	if meetsThresholds {
 		fmt.Printf("✓ Coverage thresholds met\n")
 		if thresholdNew != thresholdModified {
@@ -233,6 +317,13 @@ func outputText(result *analyzer.AnalysisResult, thresholdNew, thresholdModified
 			}
 		}
 	}
+	if branchThreshold > 0 && result.TotalBranches > 0 {
+		if meetsBranchThreshold {
+			fmt.Printf("✓ Branch coverage threshold met: %.1f%% >= %.1f%%\n", result.BranchCoveragePercentage, branchThreshold)
+		} else {
+			fmt.Printf("✗ Branch coverage threshold not met: %.1f%% < %.1f%%\n", result.BranchCoveragePercentage, branchThreshold)
+		}
+	}
 	fmt.Println()
 
 	// Per-file results
@@ -248,16 +339,99 @@ func outputText(result *analyzer.AnalysisResult, thresholdNew, thresholdModified
 		if len(fileResult.UncoveredLineNumbers) > 0 {
 			fmt.Printf("  Uncovered lines: %v\n", fileResult.UncoveredLineNumbers)
 		}
+		if len(fileResult.PartiallyCoveredLineNumbers) > 0 {
+			fmt.Printf("  Partly covered lines: %v\n", fileResult.PartiallyCoveredLineNumbers)
+		}
+		if len(fileResult.IgnoredLineNumbers) > 0 {
+			fmt.Printf("  Ignored lines: %v\n", fileResult.IgnoredLineNumbers)
+		}
+
+		for _, summary := range fileResult.SectionSummaries() {
+			if summary.UntestedHunks > 0 {
+				fmt.Printf("  %s\n", summary)
+			}
+		}
 	}
 
+	printPatches(result.Patches)
+	printExcludedLines(result)
+	printSkippedFiles(result.SkippedFiles)
+
 	// Exit with error if threshold not met
-	if !meetsThresholds {
+	if !meetsThresholds || !meetsBranchThreshold {
 		os.Exit(1)
 	}
 
 	return nil
 }
 
+// printPatches shows which patch of a series introduced the changed lines
+func printPatches(patches []*analyzer.PatchResult) {
+	if len(patches) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("Per-Patch Results:")
+	fmt.Println("------------------")
+	for _, patch := range patches {
+		fmt.Printf("\n[%d/%d] %s\n", patch.Index, len(patches), patch.Subject)
+		if patch.TotalChangedLines == 0 {
+			fmt.Println("  No lines in the net diff")
+			continue
+		}
+		fmt.Printf("  Coverage: %.1f%% (%d/%d lines covered)\n",
+			patch.CoveragePercentage,
+			patch.CoveredLines,
+			patch.TotalChangedLines)
+		files := make([]string, 0, len(patch.UncoveredLineNumbers))
+		for filePath := range patch.UncoveredLineNumbers {
+			files = append(files, filePath)
+		}
+		sort.Strings(files)
+		for _, filePath := range files {
+			fmt.Printf("  Uncovered in %s: %v\n", filePath, patch.UncoveredLineNumbers[filePath])
+		}
+	}
+}
+
+// printExcludedLines shows how many trivial changed lines were left out of the analysis
+func printExcludedLines(result *analyzer.AnalysisResult) {
+	if len(result.ExcludedLines) == 0 {
+		return
+	}
+
+	total := result.TotalExcludedLines()
+	fmt.Println()
+	fmt.Printf("Excluded Lines: %d (%d blank, %d comment-only, %d formatting-only)\n",
+		total.Total(), total.Blank, total.Comment, total.Formatting)
+	fmt.Println("---------------")
+	files := make([]string, 0, len(result.ExcludedLines))
+	for filePath := range result.ExcludedLines {
+		files = append(files, filePath)
+	}
+	sort.Strings(files)
+	for _, filePath := range files {
+		excluded := result.ExcludedLines[filePath]
+		fmt.Printf("  %s: %d blank, %d comment-only, %d formatting-only\n",
+			filePath, excluded.Blank, excluded.Comment, excluded.Formatting)
+	}
+}
+
+// printSkippedFiles lists changed files that were not evaluated
+func printSkippedFiles(skipped []*hunk.SkippedFile) {
+	if len(skipped) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("Skipped Files (not evaluated):")
+	fmt.Println("------------------------------")
+	for _, file := range skipped {
+		fmt.Printf("  %s: %s\n", file.Path, file.Description())
+	}
+}
+
 func outputJSON(result *analyzer.AnalysisResult, thresholdNew, thresholdModified float64) error {
 	// Use the higher threshold for JSON output (for backward compatibility)
 	thresholdForJSON := threshold
@@ -278,12 +452,16 @@ func outputJSON(result *analyzer.AnalysisResult, thresholdNew, thresholdModified
 	if err := json.Unmarshal(jsonOutput, &jsonData); err == nil {
 		// Use MeetsThresholds for the actual check, not MeetsThreshold
 		jsonData["meets_threshold"] = result.MeetsThresholds(thresholdNew, thresholdModified)
+		if branchThreshold > 0 {
+			jsonData["branch_threshold"] = branchThreshold
+			jsonData["meets_branch_threshold"] = result.MeetsBranchThreshold(branchThreshold)
+		}
 		jsonOutput, _ = json.MarshalIndent(jsonData, "", "  ")
 	}
 
 	fmt.Println(string(jsonOutput))
 
-	if !result.MeetsThresholds(thresholdNew, thresholdModified) {
+	if !result.MeetsThresholds(thresholdNew, thresholdModified) || !result.MeetsBranchThreshold(branchThreshold) {
 		os.Exit(1)
 	}
 
@@ -303,7 +481,7 @@ func outputMarkdown(result *analyzer.AnalysisResult, thresholdNew, thresholdModi
 	markdownOutput := report.ToMarkdown(result, thresholdForMarkdown)
 	fmt.Print(markdownOutput)
 
-	if !result.MeetsThresholds(thresholdNew, thresholdModified) {
+	if !result.MeetsThresholds(thresholdNew, thresholdModified) || !result.MeetsBranchThreshold(branchThreshold) {
 		os.Exit(1)
 	}
 
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"os"
+	"reflect"
 	"testing"
 
 	"github.com/swantron/difftron/internal/analyzer"
+	"github.com/swantron/difftron/internal/hunk"
 )
 
 func TestOutputText(t *testing.T) {
@@ -81,16 +84,70 @@ func TestOutputJSON(t *testing.T) {
 	}
 }
 
-func TestGetGitDiff(t *testing.T) {
+func TestReadGitDiff(t *testing.T) {
 	// Test with same base and head (HEAD)
-	result, err := getGitDiff("HEAD", "HEAD")
+	files := 0
+	err := readGitDiff("HEAD", "HEAD", func(*hunk.ParseResult) { files++ })
 	if err != nil {
 		// This is okay - might not have git repo or changes
-		t.Logf("getGitDiff(HEAD, HEAD) returned error (expected in some cases): %v", err)
-	} else {
-		// Should return a string (could be empty)
-		if result == "" {
-			t.Log("getGitDiff returned empty string (no changes)")
+		t.Logf("readGitDiff(HEAD, HEAD) returned error (expected in some cases): %v", err)
+	} else if files == 0 {
+		t.Log("readGitDiff returned no changes")
+	}
+}
+
+func TestReadGitDiff_FileByFile(t *testing.T) {
+	calls := fakeGitDiff(t, `diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1,1 +1,2 @@
+ package a
++var x = 1
+diff --git a/b.go b/b.go
+--- a/b.go
++++ b/b.go
+@@ -1,1 +1,2 @@
+ package b
++var y = 2
+`)
+
+	var files []string
+	visits := 0
+	err := readGitDiff("main", "feature", func(result *hunk.ParseResult) {
+		visits++
+		for file := range result.ChangedLines {
+			files = append(files, file)
 		}
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if visits != 2 || !reflect.DeepEqual(files, []string{"a.go", "b.go"}) {
+		t.Errorf("expected a.go and b.go to be visited one at a time, got %v", files)
+	}
+	if got := (*calls)[0]; !reflect.DeepEqual(got, []string{"diff", "main", "feature"}) {
+		t.Errorf("expected git diff main feature, got %v", got)
+	}
+}
+
+func TestIsPipe(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	defer reader.Close()
+	defer writer.Close()
+	if !isPipe(reader) {
+		t.Error("expected a pipe to be detected")
+	}
+
+	// A redirected file is not read as a diff without --diff -
+	file, err := os.CreateTemp(t.TempDir(), "stdin")
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	defer file.Close()
+	if isPipe(file) {
+		t.Error("expected a regular file not to be taken for a pipe")
 	}
 }
@@ -12,10 +12,12 @@ import (
 )
 
 var (
-	ciBaseRef    string
-	ciHeadRef    string
-	ciThreshold  float64
-	ciOutputFile string
+	ciBaseRef        string
+	ciHeadRef        string
+	ciThreshold      float64
+	ciOutputFile     string
+	ciCoverageFormat string
+	ciIgnoreTrivial  bool
 )
 
 var ciCmd = &cobra.Command{
@@ -31,7 +33,9 @@ func init() {
 	ciCmd.Flags().StringVar(&ciBaseRef, "base", "", "Base git ref (default: auto-detect from CI env)")
 	ciCmd.Flags().StringVar(&ciHeadRef, "head", "", "Head git ref (default: auto-detect from CI env)")
 	ciCmd.Flags().Float64Var(&ciThreshold, "threshold", 80.0, "Coverage threshold percentage")
+	ciCmd.Flags().StringVar(&ciCoverageFormat, "coverage-format", "auto", coverageFormatUsage())
 	ciCmd.Flags().StringVar(&ciOutputFile, "output-file", "", "Output file for JSON results (default: stdout)")
+	ciCmd.Flags().BoolVar(&ciIgnoreTrivial, "ignore-trivial", false, "Leave blank, comment-only and (for Go) formatting-only changed lines out of the analysis")
 
 	rootCmd.AddCommand(ciCmd)
 }
@@ -55,84 +59,40 @@ func runCI(cmd *cobra.Command, args []string) error {
 		ciHeadRef = detectHeadRef()
 	}
 
-	// Check if coverage file exists
-	if _, err := os.Stat(coverageFile); os.IsNotExist(err) {
-		return fmt.Errorf("coverage file not found: %s", coverageFile)
+	// Check if coverage files exist
+	coverageFiles := coveragePaths(coverageFile)
+	for _, file := range coverageFiles {
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			return fmt.Errorf("coverage file not found: %s", file)
+		}
 	}
 
-	// Generate git diff
-	diffOutput, err := getGitDiffForCI(ciBaseRef, ciHeadRef)
+	// Generate and parse git diff
+	diffResult, err := getGitDiffForCI(ciBaseRef, ciHeadRef)
 	if err != nil {
+		// A diff that git produced but that is corrupt or truncated must fail the gate
+		if isDiffParseError(err) {
+			return err
+		}
 		// For direct pushes, if we can't get diff, that's OK - no changes to analyze
 		fmt.Fprintf(os.Stderr, "Warning: Could not get git diff: %v\n", err)
 		fmt.Println("No changes detected in diff.")
 		return nil
 	}
 
-	if diffOutput == "" {
-		fmt.Println("No changes detected in diff.")
-		return nil
-	}
-
-	// Parse diff
-	diffResult, err := hunk.ParseGitDiff(diffOutput)
-	if err != nil {
-		return fmt.Errorf("failed to parse git diff: %w", err)
+	if ciIgnoreTrivial {
+		diffResult.ExcludeTrivialChanges(repoRoot())
 	}
 
 	if !diffResult.HasChanges() {
-		fmt.Println("No changes detected in diff.")
+		printNoAnalyzableChanges(diffResult)
 		return nil
 	}
 
-	// Parse coverage with better error handling
-	format, err := coverage.DetectCoverageFormat(coverageFile)
+	// Parse coverage, detecting its format unless --coverage-format names it
+	coverageReport, err := coverage.ParseCoverage(coverageFiles, ciCoverageFormat)
 	if err != nil {
-		return fmt.Errorf("failed to detect coverage format: %w", err)
-	}
-
-	var coverageReport *coverage.Report
-	switch format {
-	case "go":
-		coverageReport, err = coverage.ParseGoCoverage(coverageFile)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: Failed to parse as Go coverage (%v), trying other formats\n", err)
-			// Try Cobertura as fallback
-			coverageReport, err = coverage.ParseCobertura(coverageFile)
-			if err != nil {
-				// Try LCOV as last fallback
-				coverageReport, err = coverage.ParseLCOV(coverageFile)
-				if err != nil {
-					return fmt.Errorf("failed to parse coverage file (tried Go, Cobertura, and LCOV): %w", err)
-				}
-			}
-		}
-	case "cobertura":
-		coverageReport, err = coverage.ParseCobertura(coverageFile)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: Failed to parse as Cobertura (%v), trying LCOV format\n", err)
-			// Fallback to LCOV
-			coverageReport, err = coverage.ParseLCOV(coverageFile)
-			if err != nil {
-				return fmt.Errorf("failed to parse coverage file (tried Cobertura and LCOV): %w", err)
-			}
-		}
-	case "lcov":
-		coverageReport, err = coverage.ParseLCOV(coverageFile)
-		if err != nil {
-			// Try Cobertura as fallback
-			fmt.Fprintf(os.Stderr, "Warning: Failed to parse as LCOV (%v), trying Cobertura format\n", err)
-			coverageReport, err = coverage.ParseCobertura(coverageFile)
-			if err != nil {
-				// Try Go format as last fallback
-				coverageReport, err = coverage.ParseGoCoverage(coverageFile)
-				if err != nil {
-					return fmt.Errorf("failed to parse coverage file (tried LCOV, Cobertura, and Go): %w", err)
-				}
-			}
-		}
-	default:
-		return fmt.Errorf("unsupported coverage format: %s", format)
+		return fmt.Errorf("failed to read coverage: %w", err)
 	}
 
 	// Analyze
@@ -152,6 +112,24 @@ func runCI(cmd *cobra.Command, args []string) error {
 		Files:          make(map[string]FileCIOutput),
 	}
 
+	for _, skipped := range analysisResult.SkippedFiles {
+		ciOutput.Skipped = append(ciOutput.Skipped, SkippedFileCIOutput{
+			FilePath: skipped.Path,
+			Reason:   string(skipped.Reason),
+			Detail:   skipped.Detail,
+		})
+	}
+
+	if len(analysisResult.ExcludedLines) > 0 {
+		total := analysisResult.TotalExcludedLines()
+		ciOutput.Excluded = &ExcludedCIOutput{
+			BlankLines:      total.Blank,
+			CommentLines:    total.Comment,
+			FormattingLines: total.Formatting,
+			TotalLines:      total.Total(),
+		}
+	}
+
 	for filePath, fileResult := range analysisResult.FileResults {
 		ciOutput.Files[filePath] = FileCIOutput{
 			Coverage:             fileResult.CoveragePercentage,
@@ -186,6 +164,12 @@ func runCI(cmd *cobra.Command, args []string) error {
 		analysisResult.TotalChangedLines,
 		analysisResult.CoveredLines,
 		analysisResult.UncoveredLines)
+	if ciOutput.Excluded != nil {
+		fmt.Fprintf(os.Stderr, "Excluded Lines: %d (blank, comment-only or formatting-only)\n", ciOutput.Excluded.TotalLines)
+	}
+	if len(analysisResult.SkippedFiles) > 0 {
+		fmt.Fprintf(os.Stderr, "Skipped Files: %d (binary, mode-only or submodule changes)\n", len(analysisResult.SkippedFiles))
+	}
 
 	// Exit with appropriate code
 	if !ciOutput.MeetsThreshold {
@@ -204,6 +188,8 @@ type CIOutput struct {
 	CoveredLines   int                     `json:"covered_lines"`
 	UncoveredLines int                     `json:"uncovered_lines"`
 	Files          map[string]FileCIOutput `json:"files"`
+	Skipped        []SkippedFileCIOutput   `json:"skipped,omitempty"`
+	Excluded       *ExcludedCIOutput       `json:"excluded,omitempty"`
 }
 
 // FileCIOutput represents file-level CI output
@@ -214,6 +200,21 @@ type FileCIOutput struct {
 	UncoveredLineNumbers []int   `json:"uncovered_line_numbers"`
 }
 
-func getGitDiffForCI(base, head string) (string, error) {
+// SkippedFileCIOutput represents a changed file that was not evaluated
+type SkippedFileCIOutput struct {
+	FilePath string `json:"file_path"`
+	Reason   string `json:"reason"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// ExcludedCIOutput represents the trivial lines left out of the CI analysis
+type ExcludedCIOutput struct {
+	BlankLines      int `json:"blank_lines"`
+	CommentLines    int `json:"comment_lines"`
+	FormattingLines int `json:"formatting_lines"`
+	TotalLines      int `json:"total_lines"`
+}
+
+func getGitDiffForCI(base, head string) (*hunk.ParseResult, error) {
 	return getGitDiffForPR(base, head)
 }
@@ -1,12 +1,39 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
+
+	"github.com/swantron/difftron/internal/coverage"
+	"github.com/swantron/difftron/internal/hunk"
 )
 
+// printNoAnalyzableChanges reports a diff without changed lines, listing any
+// binary, mode-only or submodule changes that were skipped
+func printNoAnalyzableChanges(diffResult *hunk.ParseResult) {
+	excluded := diffResult.GetExcludedLines()
+	if excluded.Total() > 0 {
+		fmt.Printf("All %d changed lines are blank, comment-only or formatting-only and were excluded.\n", excluded.Total())
+	}
+
+	skipped := diffResult.GetSkippedFiles()
+	if len(skipped) == 0 {
+		if excluded.Total() == 0 {
+			fmt.Println("No changes detected in diff.")
+		}
+		return
+	}
+
+	fmt.Println("No analyzable changes detected in diff. Skipped files (not evaluated):")
+	for _, file := range skipped {
+		fmt.Printf("  %s: %s\n", file.Path, file.Description())
+	}
+}
+
 // detectBaseRef detects the base git ref from CI environment variables
 func detectBaseRef() string {
 	// GitHub Actions
@@ -50,7 +77,7 @@ func detectHeadRef() string {
 }
 
 // getGitDiffForPR gets git diff optimized for PRs (uses three-dot merge-base)
-func getGitDiffForPR(base, head string) (string, error) {
+func getGitDiffForPR(base, head string) (*hunk.ParseResult, error) {
 	// Handle special case where base might be a branch name
 	if !strings.HasPrefix(base, "HEAD") && !isValidSHA(base) {
 		// Try to get the SHA of the base branch
@@ -63,17 +90,136 @@ func getGitDiffForPR(base, head string) (string, error) {
 
 	// Use three dots (...) for merge-base diff (better for PRs)
 	// Falls back to two dots (..) if merge-base fails
-	cmd := exec.Command("git", "diff", base+"..."+head)
-	output, err := cmd.Output()
+	result, err := parseGitDiffCommand("diff", base+"..."+head)
 	if err != nil {
+		if isDiffParseError(err) {
+			// git produced a diff, so the two-dot diff would not parse either
+			return nil, err
+		}
 		// Fallback to two-dot diff if three-dot fails
-		cmd = exec.Command("git", "diff", base+".."+head)
-		output, err = cmd.Output()
+		result, err = parseGitDiffCommand("diff", base+".."+head)
+		if err != nil {
+			return nil, fmt.Errorf("git diff failed: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// newGitCommand creates the git commands whose diff parseGitDiffCommand parses;
+// tests replace it to feed in diffs
+var newGitCommand = func(args ...string) *exec.Cmd {
+	return exec.Command("git", args...)
+}
+
+// diffParseError is returned by parseGitDiffCommand when git's output is not a
+// valid diff, as opposed to git itself failing
+type diffParseError struct {
+	err error
+}
+
+func (e *diffParseError) Error() string {
+	return "failed to parse git diff: " + e.err.Error()
+}
+
+func (e *diffParseError) Unwrap() error {
+	return e.err
+}
+
+// isDiffParseError returns true if err comes from parsing a diff rather than from running git
+func isDiffParseError(err error) bool {
+	var parseErr *diffParseError
+	return errors.As(err, &parseErr)
+}
+
+// parseGitDiffCommand runs git with the given arguments and parses the diff
+// as git writes it, without buffering git's output. Parse failures are
+// returned as a *diffParseError.
+func parseGitDiffCommand(args ...string) (*hunk.ParseResult, error) {
+	var result *hunk.ParseResult
+	err := runGitDiff(func(r io.Reader) error {
+		var err error
+		result, err = hunk.ParseGitDiffReader(r)
+		return err
+	}, args...)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// runGitDiff runs git with the given arguments and passes its output to read.
+// Errors from read are returned as a *diffParseError.
+func runGitDiff(read func(io.Reader) error, args ...string) error {
+	cmd := newGitCommand(args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	if err := read(stdout); err != nil {
+		// Stop git rather than waiting for output nobody will read
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return &diffParseError{err: err}
+	}
+	return cmd.Wait()
+}
+
+// visitDiff parses a diff one file at a time and passes each file's changes to visit
+func visitDiff(r io.Reader, format hunk.Format, visit func(*hunk.ParseResult)) error {
+	reader, err := hunk.NewFormatReader(r, format)
+	if err != nil {
+		return err
+	}
+	for {
+		fileResult, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
 		if err != nil {
-			return "", fmt.Errorf("git diff failed: %w", err)
+			return err
 		}
+		visit(fileResult)
 	}
-	return string(output), nil
+}
+
+// repoRoot returns the top-level directory of the git checkout, which diff paths
+// are relative to, or the current directory outside a checkout
+func repoRoot() string {
+	out, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "."
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// coveragePaths splits a coverage argument into its files. Several Go profiles,
+// e.g. from sharded go test runs, can be given separated by commas; a path that
+// exists as given is never split.
+func coveragePaths(value string) []string {
+	if _, err := os.Stat(value); err == nil || !strings.Contains(value, ",") {
+		return []string{value}
+	}
+
+	var paths []string
+	for _, path := range strings.Split(value, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return []string{value}
+	}
+	return paths
+}
+
+// coverageFormatUsage describes the --coverage-format flag, listing the
+// registered coverage formats
+func coverageFormatUsage() string {
+	return "Coverage format: auto (detect), " + strings.Join(coverage.ParserNames(), ", ")
 }
 
 // isValidSHA checks if a string looks like a valid git SHA
@@ -2,6 +2,9 @@ package main
 
 import (
 	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -176,3 +179,105 @@ func TestDetectHeadRef(t *testing.T) {
 		}
 	})
 }
+
+func TestCoveragePaths(t *testing.T) {
+	dir := t.TempDir()
+	withComma := filepath.Join(dir, "unit,api.out")
+	if err := os.WriteFile(withComma, []byte("mode: set\n"), 0644); err != nil {
+		t.Fatalf("failed to write coverage file: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"single file", "coverage.out", []string{"coverage.out"}},
+		{"sharded profiles", "shard1.out,shard2.out", []string{"shard1.out", "shard2.out"}},
+		{"spaces and empty entries", "shard1.out, shard2.out,", []string{"shard1.out", "shard2.out"}},
+		{"existing file with comma", withComma, []string{withComma}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := coveragePaths(tt.input)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("coveragePaths(%q) = %v, want %v", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestGitHelperProcess stands in for git in tests that replace newGitCommand:
+// it writes the file named by DIFFTRON_TEST_DIFF to stdout
+func TestGitHelperProcess(t *testing.T) {
+	diffPath := os.Getenv("DIFFTRON_TEST_DIFF")
+	if diffPath == "" {
+		return
+	}
+	data, err := os.ReadFile(diffPath)
+	if err != nil {
+		os.Exit(2)
+	}
+	os.Stdout.Write(data)
+	os.Exit(0)
+}
+
+// fakeGitDiff makes git commands print diff for the rest of the test and
+// returns the arguments of the commands run
+func fakeGitDiff(t *testing.T, diff string) *[][]string {
+	t.Helper()
+	diffPath := filepath.Join(t.TempDir(), "git.diff")
+	if err := os.WriteFile(diffPath, []byte(diff), 0644); err != nil {
+		t.Fatalf("failed to write diff: %v", err)
+	}
+
+	var calls [][]string
+	original := newGitCommand
+	newGitCommand = func(args ...string) *exec.Cmd {
+		calls = append(calls, args)
+		cmd := exec.Command(os.Args[0], "-test.run=^TestGitHelperProcess$")
+		cmd.Env = append(os.Environ(), "DIFFTRON_TEST_DIFF="+diffPath)
+		return cmd
+	}
+	t.Cleanup(func() { newGitCommand = original })
+	return &calls
+}
+
+const truncatedDiff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,4 @@
+ package main
++
+`
+
+func TestGetGitDiffForPR_ParseError(t *testing.T) {
+	calls := fakeGitDiff(t, truncatedDiff)
+
+	_, err := getGitDiffForPR("HEAD~1", "HEAD")
+	if err == nil || !isDiffParseError(err) {
+		t.Fatalf("expected a diff parse error, got %v", err)
+	}
+	if len(*calls) != 1 {
+		t.Errorf("expected the two-dot diff not to be tried after a parse error, got %v", *calls)
+	}
+}
+
+func TestRunCI_TruncatedDiff(t *testing.T) {
+	fakeGitDiff(t, truncatedDiff)
+	coverageFile := filepath.Join(t.TempDir(), "coverage.info")
+	if err := os.WriteFile(coverageFile, []byte("SF:main.go\nDA:2,1\nend_of_record\n"), 0644); err != nil {
+		t.Fatalf("failed to write coverage file: %v", err)
+	}
+
+	originalBase, originalHead := ciBaseRef, ciHeadRef
+	ciBaseRef, ciHeadRef = "HEAD~1", "HEAD"
+	t.Cleanup(func() { ciBaseRef, ciHeadRef = originalBase, originalHead })
+
+	err := runCI(ciCmd, []string{coverageFile})
+	if err == nil || !isDiffParseError(err) {
+		t.Errorf("expected ci to fail on a truncated diff, got %v", err)
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/swantron/difftron/internal/coverage"
 	"github.com/swantron/difftron/internal/health"
-	"github.com/swantron/difftron/internal/hunk"
 )
 
 var (
@@ -22,6 +21,7 @@ var (
 	healthThresholdModified          float64
 	healthOutputFormat               string
 	healthOutputFile                 string
+	healthCoverageFormat             string
 	healthBaseRef                    string
 	healthHeadRef                    string
 	healthCommentPR                  bool
@@ -50,6 +50,7 @@ func init() {
 	healthCmd.Flags().StringVar(&healthBaselineUnitCoverage, "baseline-unit-coverage", "", "Path to baseline unit test coverage file")
 	healthCmd.Flags().StringVar(&healthBaselineAPICoverage, "baseline-api-coverage", "", "Path to baseline API test coverage file")
 	healthCmd.Flags().StringVar(&healthBaselineFunctionalCoverage, "baseline-functional-coverage", "", "Path to baseline functional test coverage file")
+	healthCmd.Flags().StringVar(&healthCoverageFormat, "coverage-format", "auto", coverageFormatUsage()+" (applies to all coverage files)")
 	healthCmd.Flags().Float64Var(&healthThreshold, "threshold", 80.0, "Coverage threshold percentage")
 	healthCmd.Flags().Float64Var(&healthThresholdNew, "threshold-new", 0, "Coverage threshold for new files (defaults to threshold if not set)")
 	healthCmd.Flags().Float64Var(&healthThresholdModified, "threshold-modified", 0, "Coverage threshold for modified files (defaults to threshold if not set)")
@@ -87,24 +88,13 @@ func runHealth(cmd *cobra.Command, args []string) error {
 		headRef = detectHeadRef()
 	}
 
-	diffOutput, err := getGitDiffForPR(baseRef, headRef)
+	diffResult, err := getGitDiffForPR(baseRef, headRef)
 	if err != nil {
 		return fmt.Errorf("failed to get git diff: %w", err)
 	}
 
-	if diffOutput == "" {
-		fmt.Println("No changes detected in diff.")
-		return nil
-	}
-
-	// Parse diff
-	diffResult, err := hunk.ParseGitDiff(diffOutput)
-	if err != nil {
-		return fmt.Errorf("failed to parse git diff: %w", err)
-	}
-
 	if !diffResult.HasChanges() {
-		fmt.Println("No changes detected in diff.")
+		printNoAnalyzableChanges(diffResult)
 		return nil
 	}
 
@@ -210,34 +200,9 @@ func runHealth(cmd *cobra.Command, args []string) error {
 }
 
 func loadCoverageReport(filePath string, testType health.TestType) (*health.TestCoverageReport, error) {
-	format, err := coverage.DetectCoverageFormat(filePath)
+	coverageReport, err := coverage.ParseCoverage(coveragePaths(filePath), healthCoverageFormat)
 	if err != nil {
-		return nil, fmt.Errorf("failed to detect coverage format: %w", err)
-	}
-
-	var coverageReport *coverage.Report
-	switch format {
-	case "go":
-		coverageReport, err = coverage.ParseGoCoverage(filePath)
-		if err != nil {
-			// Try LCOV as fallback
-			coverageReport, err = coverage.ParseLCOV(filePath)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse Go coverage (tried Go and LCOV): %w", err)
-			}
-		}
-	case "cobertura":
-		coverageReport, err = coverage.ParseCobertura(filePath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse Cobertura coverage: %w", err)
-		}
-	case "lcov":
-		coverageReport, err = coverage.ParseLCOV(filePath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse LCOV coverage: %w", err)
-		}
-	default:
-		return nil, fmt.Errorf("unsupported coverage format: %s", format)
+		return nil, err
 	}
 
 	return &health.TestCoverageReport{
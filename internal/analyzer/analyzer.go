@@ -2,37 +2,83 @@ package analyzer
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/swantron/difftron/internal/coverage"
 	"github.com/swantron/difftron/internal/hunk"
 )
 
+// LineState classifies a changed line by its coverage
+type LineState string
+
+const (
+	// LineCovered is a line that ran and took all its branches, or has no branch data
+	LineCovered LineState = "covered"
+	// LinePartiallyCovered is a line that ran without taking all its branches,
+	// such as an if whose else-path never ran
+	LinePartiallyCovered LineState = "partial"
+	// LineUncovered is a line that never ran
+	LineUncovered LineState = "uncovered"
+)
+
 // AnalysisResult contains the results of analyzing a diff against coverage
 type AnalysisResult struct {
 	// TotalChangedLines is the total number of lines changed in the diff
 	TotalChangedLines int
-	// CoveredLines is the number of changed lines that are covered
+	// CoveredLines is the number of changed lines that are covered, including
+	// the partially covered ones
 	CoveredLines int
 	// UncoveredLines is the number of changed lines that are not covered
 	UncoveredLines int
+	// IgnoredLines is the number of changed lines the coverage tool excluded from
+	// measurement (e.g. "# pragma: no cover"); they are not part of TotalChangedLines
+	IgnoredLines int
 	// CoveragePercentage is the percentage of changed lines that are covered
 	CoveragePercentage float64
+	// PartiallyCoveredLines is the number of covered changed lines that ran without taking all their branches
+	PartiallyCoveredLines int
 	// FileResults contains per-file analysis results
 	FileResults map[string]*FileResult
 
+	// TotalBranches is the number of branches on changed lines, if the coverage has branch data
+	TotalBranches int
+	// CoveredBranches is the number of branches on changed lines that were taken
+	CoveredBranches int
+	// BranchCoveragePercentage is the percentage of branches on changed lines that were taken
+	BranchCoveragePercentage float64
+
+	// ChangedFunctions is the number of functions with changed lines, if the coverage has function data
+	ChangedFunctions int
+	// UnexecutedChangedFunctions is the number of changed functions the tests never entered
+	UnexecutedChangedFunctions int
+
 	// NewFileMetrics tracks coverage for new files only
 	NewFileMetrics *FileTypeMetrics
 	// ModifiedFileMetrics tracks coverage for modified files only
 	ModifiedFileMetrics *FileTypeMetrics
+
+	// SkippedFiles lists binary, mode-only and submodule changes that were not evaluated
+	SkippedFiles []*hunk.SkippedFile
+
+	// ExcludedLines maps file path -> blank, comment-only and formatting-only lines
+	// left out of the analysis; empty unless the diff was filtered with
+	// hunk.ParseResult.ExcludeTrivialChanges
+	ExcludedLines map[string]*hunk.ExcludedLines
+
+	// Patches breaks the results down by the patch of a series that introduced
+	// each line; nil unless requested with BreakdownByPatch
+	Patches []*PatchResult
 }
 
 // FileTypeMetrics tracks coverage metrics for a specific type of files (new or modified)
 type FileTypeMetrics struct {
-	TotalChangedLines  int
-	CoveredLines       int
-	UncoveredLines     int
-	CoveragePercentage float64
-	FileCount          int
+	TotalChangedLines     int
+	CoveredLines          int
+	UncoveredLines        int
+	PartiallyCoveredLines int
+	CoveragePercentage    float64
+	FileCount             int
 }
 
 // FileResult contains analysis results for a single file
@@ -44,13 +90,108 @@ type FileResult struct {
 	CoveragePercentage float64
 	// UncoveredLineNumbers lists the line numbers that are not covered
 	UncoveredLineNumbers []int
-	// CoveredLineNumbers lists the line numbers that are covered
+	// CoveredLineNumbers lists the line numbers that are covered, including the partially covered ones
 	CoveredLineNumbers []int
+	// PartiallyCoveredLines is the number of covered lines that ran without taking all their branches
+	PartiallyCoveredLines int
+	// LineStates maps each changed line number -> its coverage state
+	LineStates map[int]LineState
 	// IsNewFile indicates if this file is new (didn't exist in base)
 	IsNewFile bool
 	// BaselineCoveragePercentage is the coverage percentage before changes (for modified files)
 	// This helps identify if coverage actually dropped or if we're just seeing untested code for the first time
 	BaselineCoveragePercentage float64
+	// HasBaseline indicates if BaselineCoveragePercentage was measured, i.e. some
+	// changed lines replaced lines of the baseline coverage
+	HasBaseline bool
+	// Hunks contains per-hunk results in diff order
+	Hunks []*HunkResult
+	// TotalBranches and CoveredBranches count the branches on changed lines
+	TotalBranches            int
+	CoveredBranches          int
+	BranchCoveragePercentage float64
+	// PartiallyCoveredLineNumbers lists the changed lines that ran without taking
+	// all their branches, such as an if whose else-path never ran
+	PartiallyCoveredLineNumbers []int
+	// ChangedFunctions lists the functions that contain changed lines
+	ChangedFunctions []*FunctionResult
+	// ExcludedLines counts the trivial lines left out of this file's results, nil if none
+	ExcludedLines *hunk.ExcludedLines
+	// IgnoredLineNumbers lists the changed lines the coverage tool excluded from measurement
+	IgnoredLineNumbers []int
+}
+
+// HunkResult contains analysis results for a single diff hunk
+type HunkResult struct {
+	OldStart int
+	OldCount int
+	NewStart int
+	NewCount int
+	// Section is the heading git printed for the hunk, usually the enclosing function
+	Section            string
+	TotalChangedLines  int
+	CoveredLines       int
+	UncoveredLines     int
+	CoveragePercentage float64
+	// PartiallyCoveredLines is the number of covered lines in this hunk that did not take all their branches
+	PartiallyCoveredLines int
+	// UncoveredLineNumbers lists the changed line numbers in this hunk that are not covered
+	UncoveredLineNumbers []int
+}
+
+// IsUntested returns true if the hunk changed lines and none of them are covered
+func (h *HunkResult) IsUntested() bool {
+	return h.TotalChangedLines > 0 && h.CoveredLines == 0
+}
+
+// SectionSummary counts the hunks of a file that share a section heading
+type SectionSummary struct {
+	Section       string
+	Hunks         int
+	UntestedHunks int
+}
+
+// String describes the summary, e.g. "3 of 4 hunks in func ParseLCOV are untested"
+func (s SectionSummary) String() string {
+	noun := "hunks"
+	if s.Hunks == 1 {
+		noun = "hunk"
+	}
+	verb := "are"
+	if s.UntestedHunks == 1 {
+		verb = "is"
+	}
+
+	section := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s.Section), "{"))
+	if section == "" {
+		return fmt.Sprintf("%d of %d %s %s untested", s.UntestedHunks, s.Hunks, noun, verb)
+	}
+	return fmt.Sprintf("%d of %d %s in %s %s untested", s.UntestedHunks, s.Hunks, noun, section, verb)
+}
+
+// SectionSummaries groups the file's hunks by section heading, in the order
+// the sections first appear in the diff. Hunks without changed lines are ignored.
+func (f *FileResult) SectionSummaries() []SectionSummary {
+	summaries := make([]SectionSummary, 0)
+	index := make(map[string]int)
+
+	for _, h := range f.Hunks {
+		if h.TotalChangedLines == 0 {
+			continue
+		}
+		i, exists := index[h.Section]
+		if !exists {
+			i = len(summaries)
+			index[h.Section] = i
+			summaries = append(summaries, SectionSummary{Section: h.Section})
+		}
+		summaries[i].Hunks++
+		if h.IsUntested() {
+			summaries[i].UntestedHunks++
+		}
+	}
+
+	return summaries
 }
 
 // Analyze compares git diff hunks with coverage data
@@ -64,45 +205,111 @@ func AnalyzeWithBaseline(diffResult *hunk.ParseResult, coverageReport *coverage.
 	if diffResult == nil {
 		return nil, fmt.Errorf("diff result cannot be nil")
 	}
+
+	accumulator, err := NewAccumulator(coverageReport, baselineReport)
+	if err != nil {
+		return nil, err
+	}
+	accumulator.Add(diffResult)
+	return accumulator.Result(), nil
+}
+
+// Accumulator analyzes a diff in parts, such as the per-file results of a
+// hunk.Reader, so that the diff never has to be held in memory as a whole.
+// Each file must be added once.
+type Accumulator struct {
+	result         *AnalysisResult
+	coverageReport *coverage.Report
+	baselineReport *coverage.Report
+}
+
+// NewAccumulator creates an Accumulator analyzing against the coverage report;
+// baselineReport can be nil if baseline coverage is not available
+func NewAccumulator(coverageReport *coverage.Report, baselineReport *coverage.Report) (*Accumulator, error) {
 	if coverageReport == nil {
 		return nil, fmt.Errorf("coverage report cannot be nil")
 	}
 
-	result := &AnalysisResult{
-		FileResults:         make(map[string]*FileResult),
-		NewFileMetrics:      &FileTypeMetrics{},
-		ModifiedFileMetrics: &FileTypeMetrics{},
+	return &Accumulator{
+		result: &AnalysisResult{
+			FileResults:         make(map[string]*FileResult),
+			NewFileMetrics:      &FileTypeMetrics{},
+			ModifiedFileMetrics: &FileTypeMetrics{},
+			ExcludedLines:       make(map[string]*hunk.ExcludedLines),
+		},
+		coverageReport: coverageReport,
+		baselineReport: baselineReport,
+	}, nil
+}
+
+// Add analyzes the files of a diff and adds them to the result
+func (a *Accumulator) Add(diffResult *hunk.ParseResult) {
+	result := a.result
+	result.SkippedFiles = append(result.SkippedFiles, diffResult.GetSkippedFiles()...)
+	for filePath, excluded := range diffResult.ExcludedLines {
+		result.ExcludedLines[filePath] = excluded
 	}
 
 	// Process each changed file
 	for filePath, changedLines := range diffResult.ChangedLines {
-		isNewFile := diffResult.IsNewFile(filePath)
-		fileResult := analyzeFile(filePath, changedLines, coverageReport, baselineReport, isNewFile)
+		// Renamed and copied files existed in base under their old path
+		isNewFile := diffResult.IsNewFile(filePath) && !diffResult.IsRenamedFile(filePath)
+		fileResult := analyzeFile(filePath, changedLines, diffResult, a.coverageReport, a.baselineReport, isNewFile)
+		if previous, ok := result.FileResults[filePath]; ok {
+			// A file listed again replaces its earlier changes, as when parsing the diff as a whole
+			a.count(previous, -1)
+		}
 		result.FileResults[filePath] = fileResult
+		a.count(fileResult, 1)
+	}
+}
 
-		// Update overall metrics
-		result.TotalChangedLines += fileResult.TotalChangedLines
-		result.CoveredLines += fileResult.CoveredLines
-		result.UncoveredLines += fileResult.UncoveredLines
-
-		// Update type-specific metrics
-		if isNewFile {
-			result.NewFileMetrics.TotalChangedLines += fileResult.TotalChangedLines
-			result.NewFileMetrics.CoveredLines += fileResult.CoveredLines
-			result.NewFileMetrics.UncoveredLines += fileResult.UncoveredLines
-			result.NewFileMetrics.FileCount++
-		} else {
-			result.ModifiedFileMetrics.TotalChangedLines += fileResult.TotalChangedLines
-			result.ModifiedFileMetrics.CoveredLines += fileResult.CoveredLines
-			result.ModifiedFileMetrics.UncoveredLines += fileResult.UncoveredLines
-			result.ModifiedFileMetrics.FileCount++
+// count adds the metrics of a file result to the overall and the new or
+// modified file metrics, or subtracts them if sign is -1
+func (a *Accumulator) count(fileResult *FileResult, sign int) {
+	result := a.result
+
+	// Update overall metrics
+	result.TotalChangedLines += sign * fileResult.TotalChangedLines
+	result.CoveredLines += sign * fileResult.CoveredLines
+	result.UncoveredLines += sign * fileResult.UncoveredLines
+	result.IgnoredLines += sign * len(fileResult.IgnoredLineNumbers)
+	result.TotalBranches += sign * fileResult.TotalBranches
+	result.CoveredBranches += sign * fileResult.CoveredBranches
+	result.PartiallyCoveredLines += sign * fileResult.PartiallyCoveredLines
+	result.ChangedFunctions += sign * len(fileResult.ChangedFunctions)
+	for _, function := range fileResult.ChangedFunctions {
+		if !function.IsExecuted() {
+			result.UnexecutedChangedFunctions += sign
 		}
 	}
 
+	// Update type-specific metrics
+	metrics := result.ModifiedFileMetrics
+	if fileResult.IsNewFile {
+		metrics = result.NewFileMetrics
+	}
+	metrics.TotalChangedLines += sign * fileResult.TotalChangedLines
+	metrics.CoveredLines += sign * fileResult.CoveredLines
+	metrics.UncoveredLines += sign * fileResult.UncoveredLines
+	metrics.PartiallyCoveredLines += sign * fileResult.PartiallyCoveredLines
+	metrics.FileCount += sign
+}
+
+// Result returns the analysis of everything added so far
+func (a *Accumulator) Result() *AnalysisResult {
+	result := a.result
+	sort.Slice(result.SkippedFiles, func(i, j int) bool {
+		return result.SkippedFiles[i].Path < result.SkippedFiles[j].Path
+	})
+
 	// Calculate overall coverage percentage
 	if result.TotalChangedLines > 0 {
 		result.CoveragePercentage = float64(result.CoveredLines) / float64(result.TotalChangedLines) * 100
 	}
+	if result.TotalBranches > 0 {
+		result.BranchCoveragePercentage = float64(result.CoveredBranches) / float64(result.TotalBranches) * 100
+	}
 
 	// Calculate type-specific coverage percentages
 	if result.NewFileMetrics.TotalChangedLines > 0 {
@@ -112,16 +319,23 @@ func AnalyzeWithBaseline(diffResult *hunk.ParseResult, coverageReport *coverage.
 		result.ModifiedFileMetrics.CoveragePercentage = float64(result.ModifiedFileMetrics.CoveredLines) / float64(result.ModifiedFileMetrics.TotalChangedLines) * 100
 	}
 
-	return result, nil
+	return result
 }
 
 // analyzeFile analyzes coverage for a single file
-func analyzeFile(filePath string, changedLines map[int]bool, coverageReport *coverage.Report, baselineReport *coverage.Report, isNewFile bool) *FileResult {
+func analyzeFile(filePath string, changedLines map[int]bool, diffResult *hunk.ParseResult, coverageReport *coverage.Report, baselineReport *coverage.Report, isNewFile bool) *FileResult {
 	fileResult := &FileResult{
 		FilePath:             filePath,
 		UncoveredLineNumbers: make([]int, 0),
 		CoveredLineNumbers:   make([]int, 0),
 		IsNewFile:            isNewFile,
+		LineStates:           make(map[int]LineState),
+
+		PartiallyCoveredLineNumbers: make([]int, 0),
+		IgnoredLineNumbers:          make([]int, 0),
+		ChangedFunctions:            make([]*FunctionResult, 0),
+		Hunks:                       make([]*HunkResult, 0),
+		ExcludedLines:               diffResult.ExcludedLines[filePath],
 	}
 
 	// Try multiple path variations to match coverage data
@@ -133,23 +347,47 @@ func analyzeFile(filePath string, changedLines map[int]bool, coverageReport *cov
 		fileCoverage = coverageReport.GetCoverageForFile(matchingPath)
 	}
 
+	// Leave out the lines the coverage tool was told not to measure
+	if fileCoverage != nil && len(fileCoverage.ExcludedLines) > 0 {
+		measured := make(map[int]bool, len(changedLines))
+		for lineNum := range changedLines {
+			if fileCoverage.ExcludedLines[lineNum] {
+				fileResult.IgnoredLineNumbers = append(fileResult.IgnoredLineNumbers, lineNum)
+				continue
+			}
+			measured[lineNum] = true
+		}
+		sort.Ints(fileResult.IgnoredLineNumbers)
+		changedLines = measured
+	}
+
 	// Get baseline coverage for modified files
+	// The base version may live under another path (renames) and its line
+	// numbers differ from the new version whenever earlier hunks shift lines
 	if !isNewFile && baselineReport != nil {
-		baselineMatchingPath := coverage.FindMatchingPath(filePath, baselineReport.FileCoverage)
+		baselinePath := diffResult.OldPath(filePath)
+		baselineMatchingPath := coverage.FindMatchingPath(baselinePath, baselineReport.FileCoverage)
 		if baselineMatchingPath != "" {
 			baselineFileCoverage = baselineReport.GetCoverageForFile(baselineMatchingPath)
 		}
 
-		// Calculate baseline coverage percentage for the changed lines
+		// Calculate baseline coverage percentage for the changed lines that
+		// replaced an existing line; purely added lines have no baseline
 		if baselineFileCoverage != nil && baselineMatchingPath != "" {
 			baselineCovered := 0
-			baselineTotal := len(changedLines)
+			baselineTotal := 0
 			for lineNum := range changedLines {
-				if baselineReport.IsLineCovered(baselineMatchingPath, lineNum) {
+				oldLineNum, ok := diffResult.OldLineNumber(filePath, lineNum)
+				if !ok {
+					continue
+				}
+				baselineTotal++
+				if baselineReport.IsLineCovered(baselineMatchingPath, oldLineNum) {
 					baselineCovered++
 				}
 			}
 			if baselineTotal > 0 {
+				fileResult.HasBaseline = true
 				fileResult.BaselineCoveragePercentage = float64(baselineCovered) / float64(baselineTotal) * 100
 			}
 		}
@@ -167,20 +405,82 @@ func analyzeFile(filePath string, changedLines map[int]bool, coverageReport *cov
 		if isCovered {
 			fileResult.CoveredLines++
 			fileResult.CoveredLineNumbers = append(fileResult.CoveredLineNumbers, lineNum)
+			fileResult.LineStates[lineNum] = LineCovered
 		} else {
 			fileResult.UncoveredLines++
 			fileResult.UncoveredLineNumbers = append(fileResult.UncoveredLineNumbers, lineNum)
+			fileResult.LineStates[lineNum] = LineUncovered
+		}
+
+		// Count the branches on the line, if the coverage has branch data
+		if fileCoverage != nil && matchingPath != "" {
+			coveredBranches, totalBranches := fileCoverage.BranchCoverage(lineNum)
+			fileResult.TotalBranches += totalBranches
+			fileResult.CoveredBranches += coveredBranches
+			if coverageReport.IsLinePartiallyCovered(matchingPath, lineNum) {
+				fileResult.PartiallyCoveredLines++
+				fileResult.PartiallyCoveredLineNumbers = append(fileResult.PartiallyCoveredLineNumbers, lineNum)
+				fileResult.LineStates[lineNum] = LinePartiallyCovered
+			}
 		}
 	}
+	sort.Ints(fileResult.PartiallyCoveredLineNumbers)
 
 	// Calculate file-level coverage percentage
 	if fileResult.TotalChangedLines > 0 {
 		fileResult.CoveragePercentage = float64(fileResult.CoveredLines) / float64(fileResult.TotalChangedLines) * 100
 	}
+	if fileResult.TotalBranches > 0 {
+		fileResult.BranchCoveragePercentage = float64(fileResult.CoveredBranches) / float64(fileResult.TotalBranches) * 100
+	}
+
+	// Find the functions the change touches
+	if fileCoverage != nil {
+		fileResult.ChangedFunctions = changedFunctions(filePath, fileCoverage.Functions, changedLines)
+	}
+
+	// Break the results down per hunk
+	for _, h := range diffResult.GetHunksForFile(filePath) {
+		fileResult.Hunks = append(fileResult.Hunks, analyzeHunk(h, changedLines, coverageReport, matchingPath))
+	}
 
 	return fileResult
 }
 
+// analyzeHunk analyzes coverage for the changed lines of a single hunk
+func analyzeHunk(h *hunk.Hunk, changedLines map[int]bool, coverageReport *coverage.Report, matchingPath string) *HunkResult {
+	hunkResult := &HunkResult{
+		OldStart:             h.OldStart,
+		OldCount:             h.OldCount,
+		NewStart:             h.NewStart,
+		NewCount:             h.NewCount,
+		Section:              h.Section,
+		UncoveredLineNumbers: make([]int, 0),
+	}
+
+	for _, lineNum := range h.AddedLines() {
+		if !changedLines[lineNum] {
+			continue
+		}
+		hunkResult.TotalChangedLines++
+		if matchingPath != "" && coverageReport.IsLineCovered(matchingPath, lineNum) {
+			hunkResult.CoveredLines++
+			if coverageReport.IsLinePartiallyCovered(matchingPath, lineNum) {
+				hunkResult.PartiallyCoveredLines++
+			}
+		} else {
+			hunkResult.UncoveredLines++
+			hunkResult.UncoveredLineNumbers = append(hunkResult.UncoveredLineNumbers, lineNum)
+		}
+	}
+
+	if hunkResult.TotalChangedLines > 0 {
+		hunkResult.CoveragePercentage = float64(hunkResult.CoveredLines) / float64(hunkResult.TotalChangedLines) * 100
+	}
+
+	return hunkResult
+}
+
 // MeetsThreshold checks if the analysis result meets the specified coverage threshold
 func (r *AnalysisResult) MeetsThreshold(threshold float64) bool {
 	return r.CoveragePercentage >= threshold
@@ -205,6 +505,34 @@ func (r *AnalysisResult) MeetsThresholds(thresholdNew, thresholdModified float64
 	return true
 }
 
+// FullyCoveredLines returns the number of covered changed lines that took all their branches
+func (r *AnalysisResult) FullyCoveredLines() int {
+	return r.CoveredLines - r.PartiallyCoveredLines
+}
+
+// LineState returns the coverage state of a changed line, or "" if the line did not change
+func (f *FileResult) LineState(lineNum int) LineState {
+	return f.LineStates[lineNum]
+}
+
+// MeetsBranchThreshold checks if the branches on changed lines meet the specified
+// branch coverage threshold. It is met when the threshold is 0 or no changed line has branch data.
+func (r *AnalysisResult) MeetsBranchThreshold(threshold float64) bool {
+	if threshold <= 0 || r.TotalBranches == 0 {
+		return true
+	}
+	return r.BranchCoveragePercentage >= threshold
+}
+
+// TotalExcludedLines returns the number of trivial lines left out of the analysis
+func (r *AnalysisResult) TotalExcludedLines() *hunk.ExcludedLines {
+	total := &hunk.ExcludedLines{}
+	for _, excluded := range r.ExcludedLines {
+		total.Add(excluded)
+	}
+	return total
+}
+
 // HasUncoveredLines returns true if there are any uncovered lines
 func (r *AnalysisResult) HasUncoveredLines() bool {
 	return r.UncoveredLines > 0
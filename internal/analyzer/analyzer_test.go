@@ -1,7 +1,9 @@
 package analyzer
 
 import (
+	"io"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/swantron/difftron/internal/coverage"
@@ -14,7 +16,7 @@ func TestAnalyze(t *testing.T) {
 index 123..456 100644
 --- a/file.go
 +++ b/file.go
-@@ -5,3 +5,5 @@ func main() {
+@@ -5,2 +5,4 @@ func main() {
  	fmt.Println("hello")
 +	fmt.Println("new line 1")
 +	fmt.Println("new line 2")
@@ -118,7 +120,7 @@ func TestAnalyze_NoCoverage(t *testing.T) {
 index 123..456 100644
 --- a/file.go
 +++ b/file.go
-@@ -5,1 +5,2 @@
+@@ -5,0 +6,1 @@
 +	new line
 `
 
@@ -146,6 +148,78 @@ index 123..456 100644
 	}
 }
 
+func TestAccumulator(t *testing.T) {
+	diffOutput := `diff --git a/a.go b/a.go
+new file mode 100644
+--- /dev/null
++++ b/a.go
+@@ -0,0 +1,2 @@
++package a
++var x = 1
+diff --git a/b.go b/b.go
+index 123..456 100644
+--- a/b.go
++++ b/b.go
+@@ -1,1 +1,3 @@
+ package b
++var y = 2
++var z = 3
+`
+
+	coverageReport := &coverage.Report{
+		FileCoverage: map[string]*coverage.CoverageData{
+			"a.go": {LineHits: map[int]int{2: 1}},
+			"b.go": {LineHits: map[int]int{2: 1, 3: 0}},
+		},
+	}
+
+	whole, err := hunk.ParseGitDiff(diffOutput)
+	if err != nil {
+		t.Fatalf("failed to parse diff: %v", err)
+	}
+	expected, err := Analyze(whole, coverageReport)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	accumulator, err := NewAccumulator(coverageReport, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reader := hunk.NewReader(strings.NewReader(diffOutput))
+	for {
+		file, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to parse diff: %v", err)
+		}
+		accumulator.Add(file)
+		// Adding a file again replaces its earlier results
+		accumulator.Add(file)
+	}
+	result := accumulator.Result()
+
+	if result.TotalChangedLines != expected.TotalChangedLines || result.CoveredLines != expected.CoveredLines ||
+		result.UncoveredLines != expected.UncoveredLines || result.CoveragePercentage != expected.CoveragePercentage {
+		t.Errorf("expected %d/%d covered (%.1f%%), got %d/%d (%.1f%%)",
+			expected.CoveredLines, expected.TotalChangedLines, expected.CoveragePercentage,
+			result.CoveredLines, result.TotalChangedLines, result.CoveragePercentage)
+	}
+	if result.NewFileMetrics.FileCount != 1 || result.ModifiedFileMetrics.FileCount != 1 {
+		t.Errorf("expected one new and one modified file, got %d and %d",
+			result.NewFileMetrics.FileCount, result.ModifiedFileMetrics.FileCount)
+	}
+	if len(result.FileResults) != 2 {
+		t.Errorf("expected 2 file results, got %d", len(result.FileResults))
+	}
+
+	if _, err := NewAccumulator(nil, nil); err == nil {
+		t.Error("expected error for a nil coverage report")
+	}
+}
+
 func TestAnalysisResult_MeetsThreshold(t *testing.T) {
 	result := &AnalysisResult{
 		TotalChangedLines:  10,
@@ -252,30 +326,39 @@ end_of_record
 
 func TestAnalyzeWithBaseline(t *testing.T) {
 	// Create test diff for a modified file
+	// An earlier hunk adds two lines, so the rewritten lines 8-9 in the new
+	// version correspond to lines 6-7 in the base version
 	diffOutput := `diff --git a/file.go b/file.go
 index 123..456 100644
 --- a/file.go
 +++ b/file.go
-@@ -5,3 +5,5 @@ func main() {
+@@ -1,1 +1,3 @@
+ package main
++
++import "fmt"
+@@ -5,4 +7,4 @@ func main() {
  	fmt.Println("hello")
+-	fmt.Println("old line 1")
+-	fmt.Println("old line 2")
 +	fmt.Println("new line 1")
 +	fmt.Println("new line 2")
  	fmt.Println("world")
 `
 
-	// Current coverage - line 6 covered, line 7 not covered
+	// Current coverage - line 8 covered, line 9 not covered
 	currentCoverage := `TN:
 SF:file.go
-DA:6,5
-DA:7,0
-DA:8,3
+DA:8,5
+DA:9,0
+DA:10,3
 end_of_record
 `
 
-	// Baseline coverage - line 6 was covered before, line 7 didn't exist
+	// Baseline coverage - old line 6 was covered, old line 7 was not
 	baselineCoverage := `TN:
 SF:file.go
 DA:6,3
+DA:7,0
 DA:8,2
 end_of_record
 `
@@ -323,16 +406,362 @@ end_of_record
 		t.Fatal("expected file result for file.go")
 	}
 
-	// Verify baseline coverage is tracked
-	// Line 6 existed in baseline and was covered (100% baseline for that line)
-	// Line 7 is new, so baseline coverage should be 0% for that line
-	// Overall baseline for changed lines: 1 out of 2 lines = 50%
-	if fileResult.BaselineCoveragePercentage != 50.0 {
+	// Verify baseline coverage is tracked through the line mapping
+	// Lines 2-3 are pure additions and have no baseline counterpart
+	// Line 8 replaced old line 6 (covered), line 9 replaced old line 7 (not covered)
+	// Overall baseline for changed lines: 1 out of 2 mapped lines = 50%
+	if !fileResult.HasBaseline || fileResult.BaselineCoveragePercentage != 50.0 {
 		t.Errorf("expected baseline coverage 50%%, got %.1f%%", fileResult.BaselineCoveragePercentage)
 	}
 
-	// Current coverage: 1 out of 2 lines = 50%
-	if fileResult.CoveragePercentage != 50.0 {
-		t.Errorf("expected current coverage 50%%, got %.1f%%", fileResult.CoveragePercentage)
+	// Current coverage: 1 out of 4 changed lines = 25%
+	if fileResult.CoveragePercentage != 25.0 {
+		t.Errorf("expected current coverage 25%%, got %.1f%%", fileResult.CoveragePercentage)
+	}
+}
+
+func TestAnalyzeWithBaseline_RenamedFile(t *testing.T) {
+	diffOutput := `diff --git a/old.go b/new.go
+similarity index 90%
+rename from old.go
+rename to new.go
+index 123..456 100644
+--- a/old.go
++++ b/new.go
+@@ -5,3 +5,3 @@ func main() {
+ 	fmt.Println("hello")
+-	fmt.Println("old line")
++	fmt.Println("new line")
+ 	fmt.Println("world")
+`
+
+	diffResult, err := hunk.ParseGitDiff(diffOutput)
+	if err != nil {
+		t.Fatalf("failed to parse diff: %v", err)
+	}
+
+	currentReport := &coverage.Report{
+		FileCoverage: map[string]*coverage.CoverageData{
+			"new.go": {LineHits: map[int]int{6: 1}, TotalLines: 1, CoveredLines: 1},
+		},
+	}
+	baselineReport := &coverage.Report{
+		FileCoverage: map[string]*coverage.CoverageData{
+			"old.go": {LineHits: map[int]int{6: 1}, TotalLines: 1, CoveredLines: 1},
+		},
+	}
+
+	result, err := AnalyzeWithBaseline(diffResult, currentReport, baselineReport)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fileResult, ok := result.FileResults["new.go"]
+	if !ok {
+		t.Fatal("expected file result for new.go")
+	}
+	if fileResult.IsNewFile {
+		t.Error("expected renamed file to be classified as modified")
+	}
+	if result.ModifiedFileMetrics.FileCount != 1 || result.NewFileMetrics.FileCount != 0 {
+		t.Errorf("expected 1 modified and 0 new files, got %d modified and %d new",
+			result.ModifiedFileMetrics.FileCount, result.NewFileMetrics.FileCount)
+	}
+	if fileResult.BaselineCoveragePercentage != 100.0 {
+		t.Errorf("expected baseline coverage looked up via old path (100%%), got %.1f%%", fileResult.BaselineCoveragePercentage)
+	}
+}
+
+func TestAnalyzeWithBaseline_OnlyAddedLines(t *testing.T) {
+	diffResult, err := hunk.ParseGitDiff(`diff --git a/file.go b/file.go
+index 123..456 100644
+--- a/file.go
++++ b/file.go
+@@ -5,2 +5,3 @@ func main() {
+ 	fmt.Println("hello")
++	fmt.Println("new line")
+ 	fmt.Println("world")
+`)
+	if err != nil {
+		t.Fatalf("failed to parse diff: %v", err)
+	}
+
+	currentReport := &coverage.Report{
+		FileCoverage: map[string]*coverage.CoverageData{
+			"file.go": {LineHits: map[int]int{6: 1}, TotalLines: 1, CoveredLines: 1},
+		},
+	}
+	baselineReport := &coverage.Report{
+		FileCoverage: map[string]*coverage.CoverageData{
+			"file.go": {LineHits: map[int]int{5: 1, 6: 0}, TotalLines: 2, CoveredLines: 1},
+		},
+	}
+
+	result, err := AnalyzeWithBaseline(diffResult, currentReport, baselineReport)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fileResult := result.FileResults["file.go"]
+	if fileResult == nil {
+		t.Fatal("expected file result for file.go")
+	}
+	if fileResult.HasBaseline {
+		t.Errorf("expected no baseline for a change that only adds lines, got %.1f%%", fileResult.BaselineCoveragePercentage)
+	}
+}
+
+func TestAnalyze_HunkResults(t *testing.T) {
+	diffOutput := `diff --git a/lcov.go b/lcov.go
+index 123..456 100644
+--- a/lcov.go
++++ b/lcov.go
+@@ -5,2 +5,3 @@ func ParseLCOV(filePath string) (*Report, error) {
+ 	a()
++	b()
+ 	c()
+@@ -20,2 +21,3 @@ func ParseLCOV(filePath string) (*Report, error) {
+ 	d()
++	e()
+ 	f()
+@@ -40,2 +42,3 @@ func GetCoverageForFile(filePath string) *CoverageData {
+ 	g()
++	h()
+ 	i()
+`
+
+	diffResult, err := hunk.ParseGitDiff(diffOutput)
+	if err != nil {
+		t.Fatalf("failed to parse diff: %v", err)
+	}
+
+	coverageReport := &coverage.Report{
+		FileCoverage: map[string]*coverage.CoverageData{
+			"lcov.go": {LineHits: map[int]int{6: 1, 22: 0, 43: 0}},
+		},
+	}
+
+	result, err := Analyze(diffResult, coverageReport)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fileResult := result.FileResults["lcov.go"]
+	if len(fileResult.Hunks) != 3 {
+		t.Fatalf("expected 3 hunk results, got %d", len(fileResult.Hunks))
+	}
+	if fileResult.Hunks[0].IsUntested() {
+		t.Error("expected first hunk to be tested")
+	}
+	if !fileResult.Hunks[1].IsUntested() || fileResult.Hunks[1].UncoveredLineNumbers[0] != 22 {
+		t.Errorf("expected second hunk to be untested at line 22, got %+v", fileResult.Hunks[1])
+	}
+
+	summaries := fileResult.SectionSummaries()
+	if len(summaries) != 2 {
+		t.Fatalf("expected 2 section summaries, got %d", len(summaries))
+	}
+	if got := summaries[0].String(); got != "1 of 2 hunks in func ParseLCOV(filePath string) (*Report, error) is untested" {
+		t.Errorf("unexpected summary: %q", got)
+	}
+	if got := summaries[1].String(); got != "1 of 1 hunk in func GetCoverageForFile(filePath string) *CoverageData is untested" {
+		t.Errorf("unexpected summary: %q", got)
+	}
+}
+
+func TestAnalyze_SkippedFiles(t *testing.T) {
+	diffOutput := `diff --git a/file.go b/file.go
+index 1234567..abcdefg 100644
+--- a/file.go
++++ b/file.go
+@@ -1,1 +1,2 @@
+ package main
++var x = 1
+diff --git a/logo.png b/logo.png
+index 1111111..2222222 100644
+Binary files a/logo.png and b/logo.png differ
+`
+
+	diffResult, err := hunk.ParseGitDiff(diffOutput)
+	if err != nil {
+		t.Fatalf("failed to parse diff: %v", err)
+	}
+
+	coverageReport := &coverage.Report{
+		FileCoverage: map[string]*coverage.CoverageData{
+			"file.go": {LineHits: map[int]int{2: 1}},
+		},
+	}
+
+	result, err := Analyze(diffResult, coverageReport)
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+
+	if len(result.SkippedFiles) != 1 || result.SkippedFiles[0].Path != "logo.png" || result.SkippedFiles[0].Reason != hunk.SkipBinary {
+		t.Errorf("expected logo.png to be reported as a skipped binary file, got %v", result.SkippedFiles)
+	}
+	if _, exists := result.FileResults["logo.png"]; exists {
+		t.Error("expected skipped file not to have a file result")
+	}
+	if result.TotalChangedLines != 1 {
+		t.Errorf("expected 1 changed line, got %d", result.TotalChangedLines)
+	}
+}
+
+func TestAnalyze_ExcludedLines(t *testing.T) {
+	diffOutput := `diff --git a/file.go b/file.go
+index 1234567..abcdefg 100644
+--- a/file.go
++++ b/file.go
+@@ -1,1 +1,4 @@
+ package main
++
++// x is one
++var x = 1
+`
+
+	diffResult, err := hunk.ParseGitDiff(diffOutput)
+	if err != nil {
+		t.Fatalf("failed to parse diff: %v", err)
+	}
+	diffResult.ExcludeTrivialChanges("")
+
+	coverageReport := &coverage.Report{
+		FileCoverage: map[string]*coverage.CoverageData{
+			"file.go": {LineHits: map[int]int{4: 1}},
+		},
+	}
+
+	result, err := Analyze(diffResult, coverageReport)
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+
+	if result.TotalChangedLines != 1 || result.CoveragePercentage != 100 {
+		t.Errorf("expected 1 covered changed line, got %d at %.1f%%", result.TotalChangedLines, result.CoveragePercentage)
+	}
+	if got := result.TotalExcludedLines(); *got != (hunk.ExcludedLines{Blank: 1, Comment: 1}) {
+		t.Errorf("expected 1 blank and 1 comment line excluded, got %+v", got)
+	}
+	if got := result.FileResults["file.go"].ExcludedLines; got == nil || got.Total() != 2 {
+		t.Errorf("expected the file result to count 2 excluded lines, got %+v", got)
+	}
+}
+
+func TestAnalyze_Branches(t *testing.T) {
+	diffOutput := `diff --git a/file.c b/file.c
+index 1234567..abcdefg 100644
+--- a/file.c
++++ b/file.c
+@@ -1,1 +1,4 @@
+ int main() {
++  if (a) {
++  if (b) {
++  return 0;
+`
+
+	diffResult, err := hunk.ParseGitDiff(diffOutput)
+	if err != nil {
+		t.Fatalf("failed to parse diff: %v", err)
+	}
+
+	coverageReport := &coverage.Report{
+		FileCoverage: map[string]*coverage.CoverageData{
+			"file.c": {
+				LineHits: map[int]int{2: 3, 3: 3, 4: 1},
+				Branches: map[int][]coverage.Branch{
+					1: {{ID: "0,0", Taken: 1}, {ID: "0,1", Taken: 0}},
+					2: {{ID: "1,0", Taken: 3}, {ID: "1,1", Taken: 0}},
+					3: {{ID: "2,0", Taken: 2}, {ID: "2,1", Taken: 1}},
+				},
+			},
+		},
+	}
+
+	result, err := Analyze(diffResult, coverageReport)
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+
+	// Line 1 is unchanged, so its branches are not counted
+	if result.TotalBranches != 4 || result.CoveredBranches != 3 || result.BranchCoveragePercentage != 75 {
+		t.Errorf("expected 3 of 4 branches covered, got %d of %d (%.1f%%)",
+			result.CoveredBranches, result.TotalBranches, result.BranchCoveragePercentage)
+	}
+	if result.PartiallyCoveredLines != 1 {
+		t.Errorf("expected 1 partially covered line, got %d", result.PartiallyCoveredLines)
+	}
+	if got := result.FileResults["file.c"].PartiallyCoveredLineNumbers; len(got) != 1 || got[0] != 2 {
+		t.Errorf("expected line 2 to have untaken branches, got %v", got)
+	}
+
+	// Partly covered lines still count as covered, but have their own state
+	fileResult := result.FileResults["file.c"]
+	if result.CoveredLines != 3 || result.FullyCoveredLines() != 2 {
+		t.Errorf("expected 3 covered lines, 2 of them fully, got %d and %d", result.CoveredLines, result.FullyCoveredLines())
+	}
+	for lineNum, want := range map[int]LineState{1: "", 2: LinePartiallyCovered, 3: LineCovered, 4: LineCovered} {
+		if got := fileResult.LineState(lineNum); got != want {
+			t.Errorf("line %d: expected state %q, got %q", lineNum, want, got)
+		}
+	}
+	if fileResult.Hunks[0].PartiallyCoveredLines != 1 || result.ModifiedFileMetrics.PartiallyCoveredLines != 1 {
+		t.Errorf("expected the hunk and modified files to count 1 partly covered line")
+	}
+
+	if !result.MeetsBranchThreshold(75) || result.MeetsBranchThreshold(80) {
+		t.Error("expected the branch threshold to be met at 75% but not at 80%")
+	}
+	if !result.MeetsBranchThreshold(0) {
+		t.Error("expected a zero branch threshold to always be met")
+	}
+}
+
+func TestAnalyze_IgnoredLines(t *testing.T) {
+	diffOutput := `diff --git a/app.py b/app.py
+index 1234567..abcdefg 100644
+--- a/app.py
++++ b/app.py
+@@ -1,1 +1,4 @@
+ def main():
++    run()
++    if debug:  # pragma: no cover
++        trace()
+`
+
+	diffResult, err := hunk.ParseGitDiff(diffOutput)
+	if err != nil {
+		t.Fatalf("failed to parse diff: %v", err)
+	}
+
+	coverageReport := &coverage.Report{
+		FileCoverage: map[string]*coverage.CoverageData{
+			"app.py": {
+				LineHits:      map[int]int{1: 1, 2: 1},
+				ExcludedLines: map[int]bool{3: true, 4: true},
+			},
+		},
+	}
+
+	result, err := Analyze(diffResult, coverageReport)
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+
+	// Excluded lines are neither covered nor uncovered
+	if result.TotalChangedLines != 1 || result.UncoveredLines != 0 || result.CoveragePercentage != 100 {
+		t.Errorf("expected 1 covered changed line, got %d changed, %d uncovered at %.1f%%",
+			result.TotalChangedLines, result.UncoveredLines, result.CoveragePercentage)
+	}
+	if result.IgnoredLines != 2 {
+		t.Errorf("expected 2 ignored lines, got %d", result.IgnoredLines)
+	}
+	fileResult := result.FileResults["app.py"]
+	if got := fileResult.IgnoredLineNumbers; len(got) != 2 || got[0] != 3 || got[1] != 4 {
+		t.Errorf("expected lines 3 and 4 to be ignored, got %v", got)
+	}
+	if fileResult.Hunks[0].TotalChangedLines != 1 || fileResult.LineState(3) != "" {
+		t.Error("expected ignored lines to be left out of the hunk results and line states")
 	}
 }
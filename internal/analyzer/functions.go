@@ -0,0 +1,71 @@
+package analyzer
+
+import (
+	"sort"
+
+	"github.com/swantron/difftron/internal/coverage"
+)
+
+// FunctionResult describes a function that contains changed lines
+type FunctionResult struct {
+	FilePath  string
+	Name      string
+	StartLine int
+	EndLine   int
+	// Hits is the number of times the function was entered
+	Hits int
+	// ChangedLines is the number of changed lines inside the function
+	ChangedLines int
+}
+
+// IsExecuted returns true if the tests entered the function at least once
+func (f *FunctionResult) IsExecuted() bool {
+	return f.Hits > 0
+}
+
+// changedFunctions returns the functions of a file that contain changed lines,
+// in order of their start line. A change inside a nested function also counts
+// for the functions around it.
+func changedFunctions(filePath string, functions []*coverage.Function, changedLines map[int]bool) []*FunctionResult {
+	results := make([]*FunctionResult, 0)
+	for _, function := range functions {
+		changed := 0
+		for lineNum := range changedLines {
+			if function.Contains(lineNum) {
+				changed++
+			}
+		}
+		if changed == 0 {
+			continue
+		}
+		results = append(results, &FunctionResult{
+			FilePath:     filePath,
+			Name:         function.Name,
+			StartLine:    function.StartLine,
+			EndLine:      function.EndLine,
+			Hits:         function.Hits,
+			ChangedLines: changed,
+		})
+	}
+	return results
+}
+
+// UnexecutedFunctions returns the changed functions that the tests never
+// entered, sorted by file and start line
+func (r *AnalysisResult) UnexecutedFunctions() []*FunctionResult {
+	functions := make([]*FunctionResult, 0)
+	for _, fileResult := range r.FileResults {
+		for _, function := range fileResult.ChangedFunctions {
+			if !function.IsExecuted() {
+				functions = append(functions, function)
+			}
+		}
+	}
+	sort.Slice(functions, func(i, j int) bool {
+		if functions[i].FilePath != functions[j].FilePath {
+			return functions[i].FilePath < functions[j].FilePath
+		}
+		return functions[i].StartLine < functions[j].StartLine
+	})
+	return functions
+}
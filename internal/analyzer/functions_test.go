@@ -0,0 +1,64 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/swantron/difftron/internal/coverage"
+	"github.com/swantron/difftron/internal/hunk"
+)
+
+func TestAnalyze_ChangedFunctions(t *testing.T) {
+	diffOutput := `diff --git a/app.js b/app.js
+index 1234567..abcdefg 100644
+--- a/app.js
++++ b/app.js
+@@ -1,1 +1,3 @@
+ function setup() {
++  init();
++  load();
+@@ -20,1 +22,2 @@
+ function render() {
++  draw();
+`
+
+	diffResult, err := hunk.ParseGitDiff(diffOutput)
+	if err != nil {
+		t.Fatalf("failed to parse diff: %v", err)
+	}
+
+	coverageReport := &coverage.Report{
+		FileCoverage: map[string]*coverage.CoverageData{
+			"app.js": {
+				LineHits: map[int]int{2: 1, 3: 1, 23: 0},
+				Functions: []*coverage.Function{
+					{Name: "setup", StartLine: 1, EndLine: 5, Hits: 1},
+					{Name: "helper", StartLine: 10, EndLine: 15, Hits: 0},
+					{Name: "render", StartLine: 22, EndLine: 30, Hits: 0},
+				},
+			},
+		},
+	}
+
+	result, err := Analyze(diffResult, coverageReport)
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+
+	// helper is never run but was not changed
+	if result.ChangedFunctions != 2 || result.UnexecutedChangedFunctions != 1 {
+		t.Errorf("expected 1 of 2 changed functions unexecuted, got %d of %d",
+			result.UnexecutedChangedFunctions, result.ChangedFunctions)
+	}
+
+	unexecuted := result.UnexecutedFunctions()
+	if len(unexecuted) != 1 {
+		t.Fatalf("expected 1 unexecuted function, got %d", len(unexecuted))
+	}
+	if f := unexecuted[0]; f.FilePath != "app.js" || f.Name != "render" || f.StartLine != 22 || f.ChangedLines != 1 {
+		t.Errorf("unexpected unexecuted function %+v", f)
+	}
+
+	if got := result.FileResults["app.js"].ChangedFunctions; len(got) != 2 || got[0].Name != "setup" || got[0].ChangedLines != 2 {
+		t.Errorf("unexpected changed functions %+v", got)
+	}
+}
@@ -0,0 +1,65 @@
+package analyzer
+
+import (
+	"sort"
+
+	"github.com/swantron/difftron/internal/hunk"
+)
+
+// PatchResult contains the analysis of the changed lines one patch of a series
+// introduced into the net diff
+type PatchResult struct {
+	// Index is the 1-based position of the patch in the series
+	Index              int
+	Commit             string
+	Subject            string
+	TotalChangedLines  int
+	CoveredLines       int
+	UncoveredLines     int
+	CoveragePercentage float64
+	// UncoveredLineNumbers maps file path -> uncovered lines the patch introduced,
+	// numbered as in the tree the whole series produces
+	UncoveredLineNumbers map[string][]int
+}
+
+// BreakdownByPatch attributes the changed lines of an analysis of series.Result
+// to the patches that added them. Lines a later patch removed again are not
+// part of the net diff and are not counted for any patch.
+func BreakdownByPatch(result *AnalysisResult, series *hunk.Series) []*PatchResult {
+	patches := make([]*PatchResult, len(series.Patches))
+	for i, patch := range series.Patches {
+		patches[i] = &PatchResult{
+			Index:                i + 1,
+			Commit:               patch.Commit,
+			Subject:              patch.Subject,
+			UncoveredLineNumbers: make(map[string][]int),
+		}
+	}
+
+	for filePath, fileResult := range result.FileResults {
+		for _, lineNum := range fileResult.CoveredLineNumbers {
+			if i, ok := series.PatchForLine(filePath, lineNum); ok {
+				patches[i].TotalChangedLines++
+				patches[i].CoveredLines++
+			}
+		}
+		for _, lineNum := range fileResult.UncoveredLineNumbers {
+			if i, ok := series.PatchForLine(filePath, lineNum); ok {
+				patches[i].TotalChangedLines++
+				patches[i].UncoveredLines++
+				patches[i].UncoveredLineNumbers[filePath] = append(patches[i].UncoveredLineNumbers[filePath], lineNum)
+			}
+		}
+	}
+
+	for _, patch := range patches {
+		if patch.TotalChangedLines > 0 {
+			patch.CoveragePercentage = float64(patch.CoveredLines) / float64(patch.TotalChangedLines) * 100
+		}
+		for _, lines := range patch.UncoveredLineNumbers {
+			sort.Ints(lines)
+		}
+	}
+
+	return patches
+}
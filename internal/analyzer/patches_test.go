@@ -0,0 +1,81 @@
+package analyzer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/swantron/difftron/internal/coverage"
+	"github.com/swantron/difftron/internal/hunk"
+)
+
+func TestBreakdownByPatch(t *testing.T) {
+	mbox := `From 1111111111111111111111111111111111111111 Mon Sep 17 00:00:00 2001
+From: Ada <ada@example.com>
+Subject: [PATCH 1/2] Add feature
+
+---
+diff --git a/file.go b/file.go
+--- a/file.go
++++ b/file.go
+@@ -1,1 +1,3 @@
+ package main
++func a() {}
++func b() {}
+
+From 2222222222222222222222222222222222222222 Mon Sep 17 00:00:00 2001
+From: Ada <ada@example.com>
+Subject: [PATCH 2/2] Extend feature
+
+---
+diff --git a/file.go b/file.go
+--- a/file.go
++++ b/file.go
+@@ -1,3 +1,4 @@
+ package main
++func c() {}
+ func a() {}
+ func b() {}
+`
+
+	series, err := hunk.ParseMbox(strings.NewReader(mbox), hunk.FormatGit)
+	if err != nil {
+		t.Fatalf("failed to parse mbox: %v", err)
+	}
+
+	// Net lines: 2 (c, second patch), 3 (a, first patch), 4 (b, first patch)
+	coverageReport := &coverage.Report{
+		FileCoverage: map[string]*coverage.CoverageData{
+			"file.go": {LineHits: map[int]int{2: 0, 3: 1, 4: 0}},
+		},
+	}
+
+	result, err := Analyze(series.Result, coverageReport)
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+
+	patches := BreakdownByPatch(result, series)
+	if len(patches) != 2 {
+		t.Fatalf("expected 2 patch results, got %d", len(patches))
+	}
+
+	first := patches[0]
+	if first.Index != 1 || first.Subject != "Add feature" || first.TotalChangedLines != 2 || first.CoveredLines != 1 {
+		t.Errorf("unexpected first patch result %+v", first)
+	}
+	if !reflect.DeepEqual(first.UncoveredLineNumbers, map[string][]int{"file.go": {4}}) {
+		t.Errorf("expected line 4 uncovered in the first patch, got %v", first.UncoveredLineNumbers)
+	}
+	if first.CoveragePercentage != 50 {
+		t.Errorf("expected 50%% coverage for the first patch, got %.1f", first.CoveragePercentage)
+	}
+
+	second := patches[1]
+	if second.Index != 2 || second.TotalChangedLines != 1 || second.UncoveredLines != 1 {
+		t.Errorf("unexpected second patch result %+v", second)
+	}
+	if !reflect.DeepEqual(second.UncoveredLineNumbers, map[string][]int{"file.go": {2}}) {
+		t.Errorf("expected line 2 uncovered in the second patch, got %v", second.UncoveredLineNumbers)
+	}
+}
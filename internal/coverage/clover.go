@@ -0,0 +1,267 @@
+package coverage
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CloverCoverage represents the root element of a Clover XML report
+type CloverCoverage struct {
+	XMLName   xml.Name      `xml:"coverage"`
+	Generated int64         `xml:"generated,attr,omitempty"`
+	Clover    string        `xml:"clover,attr,omitempty"`
+	Project   CloverProject `xml:"project"`
+}
+
+// CloverProject holds the files of a Clover report, directly or grouped in packages
+type CloverProject struct {
+	Name      string          `xml:"name,attr,omitempty"`
+	Timestamp int64           `xml:"timestamp,attr,omitempty"`
+	Metrics   *CloverMetrics  `xml:"metrics"`
+	Packages  []CloverPackage `xml:"package"`
+	Files     []CloverFile    `xml:"file"`
+}
+
+// CloverPackage groups files, e.g. by PHP namespace
+type CloverPackage struct {
+	Name  string       `xml:"name,attr"`
+	Files []CloverFile `xml:"file"`
+}
+
+// CloverFile represents one source file. PHPUnit puts the full path in Name;
+// other tools write the base name to Name and the full path to Path.
+type CloverFile struct {
+	Name    string         `xml:"name,attr"`
+	Path    string         `xml:"path,attr,omitempty"`
+	Metrics *CloverMetrics `xml:"metrics"`
+	Lines   []CloverLine   `xml:"line"`
+}
+
+// CloverLine is a "stmt", "cond" or "method" line. Conditions count how often
+// they evaluated to true and to false.
+type CloverLine struct {
+	Num        int    `xml:"num,attr"`
+	Type       string `xml:"type,attr"`
+	Name       string `xml:"name,attr,omitempty"`
+	Count      int    `xml:"count,attr"`
+	TrueCount  *int   `xml:"truecount,attr"`
+	FalseCount *int   `xml:"falsecount,attr"`
+}
+
+// CloverMetrics summarizes the elements of a file or project
+type CloverMetrics struct {
+	Statements          int `xml:"statements,attr"`
+	CoveredStatements   int `xml:"coveredstatements,attr"`
+	Conditionals        int `xml:"conditionals,attr"`
+	CoveredConditionals int `xml:"coveredconditionals,attr"`
+	Methods             int `xml:"methods,attr"`
+	CoveredMethods      int `xml:"coveredmethods,attr"`
+	Elements            int `xml:"elements,attr"`
+	CoveredElements     int `xml:"coveredelements,attr"`
+}
+
+// detectClover recognizes Clover XML, whose root is <coverage> like Cobertura's
+func detectClover(path string, head []byte) float64 {
+	content := string(head)
+	if strings.Contains(content, "<coverage") &&
+		(strings.Contains(content, "<project") || strings.Contains(content, "clover=")) {
+		return ConfidenceCertain
+	}
+	return ConfidenceNone
+}
+
+// ParseClover parses a Clover XML report, as written by PHPUnit, OpenClover and Istanbul
+// Returns a Report containing coverage data for all files
+func ParseClover(filePath string) (*Report, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Clover file: %w", err)
+	}
+	defer file.Close()
+
+	return decodeClover(file)
+}
+
+// decodeClover reads a Clover XML report from r
+func decodeClover(r io.Reader) (*Report, error) {
+	var clover CloverCoverage
+	if err := xml.NewDecoder(r).Decode(&clover); err != nil {
+		return nil, fmt.Errorf("failed to parse Clover XML: %w", err)
+	}
+
+	report := &Report{
+		FileCoverage: make(map[string]*CoverageData),
+	}
+
+	files := append([]CloverFile(nil), clover.Project.Files...)
+	for _, pkg := range clover.Project.Packages {
+		files = append(files, pkg.Files...)
+	}
+
+	for _, cloverFile := range files {
+		filePath := cloverFile.Path
+		if filePath == "" {
+			filePath = cloverFile.Name
+		}
+		report.FileCoverage[NormalizePath(filePath)] = cloverFile.toCoverageData()
+	}
+
+	return report, nil
+}
+
+// toCoverageData converts the lines of a file. Method lines become functions;
+// a condition becomes two branches, its true and its false outcome.
+func (f CloverFile) toCoverageData() *CoverageData {
+	coverage := &CoverageData{
+		LineHits: make(map[int]int),
+	}
+
+	for _, line := range f.Lines {
+		switch line.Type {
+		case "method":
+			coverage.Functions = append(coverage.Functions, &Function{Name: line.Name, StartLine: line.Num, Hits: line.Count})
+		case "cond":
+			trueCount, falseCount := derefInt(line.TrueCount), derefInt(line.FalseCount)
+			hits := line.Count
+			if hits == 0 {
+				hits = trueCount + falseCount
+			}
+			coverage.setLineHits(line.Num, hits)
+			coverage.addBranch(line.Num, "true", trueCount)
+			coverage.addBranch(line.Num, "false", falseCount)
+		default:
+			coverage.setLineHits(line.Num, line.Count)
+		}
+	}
+	coverage.finishFunctions()
+
+	return coverage
+}
+
+// WriteClover writes a report as Clover XML, with one <file> per file in path order.
+// Clover records two outcomes per condition, so a line with more than two branches
+// is written with its first branch as the true outcome and its least taken other
+// branch as the false outcome; a line with untaken branches stays partly covered.
+func WriteClover(w io.Writer, report *Report) error {
+	now := time.Now().Unix()
+	clover := CloverCoverage{
+		Generated: now,
+		Clover:    "4.4.1",
+		Project: CloverProject{
+			Name:      "difftron",
+			Timestamp: now,
+			Metrics:   &CloverMetrics{},
+		},
+	}
+
+	for _, filePath := range report.sortedPaths() {
+		cloverFile := newCloverFile(filePath, report.FileCoverage[filePath])
+		clover.Project.Metrics.add(cloverFile.Metrics)
+		clover.Project.Files = append(clover.Project.Files, cloverFile)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(clover); err != nil {
+		return fmt.Errorf("failed to write Clover XML: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// newCloverFile converts the coverage of a file into Clover lines, in line order
+func newCloverFile(filePath string, coverage *CoverageData) CloverFile {
+	cloverFile := CloverFile{
+		Name:    filePath,
+		Path:    filePath,
+		Metrics: &CloverMetrics{},
+	}
+	metrics := cloverFile.Metrics
+
+	type entry struct {
+		line CloverLine
+		// order puts a method before the statement on its line
+		order int
+	}
+	entries := make([]entry, 0, len(coverage.LineHits)+len(coverage.Functions))
+
+	for _, function := range coverage.Functions {
+		entries = append(entries, entry{line: CloverLine{Num: function.StartLine, Type: "method", Name: function.Name, Count: function.Hits}})
+		metrics.Methods++
+		if function.Hits > 0 {
+			metrics.CoveredMethods++
+		}
+	}
+
+	for lineNum, hits := range coverage.LineHits {
+		line := CloverLine{Num: lineNum, Type: "stmt", Count: hits}
+		if branches := coverage.Branches[lineNum]; len(branches) > 0 {
+			trueCount := branches[0].Taken
+			falseCount := trueCount
+			for i, branch := range branches[1:] {
+				if i == 0 || branch.Taken < falseCount {
+					falseCount = branch.Taken
+				}
+			}
+			line.Type = "cond"
+			line.TrueCount = &trueCount
+			line.FalseCount = &falseCount
+
+			metrics.Conditionals += 2
+			if trueCount > 0 {
+				metrics.CoveredConditionals++
+			}
+			if falseCount > 0 {
+				metrics.CoveredConditionals++
+			}
+		} else {
+			metrics.Statements++
+			if hits > 0 {
+				metrics.CoveredStatements++
+			}
+		}
+		entries = append(entries, entry{line: line, order: 1})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].line.Num != entries[j].line.Num {
+			return entries[i].line.Num < entries[j].line.Num
+		}
+		return entries[i].order < entries[j].order
+	})
+	for _, e := range entries {
+		cloverFile.Lines = append(cloverFile.Lines, e.line)
+	}
+
+	metrics.Elements = metrics.Statements + metrics.Conditionals + metrics.Methods
+	metrics.CoveredElements = metrics.CoveredStatements + metrics.CoveredConditionals + metrics.CoveredMethods
+	return cloverFile
+}
+
+// add adds the counts of other to the metrics
+func (m *CloverMetrics) add(other *CloverMetrics) {
+	m.Statements += other.Statements
+	m.CoveredStatements += other.CoveredStatements
+	m.Conditionals += other.Conditionals
+	m.CoveredConditionals += other.CoveredConditionals
+	m.Methods += other.Methods
+	m.CoveredMethods += other.CoveredMethods
+	m.Elements += other.Elements
+	m.CoveredElements += other.CoveredElements
+}
+
+// derefInt returns the value of an optional integer attribute, 0 if it is missing
+func derefInt(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
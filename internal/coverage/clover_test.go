@@ -0,0 +1,129 @@
+package coverage
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseClover(t *testing.T) {
+	cloverContent := `<?xml version="1.0" encoding="UTF-8"?>
+<coverage generated="1700000000">
+  <project timestamp="1700000000">
+    <package name="App">
+      <file name="src/Greeter.php">
+        <class name="Greeter" namespace="App">
+          <metrics complexity="2" methods="2" coveredmethods="1" statements="4" coveredstatements="3" elements="6" coveredelements="4"/>
+        </class>
+        <line num="5" type="method" name="greet" visibility="public" complexity="1" crap="1" count="3"/>
+        <line num="7" type="stmt" count="3"/>
+        <line num="8" type="cond" truecount="2" falsecount="0"/>
+        <line num="9" type="stmt" count="2"/>
+        <line num="12" type="method" name="farewell" visibility="public" complexity="1" crap="2" count="0"/>
+        <line num="14" type="stmt" count="0"/>
+        <metrics loc="16" ncloc="16" classes="1" methods="2" coveredmethods="1" statements="4" coveredstatements="3" elements="6" coveredelements="4"/>
+      </file>
+    </package>
+    <file name="util.js" path="web/util.js">
+      <line num="1" count="4" type="stmt"/>
+    </file>
+  </project>
+</coverage>
+`
+
+	tmpfile, err := os.CreateTemp("", "clover-*.xml")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := tmpfile.Write([]byte(cloverContent)); err != nil {
+		t.Fatalf("failed to write test data: %v", err)
+	}
+	tmpfile.Close()
+
+	report, err := ParseClover(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fileCoverage := report.GetCoverageForFile("src/Greeter.php")
+	if fileCoverage == nil {
+		t.Fatal("expected coverage data for src/Greeter.php")
+	}
+
+	// Method lines are functions, not statements
+	if fileCoverage.TotalLines != 4 || fileCoverage.CoveredLines != 3 {
+		t.Errorf("expected 3 of 4 lines covered, got %d of %d", fileCoverage.CoveredLines, fileCoverage.TotalLines)
+	}
+	if hits := report.GetCoverageForLine("src/Greeter.php", 8); hits != 2 {
+		t.Errorf("expected condition line 8 to have 2 hits, got %d", hits)
+	}
+	if !report.IsLinePartiallyCovered("src/Greeter.php", 8) {
+		t.Error("expected line 8 to be partially covered")
+	}
+
+	functions := report.GetFunctionsForFile("src/Greeter.php")
+	if len(functions) != 2 || functions[0].Name != "greet" || functions[0].Hits != 3 || functions[1].Hits != 0 {
+		t.Errorf("unexpected functions %+v", functions)
+	}
+
+	// Files outside packages use their path attribute
+	if hits := report.GetCoverageForLine("web/util.js", 1); hits != 4 {
+		t.Errorf("expected 4 hits on web/util.js line 1, got %d", hits)
+	}
+}
+
+func TestWriteClover(t *testing.T) {
+	report := &Report{
+		FileCoverage: map[string]*CoverageData{
+			"src/app.c": {
+				LineHits: map[int]int{2: 3, 3: 3, 5: 0},
+				Branches: map[int][]Branch{
+					3: {{ID: "0,0", Taken: 3}, {ID: "0,1", Taken: 1}, {ID: "0,2", Taken: 0}},
+				},
+				Functions: []*Function{{Name: "main", StartLine: 1, EndLine: 6, Hits: 1}},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteClover(&buf, report); err != nil {
+		t.Fatalf("WriteClover() error = %v", err)
+	}
+	output := buf.String()
+
+	for _, want := range []string{
+		`<line num="1" type="method" name="main" count="1">`,
+		`<line num="3" type="cond" count="3" truecount="3" falsecount="0">`,
+		`<line num="5" type="stmt" count="0">`,
+		`<metrics statements="2" coveredstatements="1" conditionals="2" coveredconditionals="1" methods="1" coveredmethods="1" elements="5" coveredelements="3">`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %s, got:\n%s", want, output)
+		}
+	}
+
+	// The written report reads back with the same line states
+	tmpfile, err := os.CreateTemp("", "clover-*.xml")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+	if _, err := tmpfile.Write(buf.Bytes()); err != nil {
+		t.Fatalf("failed to write test data: %v", err)
+	}
+	tmpfile.Close()
+
+	if format, err := DetectCoverageFormat(tmpfile.Name()); err != nil || format != "clover" {
+		t.Errorf("expected the output to be detected as clover, got %q (%v)", format, err)
+	}
+	parsed, err := ParseClover(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("ParseClover() error = %v", err)
+	}
+	if !parsed.IsLineCovered("src/app.c", 2) || parsed.IsLineCovered("src/app.c", 5) || !parsed.IsLinePartiallyCovered("src/app.c", 3) {
+		t.Error("expected lines 2 and 3 covered, line 3 partly, and line 5 uncovered after reading back")
+	}
+}
@@ -3,8 +3,10 @@ package coverage
 import (
 	"encoding/xml"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -52,12 +54,12 @@ type CoberturaClass struct {
 	Lines      CoberturaLines   `xml:"lines"`
 }
 
-// CoberturaMethods contains method elements (optional, we mainly use lines)
+// CoberturaMethods contains method elements
 type CoberturaMethods struct {
 	Method []CoberturaMethod `xml:"method"`
 }
 
-// CoberturaMethod represents a method (optional, we mainly use lines)
+// CoberturaMethod represents a method; its lines give the function's range and hits
 type CoberturaMethod struct {
 	Name       string         `xml:"name,attr"`
 	Signature  string         `xml:"signature,attr"`
@@ -79,6 +81,18 @@ type CoberturaLine struct {
 	ConditionCoverage string `xml:"condition-coverage,attr"`
 }
 
+// detectCobertura recognizes XML coverage with <package> or <class> elements,
+// which other XML formats share
+func detectCobertura(path string, head []byte) float64 {
+	content := string(head)
+	if (strings.HasPrefix(strings.TrimSpace(content), "<?xml") || strings.Contains(content, "<coverage")) &&
+		(strings.Contains(content, "cobertura") || strings.Contains(content, "coverage")) &&
+		(strings.Contains(content, "<package") || strings.Contains(content, "<class")) {
+		return ConfidenceLikely
+	}
+	return ConfidenceNone
+}
+
 // ParseCobertura parses a Cobertura XML format coverage file
 // Returns a Report containing coverage data for all files
 func ParseCobertura(filePath string) (*Report, error) {
@@ -88,8 +102,13 @@ func ParseCobertura(filePath string) (*Report, error) {
 	}
 	defer file.Close()
 
+	return decodeCobertura(file)
+}
+
+// decodeCobertura reads a Cobertura XML report from r
+func decodeCobertura(r io.Reader) (*Report, error) {
 	var cobertura CoberturaCoverage
-	decoder := xml.NewDecoder(file)
+	decoder := xml.NewDecoder(r)
 	if err := decoder.Decode(&cobertura); err != nil {
 		return nil, fmt.Errorf("failed to parse Cobertura XML: %w", err)
 	}
@@ -128,10 +147,14 @@ func ParseCobertura(filePath string) (*Report, error) {
 				if line.Hits > 0 {
 					fileCoverage.CoveredLines++
 				}
+				addCoberturaBranches(fileCoverage, line)
 			}
 
 			// Also process lines from methods (some tools put lines here)
 			for _, method := range class.Methods.Method {
+				if function := newCoberturaFunction(method); function != nil {
+					fileCoverage.Functions = append(fileCoverage.Functions, function)
+				}
 				for _, line := range method.Lines.Line {
 					// Only add if not already present (class-level takes precedence)
 					if _, exists := fileCoverage.LineHits[line.Number]; !exists {
@@ -140,15 +163,85 @@ func ParseCobertura(filePath string) (*Report, error) {
 						if line.Hits > 0 {
 							fileCoverage.CoveredLines++
 						}
+						addCoberturaBranches(fileCoverage, line)
 					}
 				}
 			}
 		}
 	}
 
+	for _, fileCoverage := range report.FileCoverage {
+		fileCoverage.finishFunctions()
+	}
+
 	return report, nil
 }
 
+// addCoberturaBranches records the branches of a line from its condition coverage,
+// e.g. condition-coverage="50% (1/2)". Cobertura only counts the conditions that
+// were reached, so each covered branch is recorded as taken once.
+func addCoberturaBranches(fileCoverage *CoverageData, line CoberturaLine) {
+	if !line.Branch {
+		return
+	}
+	covered, total, ok := parseConditionCoverage(line.ConditionCoverage)
+	if !ok {
+		return
+	}
+	for i := 0; i < total; i++ {
+		taken := 0
+		if i < covered {
+			taken = 1
+		}
+		fileCoverage.addBranch(line.Number, strconv.Itoa(i), taken)
+	}
+}
+
+// parseConditionCoverage parses the counts of a condition-coverage attribute
+// such as "50% (1/2)"
+func parseConditionCoverage(value string) (covered, total int, ok bool) {
+	open := strings.Index(value, "(")
+	end := strings.LastIndex(value, ")")
+	if open == -1 || end < open {
+		return 0, 0, false
+	}
+	coveredStr, totalStr, found := strings.Cut(value[open+1:end], "/")
+	if !found {
+		return 0, 0, false
+	}
+	covered, err := strconv.Atoi(strings.TrimSpace(coveredStr))
+	if err != nil {
+		return 0, 0, false
+	}
+	total, err = strconv.Atoi(strings.TrimSpace(totalStr))
+	if err != nil || total <= 0 || covered < 0 || covered > total {
+		return 0, 0, false
+	}
+	return covered, total, true
+}
+
+// newCoberturaFunction builds a function record from a method. Cobertura has no
+// call count, so the function's hits are the highest hit count of its lines.
+// It returns nil for a method without lines.
+func newCoberturaFunction(method CoberturaMethod) *Function {
+	if len(method.Lines.Line) == 0 {
+		return nil
+	}
+	function := &Function{Name: method.Name + method.Signature}
+	for _, line := range method.Lines.Line {
+		if function.StartLine == 0 || line.Number < function.StartLine {
+			function.StartLine = line.Number
+		}
+		if line.Number > function.EndLine {
+			function.EndLine = line.Number
+		}
+		if line.Hits > function.Hits {
+			function.Hits = line.Hits
+		}
+	}
+	return function
+}
+
 // resolveFilePath resolves a relative filename against source paths
 func resolveFilePath(filename string, sourcePaths map[string]bool) string {
 	// Try direct match first
@@ -114,6 +114,89 @@ func TestParseCobertura(t *testing.T) {
 	if file2Coverage.CoveredLines != 3 {
 		t.Errorf("expected 3 covered lines for AnotherClass.java, got %d", file2Coverage.CoveredLines)
 	}
+
+	// Methods become functions spanning their lines
+	functions := report.GetFunctionsForFile("src/com/example/MyClass.java")
+	if len(functions) != 2 {
+		t.Fatalf("expected 2 functions for MyClass.java, got %d", len(functions))
+	}
+	if f := functions[0]; f.Name != "method1()V" || f.StartLine != 10 || f.EndLine != 11 || f.Hits != 5 {
+		t.Errorf("unexpected first function %+v", f)
+	}
+	if f := functions[1]; f.Name != "method2()V" || f.StartLine != 15 || f.Hits != 0 {
+		t.Errorf("unexpected second function %+v", f)
+	}
+	if len(report.GetFunctionsForFile("src/com/example/AnotherClass.java")) != 0 {
+		t.Error("expected no functions for a class without methods")
+	}
+}
+
+func TestParseCobertura_ConditionCoverage(t *testing.T) {
+	coberturaContent := `<?xml version="1.0"?>
+<coverage line-rate="0.8" branch-rate="0.5">
+  <packages>
+    <package name="app">
+      <classes>
+        <class name="app.views" filename="app/views.py">
+          <lines>
+            <line number="3" hits="4" branch="true" condition-coverage="50% (1/2)"/>
+            <line number="4" hits="4" branch="true" condition-coverage="100% (2/2)"/>
+            <line number="5" hits="0" branch="true" condition-coverage="0% (0/4)"/>
+            <line number="6" hits="4"/>
+            <line number="7" hits="1" branch="true" condition-coverage="garbage"/>
+          </lines>
+        </class>
+      </classes>
+    </package>
+  </packages>
+</coverage>
+`
+
+	tmpfile, err := os.CreateTemp("", "test-conditions-*.xml")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := tmpfile.Write([]byte(coberturaContent)); err != nil {
+		t.Fatalf("failed to write test data: %v", err)
+	}
+	tmpfile.Close()
+
+	report, err := ParseCobertura(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fileCoverage := report.GetCoverageForFile("app/views.py")
+	if fileCoverage == nil {
+		t.Fatal("expected coverage data for app/views.py")
+	}
+	if fileCoverage.TotalBranches != 8 || fileCoverage.CoveredBranches != 3 {
+		t.Errorf("expected 3 of 8 branches covered, got %d of %d", fileCoverage.CoveredBranches, fileCoverage.TotalBranches)
+	}
+
+	tests := []struct {
+		line    int
+		covered int
+		total   int
+		partial bool
+	}{
+		{3, 1, 2, true},
+		{4, 2, 2, false},
+		{5, 0, 4, false},
+		{6, 0, 0, false},
+		{7, 0, 0, false},
+	}
+	for _, tt := range tests {
+		covered, total := report.GetBranchCoverageForLine("app/views.py", tt.line)
+		if covered != tt.covered || total != tt.total {
+			t.Errorf("line %d: expected %d of %d branches, got %d of %d", tt.line, tt.covered, tt.total, covered, total)
+		}
+		if got := report.IsLinePartiallyCovered("app/views.py", tt.line); got != tt.partial {
+			t.Errorf("line %d: expected partially covered %v, got %v", tt.line, tt.partial, got)
+		}
+	}
 }
 
 func TestParseCobertura_EmptyFile(t *testing.T) {
@@ -0,0 +1,154 @@
+package coverage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CoveragePyReport represents the output of coverage.py's "coverage json"
+type CoveragePyReport struct {
+	Meta  CoveragePyMeta            `json:"meta"`
+	Files map[string]CoveragePyFile `json:"files"`
+}
+
+// CoveragePyMeta describes the coverage.py run that wrote the report
+type CoveragePyMeta struct {
+	Version        string `json:"version"`
+	BranchCoverage bool   `json:"branch_coverage"`
+}
+
+// CoveragePyFile holds the line and branch results of one file. Branches are
+// [from, to] line pairs; a negative "to" is an exit from the code object.
+type CoveragePyFile struct {
+	ExecutedLines    []int                           `json:"executed_lines"`
+	MissingLines     []int                           `json:"missing_lines"`
+	ExcludedLines    []int                           `json:"excluded_lines"`
+	ExecutedBranches [][2]int                        `json:"executed_branches"`
+	MissingBranches  [][2]int                        `json:"missing_branches"`
+	Functions        map[string]CoveragePyRegionData `json:"functions"`
+}
+
+// CoveragePyRegionData holds the lines of a function, as reported by coverage.py 7.5 and later
+type CoveragePyRegionData struct {
+	ExecutedLines []int `json:"executed_lines"`
+	MissingLines  []int `json:"missing_lines"`
+}
+
+// detectCoveragePy recognizes coverage.py JSON, which starts with its "meta" object
+func detectCoveragePy(path string, head []byte) float64 {
+	content := string(head)
+	if strings.HasPrefix(strings.TrimSpace(content), "{") &&
+		(strings.Contains(content, `"executed_lines"`) || strings.Contains(content, `"show_contexts"`)) {
+		return ConfidenceCertain
+	}
+	return ConfidenceNone
+}
+
+// ParseCoveragePy parses a coverage.py JSON report (coverage.json)
+// Returns a Report containing coverage data for all files
+func ParseCoveragePy(filePath string) (*Report, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open coverage.py file: %w", err)
+	}
+	defer file.Close()
+
+	return decodeCoveragePy(file)
+}
+
+// decodeCoveragePy reads a coverage.py JSON report from r
+func decodeCoveragePy(r io.Reader) (*Report, error) {
+	var coveragePy CoveragePyReport
+	if err := json.NewDecoder(r).Decode(&coveragePy); err != nil {
+		return nil, fmt.Errorf("failed to parse coverage.py JSON: %w", err)
+	}
+	if coveragePy.Files == nil {
+		return nil, fmt.Errorf("not a coverage.py JSON report (no files found)")
+	}
+
+	report := &Report{
+		FileCoverage: make(map[string]*CoverageData),
+	}
+
+	for path, fileData := range coveragePy.Files {
+		report.FileCoverage[NormalizePath(path)] = fileData.toCoverageData()
+	}
+
+	return report, nil
+}
+
+// toCoverageData converts a file's results. Only executed and missing lines are
+// executable; coverage.py has no hit counts, so executed lines get one hit.
+func (f CoveragePyFile) toCoverageData() *CoverageData {
+	coverage := &CoverageData{
+		LineHits: make(map[int]int),
+	}
+
+	for _, line := range f.MissingLines {
+		coverage.LineHits[line] = 0
+	}
+	for _, line := range f.ExecutedLines {
+		coverage.LineHits[line] = 1
+	}
+	for _, hits := range coverage.LineHits {
+		coverage.TotalLines++
+		if hits > 0 {
+			coverage.CoveredLines++
+		}
+	}
+
+	if len(f.ExcludedLines) > 0 {
+		coverage.ExcludedLines = make(map[int]bool)
+		for _, line := range f.ExcludedLines {
+			coverage.ExcludedLines[line] = true
+		}
+	}
+
+	// A branch is identified by the line it jumps to
+	for _, arc := range f.ExecutedBranches {
+		coverage.addBranch(arc[0], strconv.Itoa(arc[1]), 1)
+	}
+	for _, arc := range f.MissingBranches {
+		coverage.addBranch(arc[0], strconv.Itoa(arc[1]), 0)
+	}
+
+	names := make([]string, 0, len(f.Functions))
+	for name := range f.Functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// The unnamed region is the module-level code
+		if name == "" {
+			continue
+		}
+		if function := newCoveragePyFunction(name, f.Functions[name]); function != nil {
+			coverage.Functions = append(coverage.Functions, function)
+		}
+	}
+	coverage.finishFunctions()
+
+	return coverage
+}
+
+// newCoveragePyFunction builds a function record spanning the executable lines
+// of a function region. coverage.py does not count calls, so Hits is 1 if any
+// line of the function ran. It returns nil for functions without executable lines.
+func newCoveragePyFunction(name string, region CoveragePyRegionData) *Function {
+	lines := append(append([]int(nil), region.ExecutedLines...), region.MissingLines...)
+	if len(lines) == 0 {
+		return nil
+	}
+	sort.Ints(lines)
+
+	function := &Function{Name: name, StartLine: lines[0], EndLine: lines[len(lines)-1]}
+	if len(region.ExecutedLines) > 0 {
+		function.Hits = 1
+	}
+	return function
+}
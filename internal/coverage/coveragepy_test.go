@@ -0,0 +1,99 @@
+package coverage
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseCoveragePy(t *testing.T) {
+	coveragePyContent := `{
+  "meta": {"format": 3, "version": "7.6.1", "timestamp": "2024-05-01T12:00:00", "branch_coverage": true, "show_contexts": false},
+  "files": {
+    "app/views.py": {
+      "executed_lines": [1, 3, 4, 5, 9],
+      "summary": {"covered_lines": 5, "num_statements": 7},
+      "missing_lines": [6, 10],
+      "excluded_lines": [12, 13],
+      "executed_branches": [[4, 5], [4, 9]],
+      "missing_branches": [[5, 6], [5, -3]],
+      "functions": {
+        "index": {"executed_lines": [4, 5], "missing_lines": [6], "excluded_lines": []},
+        "debug": {"executed_lines": [], "missing_lines": [10], "excluded_lines": []},
+        "": {"executed_lines": [1, 3, 9], "missing_lines": [], "excluded_lines": []}
+      }
+    }
+  },
+  "totals": {"covered_lines": 5, "num_statements": 7}
+}`
+
+	tmpfile, err := os.CreateTemp("", "coverage-*.json")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := tmpfile.Write([]byte(coveragePyContent)); err != nil {
+		t.Fatalf("failed to write test data: %v", err)
+	}
+	tmpfile.Close()
+
+	report, err := ParseCoveragePy(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fileCoverage := report.GetCoverageForFile("app/views.py")
+	if fileCoverage == nil {
+		t.Fatal("expected coverage data for app/views.py")
+	}
+	if fileCoverage.TotalLines != 7 || fileCoverage.CoveredLines != 5 {
+		t.Errorf("expected 5 of 7 lines covered, got %d of %d", fileCoverage.CoveredLines, fileCoverage.TotalLines)
+	}
+
+	// Line 2 is not executable; line 12 is excluded, not uncovered
+	if _, ok := fileCoverage.LineHits[2]; ok {
+		t.Error("expected line 2 to be non-executable")
+	}
+	if _, ok := fileCoverage.LineHits[12]; ok {
+		t.Error("expected excluded line 12 to have no hit data")
+	}
+	if !report.IsLineExcluded("app/views.py", 12) || report.IsLineExcluded("app/views.py", 6) {
+		t.Error("expected only lines 12 and 13 to be excluded")
+	}
+
+	if covered, total := report.GetBranchCoverageForLine("app/views.py", 4); covered != 2 || total != 2 {
+		t.Errorf("expected 2 of 2 branches on line 4, got %d of %d", covered, total)
+	}
+	if covered, total := report.GetBranchCoverageForLine("app/views.py", 5); covered != 0 || total != 2 {
+		t.Errorf("expected 0 of 2 branches on line 5, got %d of %d", covered, total)
+	}
+
+	// The module-level region is not a function
+	functions := report.GetFunctionsForFile("app/views.py")
+	if len(functions) != 2 {
+		t.Fatalf("expected 2 functions, got %d", len(functions))
+	}
+	if f := functions[0]; f.Name != "index" || f.StartLine != 4 || f.EndLine != 6 || f.Hits != 1 {
+		t.Errorf("unexpected function %+v", f)
+	}
+	if f := functions[1]; f.Name != "debug" || f.Hits != 0 {
+		t.Errorf("unexpected function %+v", f)
+	}
+}
+
+func TestParseCoveragePy_NotCoveragePy(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "coverage-*.json")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := tmpfile.Write([]byte(`{"name": "package.json"}`)); err != nil {
+		t.Fatalf("failed to write test data: %v", err)
+	}
+	tmpfile.Close()
+
+	if _, err := ParseCoveragePy(tmpfile.Name()); err == nil {
+		t.Error("expected error for JSON without files")
+	}
+}
@@ -0,0 +1,285 @@
+package coverage
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// GcovReport represents the JSON intermediate format of "gcov --json-format"
+// (.gcov.json.gz) and the output of "gcovr --json", which share the file and
+// line layout
+type GcovReport struct {
+	// FormatVersion and CurrentWorkingDirectory are only written by gcov;
+	// its file paths are relative to the working directory
+	FormatVersion           string     `json:"format_version"`
+	CurrentWorkingDirectory string     `json:"current_working_directory"`
+	Files                   []GcovFile `json:"files"`
+}
+
+// GcovFile holds the lines and functions of one source file
+type GcovFile struct {
+	File      string         `json:"file"`
+	Lines     []GcovLine     `json:"lines"`
+	Functions []GcovFunction `json:"functions"`
+}
+
+// GcovLine is one line with an execution count. gcov sets UnexecutedBlock when
+// the line ran but one of its basic blocks did not; gcovr marks lines that are
+// not code or are excluded by a GCOVR_EXCL marker.
+type GcovLine struct {
+	LineNumber      int          `json:"line_number"`
+	Count           int          `json:"count"`
+	UnexecutedBlock bool         `json:"unexecuted_block"`
+	Branches        []GcovBranch `json:"branches"`
+	Noncode         bool         `json:"gcovr/noncode"`
+	Excluded        bool         `json:"gcovr/excluded"`
+}
+
+// GcovBranch is one outgoing edge of the basic block ending on a line
+type GcovBranch struct {
+	Count       int  `json:"count"`
+	Fallthrough bool `json:"fallthrough"`
+	Throw       bool `json:"throw"`
+}
+
+// GcovFunction describes a function; gcov writes start_line and end_line,
+// gcovr writes lineno
+type GcovFunction struct {
+	Name           string `json:"name"`
+	DemangledName  string `json:"demangled_name"`
+	StartLine      int    `json:"start_line"`
+	EndLine        int    `json:"end_line"`
+	Lineno         int    `json:"lineno"`
+	ExecutionCount int    `json:"execution_count"`
+	Excluded       bool   `json:"gcovr/excluded"`
+}
+
+// detectGcov recognizes gcov JSON intermediate output and gcovr JSON by their
+// version keys, or failing that by their "line_number" keys
+func detectGcov(path string, head []byte) float64 {
+	content := string(head)
+	if !strings.HasPrefix(strings.TrimSpace(content), "{") {
+		return ConfidenceNone
+	}
+	if strings.Contains(content, "gcovr/format_version") || strings.Contains(content, `"gcc_version"`) {
+		return ConfidenceCertain
+	}
+	if strings.Contains(content, `"line_number"`) {
+		return ConfidenceLikely
+	}
+	return ConfidenceNone
+}
+
+// gcovParser is the registered parser of gcov and gcovr JSON. gcov writes one
+// .gcov.json.gz per object file, so several files can be merged.
+type gcovParser struct{}
+
+func (gcovParser) Name() string {
+	return "gcov"
+}
+
+func (gcovParser) Detect(path string, head []byte) float64 {
+	return detectGcov(path, head)
+}
+
+func (gcovParser) Parse(r io.Reader) (*Report, error) {
+	return decodeGcov(r)
+}
+
+func (gcovParser) ParseFiles(paths ...string) (*Report, error) {
+	return ParseGcov(paths...)
+}
+
+// ParseGcov parses gcov JSON intermediate output or gcovr JSON reports,
+// decompressing them first if they are gzipped. The coverage of a source file
+// listed in several reports, such as a header compiled into several object
+// files, is merged: a line keeps its highest count, and branch and function
+// counts are added up.
+// Returns a Report containing coverage data for all files
+func ParseGcov(filePaths ...string) (*Report, error) {
+	report := &Report{
+		FileCoverage: make(map[string]*CoverageData),
+	}
+	functions := make(map[string]map[gcovFunctionKey]*Function)
+
+	for _, filePath := range filePaths {
+		reader, err := openMaybeGzipped(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open gcov file: %w", err)
+		}
+		gcov, err := readGcovReport(reader)
+		reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filePath, err)
+		}
+		gcov.addTo(report, functions)
+	}
+
+	for _, fileCoverage := range report.FileCoverage {
+		fileCoverage.finishFunctions()
+	}
+
+	return report, nil
+}
+
+// decodeGcov reads gcov JSON intermediate output or a gcovr JSON report from r
+func decodeGcov(r io.Reader) (*Report, error) {
+	gcov, err := readGcovReport(r)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		FileCoverage: make(map[string]*CoverageData),
+	}
+	gcov.addTo(report, make(map[string]map[gcovFunctionKey]*Function))
+	for _, fileCoverage := range report.FileCoverage {
+		fileCoverage.finishFunctions()
+	}
+
+	return report, nil
+}
+
+// readGcovReport decodes a gcov or gcovr JSON report
+func readGcovReport(r io.Reader) (*GcovReport, error) {
+	var gcov GcovReport
+	if err := json.NewDecoder(r).Decode(&gcov); err != nil {
+		return nil, fmt.Errorf("failed to parse gcov JSON: %w", err)
+	}
+	if gcov.Files == nil {
+		return nil, fmt.Errorf("not a gcov JSON report (no files found)")
+	}
+	return &gcov, nil
+}
+
+// gcovFunctionKey identifies a function across reports
+type gcovFunctionKey struct {
+	name      string
+	startLine int
+}
+
+// addTo adds the coverage of the report's files to report. functions holds the
+// functions already added per file path, so that a function listed in several
+// reports is counted once with the sum of its executions.
+func (g *GcovReport) addTo(report *Report, functions map[string]map[gcovFunctionKey]*Function) {
+	for _, gcovFile := range g.Files {
+		filePath := gcovFile.File
+		if g.CurrentWorkingDirectory != "" && !filepath.IsAbs(filePath) {
+			filePath = filepath.Join(g.CurrentWorkingDirectory, filePath)
+		}
+		filePath = NormalizePath(filePath)
+
+		fileCoverage := report.FileCoverage[filePath]
+		if fileCoverage == nil {
+			fileCoverage = &CoverageData{
+				LineHits: make(map[int]int),
+			}
+			report.FileCoverage[filePath] = fileCoverage
+			functions[filePath] = make(map[gcovFunctionKey]*Function)
+		}
+		gcovFile.addTo(fileCoverage, functions[filePath])
+	}
+}
+
+// addTo adds the lines, branches and functions of a file to its coverage. A
+// line listed more than once, e.g. for each instantiation of a template or in
+// the reports of several object files, keeps its highest count.
+func (f GcovFile) addTo(coverage *CoverageData, functions map[gcovFunctionKey]*Function) {
+	// records counts the line records seen per line number
+	records := make(map[int]int)
+	for _, line := range f.Lines {
+		if line.Noncode {
+			continue
+		}
+		if line.Excluded {
+			if coverage.ExcludedLines == nil {
+				coverage.ExcludedLines = make(map[int]bool)
+			}
+			coverage.ExcludedLines[line.LineNumber] = true
+			continue
+		}
+
+		coverage.setLineHits(line.LineNumber, line.Count)
+		if line.UnexecutedBlock && line.Count > 0 {
+			if coverage.UnexecutedBlocks == nil {
+				coverage.UnexecutedBlocks = make(map[int]bool)
+			}
+			coverage.UnexecutedBlocks[line.LineNumber] = true
+		}
+
+		// Branches are identified by which record of their line they are on and
+		// their position on it, so instantiations keep separate branches while
+		// the same branch in another object file adds to the same count
+		record := records[line.LineNumber]
+		records[line.LineNumber]++
+		for j, branch := range line.Branches {
+			coverage.addBranch(line.LineNumber, strconv.Itoa(record)+","+strconv.Itoa(j), branch.Count)
+		}
+	}
+
+	for _, function := range f.Functions {
+		if function.Excluded {
+			continue
+		}
+		startLine := function.StartLine
+		if startLine == 0 {
+			startLine = function.Lineno
+		}
+		if startLine == 0 {
+			continue
+		}
+		name := function.DemangledName
+		if name == "" {
+			name = function.Name
+		}
+		key := gcovFunctionKey{name: name, startLine: startLine}
+		if existing := functions[key]; existing != nil {
+			existing.Hits += function.ExecutionCount
+			continue
+		}
+		functions[key] = &Function{
+			Name:      name,
+			StartLine: startLine,
+			EndLine:   function.EndLine,
+			Hits:      function.ExecutionCount,
+		}
+		coverage.Functions = append(coverage.Functions, functions[key])
+	}
+}
+
+// gzipMagic starts every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// openMaybeGzipped opens a file, transparently decompressing it if it is gzipped
+func openMaybeGzipped(filePath string) (io.ReadCloser, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	buffered := bufio.NewReader(file)
+	magic, _ := buffered.Peek(len(gzipMagic))
+	if string(magic) != string(gzipMagic) {
+		return struct {
+			io.Reader
+			io.Closer
+		}{buffered, file}, nil
+	}
+
+	gz, err := gzip.NewReader(buffered)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to decompress %s: %w", filePath, err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{gz, file}, nil
+}
@@ -0,0 +1,196 @@
+package coverage
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestParseGcov_Gzipped(t *testing.T) {
+	gcovContent := `{
+  "format_version": "2",
+  "gcc_version": "13.2.0",
+  "current_working_directory": "build",
+  "data_file": "main.gcda",
+  "files": [{
+    "file": "../src/main.c",
+    "functions": [
+      {"name": "main", "demangled_name": "main", "start_line": 3, "start_column": 5, "end_line": 9, "end_column": 1, "blocks": 5, "blocks_executed": 4, "execution_count": 1},
+      {"name": "unused", "demangled_name": "unused", "start_line": 11, "start_column": 5, "end_line": 13, "end_column": 1, "blocks": 2, "blocks_executed": 0, "execution_count": 0}
+    ],
+    "lines": [
+      {"line_number": 3, "function_name": "main", "count": 1, "unexecuted_block": false, "branches": []},
+      {"line_number": 4, "function_name": "main", "count": 1, "unexecuted_block": false, "branches": [
+        {"count": 1, "throw": false, "fallthrough": true, "source_block_id": 2, "destination_block_id": 3},
+        {"count": 0, "throw": false, "fallthrough": false, "source_block_id": 2, "destination_block_id": 4}
+      ]},
+      {"line_number": 5, "function_name": "main", "count": 0, "unexecuted_block": true, "branches": []},
+      {"line_number": 6, "function_name": "main", "count": 1, "unexecuted_block": true, "branches": []},
+      {"line_number": 12, "function_name": "unused", "count": 0, "unexecuted_block": true, "branches": []}
+    ]
+  }]
+}`
+
+	tmpfile, err := os.CreateTemp("", "main-*.gcov.json.gz")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	gz := gzip.NewWriter(tmpfile)
+	if _, err := gz.Write([]byte(gcovContent)); err != nil {
+		t.Fatalf("failed to write test data: %v", err)
+	}
+	gz.Close()
+	tmpfile.Close()
+
+	if format, err := DetectCoverageFormat(tmpfile.Name()); err != nil || format != "gcov" {
+		t.Errorf("expected gzipped gcov JSON to be detected as gcov, got %q (%v)", format, err)
+	}
+
+	report, err := ParseGcov(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Paths are relative to the working directory gcov ran in
+	fileCoverage := report.GetCoverageForFile("src/main.c")
+	if fileCoverage == nil {
+		t.Fatalf("expected coverage data for src/main.c, got %v", keys(report.FileCoverage))
+	}
+	if fileCoverage.TotalLines != 5 || fileCoverage.CoveredLines != 3 {
+		t.Errorf("expected 3 of 5 lines covered, got %d of %d", fileCoverage.CoveredLines, fileCoverage.TotalLines)
+	}
+
+	tests := []struct {
+		line    int
+		partial bool
+	}{
+		{3, false},
+		{4, true},  // the branch to block 4 was never taken
+		{5, false}, // never ran, so it is uncovered rather than partial
+		{6, true},  // ran, but one of its blocks did not
+	}
+	for _, tt := range tests {
+		if got := report.IsLinePartiallyCovered("src/main.c", tt.line); got != tt.partial {
+			t.Errorf("line %d: expected partially covered %v, got %v", tt.line, tt.partial, got)
+		}
+	}
+
+	functions := report.GetFunctionsForFile("src/main.c")
+	if len(functions) != 2 || functions[0].EndLine != 9 || functions[1].Name != "unused" || functions[1].Hits != 0 {
+		t.Errorf("unexpected functions %+v", functions)
+	}
+}
+
+func TestParseGcov_Gcovr(t *testing.T) {
+	gcovrContent := `{
+  "gcovr/format_version": "0.6",
+  "files": [{
+    "file": "src/driver.c",
+    "lines": [
+      {"line_number": 1, "count": 0, "branches": [], "gcovr/noncode": true},
+      {"line_number": 2, "count": 4, "branches": [{"count": 4, "fallthrough": true, "throw": false}, {"count": 2, "fallthrough": false, "throw": false}]},
+      {"line_number": 3, "count": 0, "branches": []},
+      {"line_number": 4, "count": 0, "branches": [], "gcovr/excluded": true}
+    ],
+    "functions": [
+      {"name": "driver_init", "lineno": 2, "execution_count": 4}
+    ]
+  }]
+}`
+
+	tmpfile, err := os.CreateTemp("", "gcovr-*.json")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := tmpfile.Write([]byte(gcovrContent)); err != nil {
+		t.Fatalf("failed to write test data: %v", err)
+	}
+	tmpfile.Close()
+
+	report, err := ParseGcov(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fileCoverage := report.GetCoverageForFile("src/driver.c")
+	if fileCoverage == nil {
+		t.Fatal("expected coverage data for src/driver.c")
+	}
+
+	// Non-code lines are not executable and excluded lines are ignored
+	if fileCoverage.TotalLines != 2 || fileCoverage.CoveredLines != 1 {
+		t.Errorf("expected 1 of 2 lines covered, got %d of %d", fileCoverage.CoveredLines, fileCoverage.TotalLines)
+	}
+	if !report.IsLineExcluded("src/driver.c", 4) {
+		t.Error("expected line 4 to be excluded")
+	}
+	if covered, total := report.GetBranchCoverageForLine("src/driver.c", 2); covered != 2 || total != 2 {
+		t.Errorf("expected 2 of 2 branches on line 2, got %d of %d", covered, total)
+	}
+
+	functions := report.GetFunctionsForFile("src/driver.c")
+	if len(functions) != 1 || functions[0].StartLine != 2 || functions[0].Hits != 4 {
+		t.Errorf("unexpected functions %+v", functions)
+	}
+}
+
+func TestParseGcov_MergeObjectFiles(t *testing.T) {
+	// util.h is compiled into both objects; each took one of its branches
+	objectReport := func(source string, headerCount, taken, notTaken int) string {
+		return `{
+  "format_version": "2",
+  "gcc_version": "13.2.0",
+  "current_working_directory": "build",
+  "files": [{
+    "file": "../src/` + source + `",
+    "functions": [{"name": "main", "demangled_name": "main", "start_line": 1, "end_line": 3, "execution_count": 1}],
+    "lines": [{"line_number": 2, "count": 1, "unexecuted_block": false, "branches": []}]
+  }, {
+    "file": "../include/util.h",
+    "functions": [{"name": "clamp", "demangled_name": "clamp", "start_line": 1, "end_line": 4, "execution_count": ` + strconv.Itoa(headerCount) + `}],
+    "lines": [
+      {"line_number": 2, "count": ` + strconv.Itoa(headerCount) + `, "unexecuted_block": false, "branches": [
+        {"count": ` + strconv.Itoa(taken) + `, "throw": false, "fallthrough": true},
+        {"count": ` + strconv.Itoa(notTaken) + `, "throw": false, "fallthrough": false}
+      ]},
+      {"line_number": 3, "count": 0, "unexecuted_block": true, "branches": []}
+    ]
+  }]
+}`
+	}
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "a.gcov.json", objectReport("a.c", 3, 3, 0))
+	writeTestFile(t, dir, "b.gcov.json", objectReport("b.c", 2, 0, 2))
+	paths := []string{filepath.Join(dir, "a.gcov.json"), filepath.Join(dir, "b.gcov.json")}
+
+	report, err := ParseCoverage(paths, "auto")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if report.GetCoverageForFile("src/a.c") == nil || report.GetCoverageForFile("src/b.c") == nil {
+		t.Errorf("expected coverage for both sources, got %v", keys(report.FileCoverage))
+	}
+
+	header := report.GetCoverageForFile("include/util.h")
+	if header == nil {
+		t.Fatalf("expected coverage data for include/util.h, got %v", keys(report.FileCoverage))
+	}
+	if header.LineHits[2] != 3 || header.TotalLines != 2 || header.CoveredLines != 1 {
+		t.Errorf("expected line 2 with 3 hits and 1 of 2 lines covered, got %v (%d of %d)",
+			header.LineHits, header.CoveredLines, header.TotalLines)
+	}
+	if covered, total := header.BranchCoverage(2); covered != 2 || total != 2 {
+		t.Errorf("expected both branches taken across the objects, got %d of %d", covered, total)
+	}
+	if len(header.Functions) != 1 || header.Functions[0].Hits != 5 {
+		t.Errorf("expected clamp once with 5 executions, got %+v", header.Functions)
+	}
+}
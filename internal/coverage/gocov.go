@@ -3,9 +3,13 @@ package coverage
 import (
 	"bufio"
 	"fmt"
+	"go/scanner"
+	"go/token"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -50,10 +54,8 @@ func ConvertGoCoverageToLCOV(coverageOutPath, outputPath string) error {
 			continue
 		}
 
-		filePath := fileParts[0]
-		// Remove the module path prefix
-		filePath = strings.TrimPrefix(filePath, "github.com/swantron/difftron/")
-		filePath = strings.TrimPrefix(filePath, "github.com\\swantron\\difftron\\") // Windows
+		// Map the import path to the repository file
+		filePath := normalizeGoFilePath(fileParts[0])
 
 		// Get coverage percentage
 		coverageStr := parts[len(parts)-1]
@@ -89,177 +91,415 @@ func ConvertGoCoverageToLCOV(coverageOutPath, outputPath string) error {
 	return os.WriteFile(outputPath, []byte(strings.Join(lcovLines, "\n")), 0644)
 }
 
-// ParseGoCoverage parses Go's native coverage.out format directly
-// Supports both text format (mode: set/count) and binary format
-// For text format, parses line-by-line coverage ranges
-func ParseGoCoverage(coverageOutPath string) (*Report, error) {
-	// Try to parse as text format first
-	report, err := parseGoCoverageText(coverageOutPath)
-	if err == nil {
-		return report, nil
-	}
+// GoProfile holds the blocks of one file of a Go text coverage profile
+type GoProfile struct {
+	FileName string
+	Mode     string
+	Blocks   []GoProfileBlock
+}
 
-	// If text parsing fails, fall back to function-level parsing via go tool cover
-	return parseGoCoverageFunc(coverageOutPath)
+// GoProfileBlock is a block of a Go coverage profile: a range of source with the
+// number of statements in it and how often it ran. Lines and columns are 1-based
+// and the end column is exclusive.
+type GoProfileBlock struct {
+	StartLine int
+	StartCol  int
+	EndLine   int
+	EndCol    int
+	NumStmt   int
+	Count     int
 }
 
-// parseGoCoverageText parses Go coverage.out text format (mode: set or mode: count)
-// Format: mode: set
-//
-//	file:startLine.startCol,endLine.endCol count statements
-func parseGoCoverageText(coverageOutPath string) (*Report, error) {
-	file, err := os.Open(coverageOutPath)
+// goBlockRange identifies a block of a file by its position
+type goBlockRange struct {
+	startLine, startCol, endLine, endCol int
+}
+
+// ParseGoCoverage parses one or more Go text coverage profiles (coverage.out),
+// such as the profiles of sharded "go test -coverprofile" runs, merged as
+// described for ParseGoProfiles. A line is executable if it holds statements
+// and covered if any of them ran; a covered line that also holds statements of
+// a block that never ran is partly covered (CoverageData.UnexecutedBlocks).
+// A single profile that is not in text format falls back to function-level
+// parsing via go tool cover.
+func ParseGoCoverage(coverageOutPaths ...string) (*Report, error) {
+	if len(coverageOutPaths) == 0 {
+		return nil, fmt.Errorf("no Go coverage profile given")
+	}
+
+	profiles, err := ParseGoProfiles(coverageOutPaths...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open coverage file: %w", err)
+		if len(coverageOutPaths) == 1 {
+			return parseGoCoverageFunc(coverageOutPaths[0])
+		}
+		return nil, err
 	}
-	defer file.Close()
 
+	return goProfilesReport(profiles), nil
+}
+
+// goProfilesReport converts the blocks of Go profiles into line coverage
+func goProfilesReport(profiles []*GoProfile) *Report {
 	report := &Report{
 		FileCoverage: make(map[string]*CoverageData),
 	}
 
-	scanner := bufio.NewScanner(file)
-	var mode string
-	lineNum := 0
+	for _, profile := range profiles {
+		// Normalize file path (map the import path to the repository file)
+		filePath := normalizeGoFilePath(profile.FileName)
+		fileCoverage := report.FileCoverage[filePath]
+		if fileCoverage == nil {
+			fileCoverage = &CoverageData{
+				LineHits: make(map[int]int),
+			}
+			report.FileCoverage[filePath] = fileCoverage
+		}
+		profile.addTo(fileCoverage, readGoSource(filePath))
+	}
+
+	return report
+}
+
+// goProfileParser is the registered parser of Go text profiles
+type goProfileParser struct{}
+
+func (goProfileParser) Name() string {
+	return "go"
+}
+
+// Detect recognizes profiles by their mode line, and guesses that other .out
+// files without LCOV markers are Go coverage too
+func (goProfileParser) Detect(path string, head []byte) float64 {
+	if head == nil {
+		return ConfidenceNone
+	}
+	content := string(head)
+	if strings.HasPrefix(strings.TrimSpace(content), "mode:") {
+		return ConfidenceCertain
+	}
+	if filepath.Ext(path) == ".out" && !strings.Contains(content, "SF:") && !strings.Contains(content, "TN:") {
+		return ConfidenceLikely
+	}
+	return ConfidenceNone
+}
+
+func (goProfileParser) Parse(r io.Reader) (*Report, error) {
+	merger := newGoProfileMerger()
+	if err := merger.add("coverage profile", r); err != nil {
+		return nil, err
+	}
+	return goProfilesReport(merger.profiles()), nil
+}
+
+func (goProfileParser) ParseFiles(paths ...string) (*Report, error) {
+	return ParseGoCoverage(paths...)
+}
+
+// ParseGoProfiles parses Go text coverage profiles and merges them the way
+// go tool cover does: a block listed more than once, in one profile or across
+// profiles, ran if any listing ran in "set" mode and has the sum of its counts
+// in "count" and "atomic" mode. All profiles must be written in the same mode.
+// Returns one profile per file, sorted by file name, with blocks in source order.
+//
+// Format: mode: set
+//
+//	file:startLine.startCol,endLine.endCol numberOfStatements count
+func ParseGoProfiles(coverageOutPaths ...string) ([]*GoProfile, error) {
+	merger := newGoProfileMerger()
+	for _, coverageOutPath := range coverageOutPaths {
+		file, err := os.Open(coverageOutPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open coverage file: %w", err)
+		}
+		err = merger.add(coverageOutPath, file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return merger.profiles(), nil
+}
+
+// goProfileMerger merges the blocks of Go text profiles
+type goProfileMerger struct {
+	mode, modeSource string
+	byFile           map[string]*GoProfile
+	blockIndex       map[string]map[goBlockRange]int
+}
 
+func newGoProfileMerger() *goProfileMerger {
+	return &goProfileMerger{
+		byFile:     make(map[string]*GoProfile),
+		blockIndex: make(map[string]map[goBlockRange]int),
+	}
+}
+
+// add reads a text profile from r and merges its blocks; name identifies the
+// profile in errors
+func (m *goProfileMerger) add(name string, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	fileMode := ""
+	blocks := 0
 	for scanner.Scan() {
-		lineNum++
 		line := strings.TrimSpace(scanner.Text())
-
-		// Skip empty lines
 		if line == "" {
 			continue
 		}
 
-		// Parse mode line: "mode: set" or "mode: count"
+		// Parse mode line: "mode: set", "mode: count" or "mode: atomic"
 		if strings.HasPrefix(line, "mode:") {
-			mode = strings.TrimSpace(strings.TrimPrefix(line, "mode:"))
+			fileMode = strings.TrimSpace(strings.TrimPrefix(line, "mode:"))
+			if m.mode == "" {
+				m.mode, m.modeSource = fileMode, name
+			} else if fileMode != m.mode {
+				return fmt.Errorf("cannot merge %s in mode %q with %s in mode %q", name, fileMode, m.modeSource, m.mode)
+			}
 			continue
 		}
 
-		// Parse coverage line: file:startLine.startCol,endLine.endCol count statements
-		// Example: github.com/swantron/difftron/internal/hunk/parser.go:42.0,43.0 1 0
-		parts := strings.Fields(line)
-		if len(parts) < 2 {
+		fileName, block, ok := parseGoProfileLine(line)
+		if !ok {
 			continue
 		}
+		blocks++
 
-		// Parse file and line range
-		fileAndRange := parts[0]
-		colonIdx := strings.LastIndex(fileAndRange, ":")
-		if colonIdx == -1 {
-			continue
+		profile := m.byFile[fileName]
+		if profile == nil {
+			profile = &GoProfile{FileName: fileName}
+			m.byFile[fileName] = profile
+			m.blockIndex[fileName] = make(map[goBlockRange]int)
 		}
-
-		filePath := fileAndRange[:colonIdx]
-		rangeStr := fileAndRange[colonIdx+1:]
-
-		// Parse range: startLine.startCol,endLine.endCol
-		commaIdx := strings.Index(rangeStr, ",")
-		if commaIdx == -1 {
+		key := goBlockRange{block.StartLine, block.StartCol, block.EndLine, block.EndCol}
+		i, exists := m.blockIndex[fileName][key]
+		if !exists {
+			m.blockIndex[fileName][key] = len(profile.Blocks)
+			profile.Blocks = append(profile.Blocks, block)
 			continue
 		}
 
-		startStr := rangeStr[:commaIdx]
-		endStr := rangeStr[commaIdx+1:]
-
-		// Extract line numbers (ignore column numbers)
-		startDotIdx := strings.Index(startStr, ".")
-		if startDotIdx != -1 {
-			startStr = startStr[:startDotIdx]
+		existing := &profile.Blocks[i]
+		if existing.NumStmt != block.NumStmt {
+			return fmt.Errorf("inconsistent statement count for %s:%d.%d in %s: %d and %d",
+				fileName, block.StartLine, block.StartCol, name, existing.NumStmt, block.NumStmt)
 		}
-		endDotIdx := strings.Index(endStr, ".")
-		if endDotIdx != -1 {
-			endStr = endStr[:endDotIdx]
+		if m.mode == "set" {
+			existing.Count |= block.Count
+		} else {
+			existing.Count += block.Count
 		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading coverage file: %w", err)
+	}
 
-		startLine, err := strconv.Atoi(startStr)
-		if err != nil {
+	// If we didn't see a mode line, this might not be a text format file
+	if fileMode == "" && blocks == 0 {
+		return fmt.Errorf("not a valid Go coverage text format (no mode line found in %s)", name)
+	}
+	return nil
+}
+
+// profiles returns the merged profiles, sorted by file name, with blocks in source order
+func (m *goProfileMerger) profiles() []*GoProfile {
+	result := make([]*GoProfile, 0, len(m.byFile))
+	for _, profile := range m.byFile {
+		profile.Mode = m.mode
+		sort.Slice(profile.Blocks, func(i, j int) bool {
+			a, b := profile.Blocks[i], profile.Blocks[j]
+			if a.StartLine != b.StartLine {
+				return a.StartLine < b.StartLine
+			}
+			return a.StartCol < b.StartCol
+		})
+		result = append(result, profile)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].FileName < result[j].FileName
+	})
+	return result
+}
+
+// parseGoProfileLine parses a block line of a text profile
+// Example: github.com/swantron/difftron/internal/hunk/parser.go:42.30,45.2 3 1
+func parseGoProfileLine(line string) (string, GoProfileBlock, bool) {
+	var block GoProfileBlock
+
+	colonIdx := strings.LastIndex(line, ":")
+	if colonIdx == -1 {
+		return "", block, false
+	}
+	fields := strings.Fields(line[colonIdx+1:])
+	if len(fields) != 3 {
+		return "", block, false
+	}
+	start, end, found := strings.Cut(fields[0], ",")
+	if !found {
+		return "", block, false
+	}
+
+	var ok [4]bool
+	block.StartLine, block.StartCol, ok[0] = parseGoPosition(start)
+	block.EndLine, block.EndCol, ok[1] = parseGoPosition(end)
+	numStmt, err := strconv.Atoi(fields[1])
+	ok[2] = err == nil
+	count, err := strconv.Atoi(fields[2])
+	ok[3] = err == nil
+	if ok != [4]bool{true, true, true, true} {
+		return "", block, false
+	}
+	block.NumStmt = numStmt
+	block.Count = count
+
+	return line[:colonIdx], block, true
+}
+
+// parseGoPosition parses a "line.column" position
+func parseGoPosition(s string) (int, int, bool) {
+	lineStr, colStr, found := strings.Cut(s, ".")
+	if !found {
+		return 0, 0, false
+	}
+	line, err := strconv.Atoi(lineStr)
+	if err != nil {
+		return 0, 0, false
+	}
+	col, err := strconv.Atoi(colStr)
+	if err != nil {
+		return 0, 0, false
+	}
+	return line, col, true
+}
+
+// addTo adds the blocks of a profile to the coverage of its file. Each line
+// holding statements gets the highest count of the blocks with statements on
+// it, and is marked as partly covered if one of them never ran. source holds
+// the lines of the file, or is nil if it could not be read.
+func (p *GoProfile) addTo(coverage *CoverageData, source []string) {
+	unexecuted := make(map[int]bool)
+	for _, block := range p.Blocks {
+		// Blocks without statements, such as empty function bodies, are not executable
+		if block.NumStmt == 0 {
 			continue
 		}
-		endLine, err := strconv.Atoi(endStr)
-		if err != nil {
-			continue
+		for _, lineNum := range block.statementLines(source) {
+			coverage.setLineHits(lineNum, block.Count)
+			if block.Count == 0 {
+				unexecuted[lineNum] = true
+			}
 		}
+	}
 
-		// Parse count (number of times this range was executed)
-		count, err := strconv.Atoi(parts[1])
-		if err != nil {
-			continue
+	for lineNum := range unexecuted {
+		if coverage.LineHits[lineNum] > 0 {
+			if coverage.UnexecutedBlocks == nil {
+				coverage.UnexecutedBlocks = make(map[int]bool)
+			}
+			coverage.UnexecutedBlocks[lineNum] = true
 		}
+	}
+}
 
-		// Normalize file path (remove module prefix)
-		filePath = normalizeGoFilePath(filePath)
-
-		// Get or create file coverage
-		fileCoverage := report.FileCoverage[filePath]
-		if fileCoverage == nil {
-			fileCoverage = &CoverageData{
-				LineHits: make(map[int]int),
-			}
-			report.FileCoverage[filePath] = fileCoverage
+// statementLines returns the lines holding the block's statements: the lines
+// with Go tokens between its start and end columns, as found by go/scanner so
+// that comment markers inside strings and /* */ comments are told apart. Lines
+// that only hold a brace of the block, such as the "{" ending an if
+// statement's line and the closing "}", and blank and comment lines are left
+// out. Without source, or if the source does not match the block, every line
+// of the block is returned.
+func (b GoProfileBlock) statementLines(source []string) []int {
+	allLines := func() []int {
+		lines := make([]int, 0, b.EndLine-b.StartLine+1)
+		for lineNum := b.StartLine; lineNum <= b.EndLine; lineNum++ {
+			lines = append(lines, lineNum)
 		}
+		return lines
+	}
+	if source == nil || b.StartLine < 1 || b.EndLine < b.StartLine || b.EndLine > len(source) {
+		return allLines()
+	}
 
-		// Mark lines in range as covered (if count > 0)
-		// For mode: set, count is 0 or 1
-		// For mode: count, count is the actual execution count
-		for line := startLine; line <= endLine; line++ {
-			// Track if this line was already seen (for TotalLines counting)
-			wasAlreadySeen := fileCoverage.LineHits[line] > 0
-
-			if count > 0 {
-				// Update hit count (take maximum if already set, or sum for count mode)
-				if existingCount, exists := fileCoverage.LineHits[line]; exists {
-					// If mode is count, we might want to sum, but typically we take max
-					// For now, take maximum to avoid double counting
-					if count > existingCount {
-						fileCoverage.LineHits[line] = count
-					}
-				} else {
-					fileCoverage.LineHits[line] = count
-					// New line covered
-					fileCoverage.CoveredLines++
-				}
+	blockText := make([]string, 0, b.EndLine-b.StartLine+1)
+	for lineNum := b.StartLine; lineNum <= b.EndLine; lineNum++ {
+		text := source[lineNum-1]
+		if lineNum == b.EndLine && b.EndCol >= 1 && b.EndCol-1 < len(text) {
+			text = text[:b.EndCol-1]
+		}
+		if lineNum == b.StartLine && b.StartCol >= 1 {
+			if b.StartCol-1 > len(text) {
+				text = ""
+			} else {
+				text = text[b.StartCol-1:]
 			}
+		}
+		blockText = append(blockText, text)
+	}
 
-			// Count total lines (only once per line)
-			if !wasAlreadySeen {
-				fileCoverage.TotalLines++
-			}
+	src := []byte(strings.Join(blockText, "\n"))
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, 0)
+
+	code := make(map[int]bool)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		// Skip braces and the semicolons inserted at line ends
+		if tok == token.LBRACE || tok == token.RBRACE || (tok == token.SEMICOLON && lit == "\n") {
+			continue
+		}
+		// A raw string literal may span several lines
+		line := b.StartLine + file.Line(pos) - 1
+		for i := 0; i <= strings.Count(lit, "\n"); i++ {
+			code[line+i] = true
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading coverage file: %w", err)
+	var lines []int
+	for lineNum := b.StartLine; lineNum <= b.EndLine; lineNum++ {
+		if code[lineNum] {
+			lines = append(lines, lineNum)
+		}
 	}
 
-	// If we didn't see a mode line, this might not be a text format file
-	if mode == "" && len(report.FileCoverage) == 0 {
-		return nil, fmt.Errorf("not a valid Go coverage text format (no mode line found)")
+	// The file changed since the profile was written
+	if len(lines) == 0 {
+		return allLines()
 	}
+	return lines
+}
 
-	return report, nil
+// readGoSource returns the lines of a Go file given by its repository path, or
+// nil if it cannot be read
+func readGoSource(filePath string) []string {
+	root := goModuleRoot()
+	if root == "" || filepath.IsAbs(filePath) {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(filePath)))
+	if err != nil {
+		return nil
+	}
+	return strings.Split(string(data), "\n")
 }
 
-// normalizeGoFilePath normalizes Go file paths by removing module prefixes
+// normalizeGoFilePath maps the import path of a file in a Go coverage profile,
+// e.g. github.com/org/repo/sub/pkg/file.go, to its path in the repository using
+// the modules declared by go.mod and go.work files. Paths outside the repository's
+// modules, such as absolute paths of packages outside any module, are normalized
+// as usual.
 func normalizeGoFilePath(filePath string) string {
 	// Convert Windows paths to forward slashes
 	filePath = filepath.ToSlash(filePath)
 
-	// Try to detect and remove common module prefixes
-	// This is a heuristic - actual module path detection would require go.mod parsing
-	commonPrefixes := []string{
-		"github.com/swantron/difftron/",
-		"github.com\\swantron\\difftron\\",
+	if relPath, ok := goModuleFilePath(filePath, goModules()); ok {
+		return relPath
 	}
 
-	for _, prefix := range commonPrefixes {
-		if strings.HasPrefix(filePath, prefix) {
-			return strings.TrimPrefix(filePath, prefix)
-		}
-	}
-
-	return filePath
+	return NormalizePath(filePath)
 }
 
 // parseGoCoverageFunc parses Go coverage using go tool cover -func (function-level fallback)
@@ -333,58 +573,3 @@ func parseGoCoverageFunc(coverageOutPath string) (*Report, error) {
 
 	return report, nil
 }
-
-// DetectCoverageFormat detects if a coverage file is LCOV or Go format
-func DetectCoverageFormat(filePath string) (string, error) {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return "", err
-	}
-
-	// Read first few bytes to check format without reading entire file
-	content := string(data)
-	if len(content) > 1000 {
-		content = content[:1000] // Only check first 1KB
-	}
-
-	trimmed := strings.TrimSpace(content)
-
-	// Check for Cobertura XML format
-	if strings.HasPrefix(trimmed, "<?xml") || strings.Contains(content, "<coverage") {
-		// Check if it's Cobertura format
-		if strings.Contains(content, "cobertura") || strings.Contains(content, "coverage") {
-			// Verify it has Cobertura-specific elements
-			if strings.Contains(content, "<package") || strings.Contains(content, "<class") {
-				return "cobertura", nil
-			}
-		}
-	}
-
-	// Check for LCOV format markers (must be first)
-	if strings.HasPrefix(trimmed, "TN:") ||
-		strings.HasPrefix(trimmed, "SF:") ||
-		(strings.Contains(content, "SF:") && strings.Contains(content, "DA:")) {
-		return "lcov", nil
-	}
-
-	// Check for Go coverage format
-	// Go coverage.out files start with "mode:" on first line
-	if strings.HasPrefix(trimmed, "mode:") {
-		return "go", nil
-	}
-
-	// If file extension is .out and starts with binary-looking data or "mode:", assume Go
-	if filepath.Ext(filePath) == ".out" {
-		// Check if it looks like Go coverage (starts with "mode:" or is binary)
-		if strings.HasPrefix(trimmed, "mode:") {
-			return "go", nil
-		}
-		// If it's not text (doesn't contain common text markers), assume binary Go format
-		if !strings.Contains(content, "SF:") && !strings.Contains(content, "TN:") {
-			return "go", nil
-		}
-	}
-
-	// Default to LCOV
-	return "lcov", nil
-}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
 	"testing"
@@ -54,6 +55,66 @@ func TestDetectCoverageFormat_LCOV(t *testing.T) {
 			filename: "coverage.out",
 			expected: "go", // .out files default to go format if no LCOV markers
 		},
+		{
+			name:     "JaCoCo XML",
+			content:  "<?xml version=\"1.0\" encoding=\"UTF-8\" standalone=\"yes\"?><!DOCTYPE report PUBLIC \"-//JACOCO//DTD Report 1.1//EN\" \"report.dtd\"><report name=\"coverage\"><sessioninfo id=\"s\" start=\"1\" dump=\"2\"/><package name=\"com/example\"><class name=\"com/example/App\"/></package></report>",
+			filename: "jacoco.xml",
+			expected: "jacoco",
+		},
+		{
+			name:     "Cobertura XML",
+			content:  "<?xml version=\"1.0\"?><coverage line-rate=\"1\"><packages><package name=\"app\"><classes><class filename=\"app.py\"/></classes></package></packages></coverage>",
+			filename: "coverage.xml",
+			expected: "cobertura",
+		},
+		{
+			name:     "Clover XML",
+			content:  "<?xml version=\"1.0\" encoding=\"UTF-8\"?><coverage generated=\"1700000000\"><project timestamp=\"1700000000\"><package name=\"App\"><file name=\"/repo/src/Foo.php\"><line num=\"3\" type=\"stmt\" count=\"1\"/></file></package></project></coverage>",
+			filename: "clover.xml",
+			expected: "clover",
+		},
+		{
+			name:     "SonarQube generic XML",
+			content:  "<coverage version=\"1\"><file path=\"src/app.js\"><lineToCover lineNumber=\"3\" covered=\"true\"/></file></coverage>",
+			filename: "sonar-coverage.xml",
+			expected: "sonar",
+		},
+		{
+			name:     "Istanbul JSON",
+			content:  `{"/repo/src/app.js": {"path": "/repo/src/app.js", "statementMap": {}, "fnMap": {}, "branchMap": {}, "s": {}, "f": {}, "b": {}}}`,
+			filename: "coverage-final.json",
+			expected: "istanbul",
+		},
+		{
+			name:     "coverage.py JSON",
+			content:  `{"meta": {"format": 3, "version": "7.6.1", "timestamp": "2024-01-01T00:00:00", "branch_coverage": false, "show_contexts": false}, "files": {"app.py": {"executed_lines": [1], "missing_lines": [], "excluded_lines": []}}}`,
+			filename: "coverage.json",
+			expected: "coveragepy",
+		},
+		{
+			name:     "gcov JSON",
+			content:  `{"format_version": "2", "gcc_version": "13.2.0", "current_working_directory": "/repo/build", "data_file": "main.gcda", "files": []}`,
+			filename: "main.gcov.json",
+			expected: "gcov",
+		},
+		{
+			name:     "gcovr JSON",
+			content:  `{"files": [{"file": "src/main.c", "lines": [{"line_number": 3, "count": 1, "branches": []}], "functions": []}], "gcovr/format_version": "0.6"}`,
+			filename: "gcovr.json",
+			expected: "gcov",
+		},
+		{
+			name:     "llvm-cov export JSON",
+			content:  `{"data":[{"files":[{"branches":[],"expansions":[],"filename":"/repo/src/main.rs","segments":[[1,11,1,true,true,false]]}]}],"type":"llvm.coverage.json.export","version":"2.0.1"}`,
+			filename: "coverage.json",
+			expected: "llvmcov",
+		},
+		{
+			name:     "Go binary coverage meta-data file",
+			content:  "\x00cvm\x01\x00\x00\x00",
+			filename: "covmeta.0123456789abcdef",
+			expected: "gocoverdir",
+		},
 		{
 			name:     "Default to LCOV for unknown",
 			content:  "some unknown format",
@@ -286,3 +347,204 @@ github.com/swantron/difftron/internal/coverage/lcov.go:31.16,33.3 1 1
 
 	return tmpfile.Name()
 }
+
+// writeGoProfile writes a text profile to a temp file and returns its path
+func writeGoProfile(t *testing.T, content string) string {
+	t.Helper()
+	profile := filepath.Join(t.TempDir(), "coverage.out")
+	if err := os.WriteFile(profile, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write profile: %v", err)
+	}
+	return profile
+}
+
+func TestParseGoCoverage_Statements(t *testing.T) {
+	// The file is not in the repository, so every line of a block holds its statements
+	profile := writeGoProfile(t, `mode: count
+example.com/app/main.go:5.13,7.23 2 4
+example.com/app/main.go:7.23,9.3 1 0
+example.com/app/main.go:10.2,10.17 1 4
+example.com/app/main.go:12.20,12.21 0 0
+`)
+
+	report, err := ParseGoCoverage(profile)
+	if err != nil {
+		t.Fatalf("failed to parse profile: %v", err)
+	}
+	fileCoverage := report.FileCoverage["example.com/app/main.go"]
+	if fileCoverage == nil {
+		t.Fatalf("expected coverage for example.com/app/main.go, got files %v", keys(report.FileCoverage))
+	}
+
+	expectedHits := map[int]int{5: 4, 6: 4, 7: 4, 8: 0, 9: 0, 10: 4}
+	if len(fileCoverage.LineHits) != len(expectedHits) {
+		t.Errorf("expected %d executable lines, got %d", len(expectedHits), len(fileCoverage.LineHits))
+	}
+	for line, expected := range expectedHits {
+		if hits := fileCoverage.LineHits[line]; hits != expected {
+			t.Errorf("expected %d hits on line %d, got %d", expected, line, hits)
+		}
+	}
+	// Line 7 is shared by both blocks but only counted once
+	if fileCoverage.TotalLines != 6 || fileCoverage.CoveredLines != 4 {
+		t.Errorf("expected 4 of 6 lines covered, got %d of %d", fileCoverage.CoveredLines, fileCoverage.TotalLines)
+	}
+	if !fileCoverage.UnexecutedBlocks[7] || len(fileCoverage.UnexecutedBlocks) != 1 {
+		t.Errorf("expected only line 7 to be partly covered, got %v", fileCoverage.UnexecutedBlocks)
+	}
+	// The empty block on line 12 has no statements
+	if _, ok := fileCoverage.LineHits[12]; ok {
+		t.Error("expected the empty block on line 12 not to be executable")
+	}
+}
+
+func TestGoProfile_StatementLines(t *testing.T) {
+	source := strings.Split(`package main
+
+import "os"
+
+func main() {
+	if len(os.Args) > 1 {
+		println("args")
+
+		// a comment
+	} else { println("none") }
+	println("done")
+}
+`, "\n")
+
+	profile := &GoProfile{
+		FileName: "example.com/app/main.go",
+		Mode:     "set",
+		Blocks: []GoProfileBlock{
+			{StartLine: 5, StartCol: 13, EndLine: 6, EndCol: 23, NumStmt: 1, Count: 1},
+			{StartLine: 6, StartCol: 23, EndLine: 10, EndCol: 3, NumStmt: 1, Count: 0},
+			{StartLine: 10, StartCol: 9, EndLine: 10, EndCol: 28, NumStmt: 1, Count: 1},
+			{StartLine: 11, StartCol: 2, EndLine: 11, EndCol: 17, NumStmt: 1, Count: 1},
+		},
+	}
+	coverage := &CoverageData{LineHits: make(map[int]int)}
+	profile.addTo(coverage, source)
+
+	// Lines 5, 8 and 9 and the braces on lines 6 and 10 hold no statements of their blocks
+	expectedHits := map[int]int{6: 1, 7: 0, 10: 1, 11: 1}
+	if len(coverage.LineHits) != len(expectedHits) {
+		t.Errorf("expected lines %v, got %v", expectedHits, coverage.LineHits)
+	}
+	for line, expected := range expectedHits {
+		hits, ok := coverage.LineHits[line]
+		if !ok || hits != expected {
+			t.Errorf("expected %d hits on line %d, got %d (present: %v)", expected, line, hits, ok)
+		}
+	}
+	if len(coverage.UnexecutedBlocks) != 0 {
+		t.Errorf("expected no partly covered lines, got %v", coverage.UnexecutedBlocks)
+	}
+}
+
+func TestGoProfile_StatementLinesComments(t *testing.T) {
+	source := strings.Split(`package main
+
+func main() {
+	/* a block comment
+	   spanning lines */
+	url := "http://example.com" // trailing comment
+	/* inline */ println(url)
+	query := `+"`"+`
+// not a comment
+`+"`"+`
+	println(query) /* done */
+}
+`, "\n")
+
+	profile := &GoProfile{
+		FileName: "example.com/app/main.go",
+		Mode:     "set",
+		Blocks: []GoProfileBlock{
+			{StartLine: 3, StartCol: 13, EndLine: 12, EndCol: 2, NumStmt: 4, Count: 1},
+		},
+	}
+	coverage := &CoverageData{LineHits: make(map[int]int)}
+	profile.addTo(coverage, source)
+
+	// The block comment on lines 4-5 holds no statement; the raw string on
+	// lines 8-10 does, though line 9 looks like a comment
+	expectedHits := map[int]int{6: 1, 7: 1, 8: 1, 9: 1, 10: 1, 11: 1}
+	if !reflect.DeepEqual(coverage.LineHits, expectedHits) {
+		t.Errorf("expected lines %v, got %v", expectedHits, coverage.LineHits)
+	}
+}
+
+func TestParseGoProfiles_Merge(t *testing.T) {
+	shard1 := writeGoProfile(t, `mode: atomic
+example.com/app/a.go:3.10,5.2 2 1
+example.com/app/a.go:7.10,9.2 1 0
+example.com/app/b.go:1.1,1.10 1 0
+`)
+	shard2 := writeGoProfile(t, `mode: atomic
+example.com/app/a.go:7.10,9.2 1 5
+example.com/app/a.go:3.10,5.2 2 2
+`)
+
+	profiles, err := ParseGoProfiles(shard1, shard2)
+	if err != nil {
+		t.Fatalf("failed to merge profiles: %v", err)
+	}
+	if len(profiles) != 2 || profiles[0].FileName != "example.com/app/a.go" || profiles[1].FileName != "example.com/app/b.go" {
+		t.Fatalf("expected profiles for a.go and b.go, got %d", len(profiles))
+	}
+	blocks := profiles[0].Blocks
+	if len(blocks) != 2 || blocks[0].StartLine != 3 || blocks[1].StartLine != 7 {
+		t.Fatalf("expected 2 blocks in source order, got %+v", blocks)
+	}
+	// Counts add up in atomic mode
+	if blocks[0].Count != 3 || blocks[1].Count != 5 {
+		t.Errorf("expected counts 3 and 5, got %d and %d", blocks[0].Count, blocks[1].Count)
+	}
+	if profiles[0].Mode != "atomic" {
+		t.Errorf("expected mode atomic, got %s", profiles[0].Mode)
+	}
+
+	report, err := ParseGoCoverage(shard1, shard2)
+	if err != nil {
+		t.Fatalf("failed to parse profiles: %v", err)
+	}
+	if hits := report.FileCoverage["example.com/app/a.go"].LineHits[8]; hits != 5 {
+		t.Errorf("expected 5 hits on line 8, got %d", hits)
+	}
+}
+
+func TestParseGoProfiles_MergeSet(t *testing.T) {
+	shard1 := writeGoProfile(t, "mode: set\nexample.com/app/a.go:3.10,5.2 2 1\nexample.com/app/a.go:7.10,9.2 1 0\n")
+	shard2 := writeGoProfile(t, "mode: set\nexample.com/app/a.go:3.10,5.2 2 1\n")
+
+	profiles, err := ParseGoProfiles(shard1, shard2)
+	if err != nil {
+		t.Fatalf("failed to merge profiles: %v", err)
+	}
+	// A block that ran in either profile ran
+	blocks := profiles[0].Blocks
+	if blocks[0].Count != 1 || blocks[1].Count != 0 {
+		t.Errorf("expected counts 1 and 0, got %d and %d", blocks[0].Count, blocks[1].Count)
+	}
+}
+
+func TestParseGoProfiles_Errors(t *testing.T) {
+	setProfile := writeGoProfile(t, "mode: set\nexample.com/app/a.go:3.10,5.2 2 1\n")
+	countProfile := writeGoProfile(t, "mode: count\nexample.com/app/a.go:3.10,5.2 2 1\n")
+	if _, err := ParseGoProfiles(setProfile, countProfile); err == nil {
+		t.Error("expected error merging profiles with different modes")
+	}
+	if _, err := ParseGoCoverage(setProfile, countProfile); err == nil {
+		t.Error("expected error parsing profiles with different modes")
+	}
+
+	changedProfile := writeGoProfile(t, "mode: set\nexample.com/app/a.go:3.10,5.2 3 1\n")
+	if _, err := ParseGoProfiles(setProfile, changedProfile); err == nil {
+		t.Error("expected error merging a block with different statement counts")
+	}
+
+	if _, err := ParseGoProfiles(setProfile, "/nonexistent/file.out"); err == nil {
+		t.Error("expected error for non-existent profile")
+	}
+}
@@ -0,0 +1,505 @@
+package coverage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Go 1.20+ binaries built with "go build -cover" write their coverage to the
+// GOCOVERDIR directory as binary files: a covmeta.<hash> file describing the
+// coverable units of every package, and one covcounters.<hash>.<pid>.<time>
+// file per run holding the unit counters. The layout follows the Go
+// toolchain's internal/coverage package.
+
+var (
+	goCovMetaMagic    = []byte{0x00, 'c', 'v', 'm'}
+	goCovCounterMagic = []byte{0x00, 'c', 'w', 'm'}
+)
+
+const (
+	goCovMetaFilePrefix    = "covmeta."
+	goCovCounterFilePrefix = "covcounters."
+
+	// goCovMetaFileHeaderSize is the size of the meta-data file header
+	goCovMetaFileHeaderSize = 56
+	// goCovMetaSymbolHeaderSize is the size of the header of each package's meta-data
+	goCovMetaSymbolHeaderSize = 44
+	// goCovCounterFileHeaderSize and goCovCounterFileFooterSize frame a counter file;
+	// a footer follows every segment
+	goCovCounterFileHeaderSize = 32
+	goCovCounterFileFooterSize = 16
+	// goCovCounterSegmentHeaderSize is the size of the header starting a counter segment
+	goCovCounterSegmentHeaderSize = 16
+)
+
+// Counter modes and granularities recorded in the meta-data file header
+const (
+	goCovModeSet          = 1
+	goCovGranularityPerFn = 2
+)
+
+// Counter encodings recorded in the counter file header
+const (
+	goCovCounterRaw     = 1
+	goCovCounterULeb128 = 2
+)
+
+// goCovMeta is the decoded meta-data of one instrumented binary
+type goCovMeta struct {
+	hash         [16]byte
+	mode         uint8
+	granularity  uint8
+	packages     []goCovPackage
+	funcCounters map[goCovFuncKey][]uint32
+}
+
+// goCovPackage holds the functions of one package, indexed like the counter data
+type goCovPackage struct {
+	path  string
+	funcs []goCovFunc
+}
+
+// goCovFunc is a function and its coverable units, the blocks of the text profile
+type goCovFunc struct {
+	name string
+	file string
+	// units are the function's blocks, each with its own counter
+	units []GoProfileBlock
+}
+
+// goCovFuncKey identifies a function by package and function index
+type goCovFuncKey struct {
+	pkg, fn uint32
+}
+
+// IsGoCoverDir returns true if the directory holds Go binary coverage data
+func IsGoCoverDir(dir string) bool {
+	matches, err := filepath.Glob(filepath.Join(dir, goCovMetaFilePrefix+"*"))
+	return err == nil && len(matches) > 0
+}
+
+// ParseGoCoverDir parses the binary coverage data "go build -cover" binaries
+// wrote to GOCOVERDIR. Each dir is a coverage directory or one of the files in
+// it; several directories, e.g. from separate test jobs, are merged. Counters
+// from several runs are added up ("count" and "atomic" mode) or combined
+// ("set" mode), and the blocks are converted into line hits like a text profile.
+// Returns a Report containing coverage data for all files
+func ParseGoCoverDir(dirs ...string) (*Report, error) {
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("no coverage directory given")
+	}
+
+	var metaFiles, counterFiles []string
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("failed to open coverage directory: %w", err)
+		} else if !info.IsDir() {
+			dir = filepath.Dir(dir)
+		}
+
+		dirMetaFiles, err := filepath.Glob(filepath.Join(dir, goCovMetaFilePrefix+"*"))
+		if err != nil || len(dirMetaFiles) == 0 {
+			return nil, fmt.Errorf("no Go coverage meta-data files (covmeta.*) found in %s", dir)
+		}
+		dirCounterFiles, err := filepath.Glob(filepath.Join(dir, goCovCounterFilePrefix+"*"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list counter files in %s: %w", dir, err)
+		}
+		metaFiles = append(metaFiles, dirMetaFiles...)
+		counterFiles = append(counterFiles, dirCounterFiles...)
+	}
+
+	metas := make(map[[16]byte]*goCovMeta)
+	for _, metaFile := range metaFiles {
+		meta, err := readGoCovMetaFile(metaFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(metaFile), err)
+		}
+		metas[meta.hash] = meta
+	}
+
+	for _, counterFile := range counterFiles {
+		if err := readGoCovCounterFile(counterFile, metas); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(counterFile), err)
+		}
+	}
+
+	report := &Report{
+		FileCoverage: make(map[string]*CoverageData),
+	}
+	for _, meta := range metas {
+		meta.addTo(report)
+	}
+	for _, fileCoverage := range report.FileCoverage {
+		fileCoverage.finishFunctions()
+	}
+
+	return report, nil
+}
+
+// goCoverDirParser is the registered parser of GOCOVERDIR data, which is read
+// from its directory rather than from a single stream
+type goCoverDirParser struct{}
+
+func (goCoverDirParser) Name() string {
+	return "gocoverdir"
+}
+
+// Detect recognizes coverage directories and the covmeta and covcounters files in them
+func (goCoverDirParser) Detect(path string, head []byte) float64 {
+	if (head == nil && IsGoCoverDir(path)) || isGoCovDataFile(head) {
+		return ConfidenceCertain
+	}
+	return ConfidenceNone
+}
+
+func (goCoverDirParser) Parse(r io.Reader) (*Report, error) {
+	return nil, fmt.Errorf("GOCOVERDIR coverage must be read from its directory")
+}
+
+func (goCoverDirParser) ParseFiles(paths ...string) (*Report, error) {
+	return ParseGoCoverDir(paths...)
+}
+
+// addTo adds the line hits and functions of all packages to the report. The
+// blocks of each file become a profile, whose lines are counted like those of
+// a text profile. Functions missing from the counter files never ran.
+func (m *goCovMeta) addTo(report *Report) {
+	profiles := make(map[string]*GoProfile)
+	for pkgIdx, pkg := range m.packages {
+		for fnIdx, fn := range pkg.funcs {
+			filePath := normalizeGoFilePath(fn.file)
+			fileCoverage := report.FileCoverage[filePath]
+			if fileCoverage == nil {
+				fileCoverage = &CoverageData{
+					LineHits: make(map[int]int),
+				}
+				report.FileCoverage[filePath] = fileCoverage
+			}
+			profile := profiles[filePath]
+			if profile == nil {
+				profile = &GoProfile{FileName: fn.file}
+				profiles[filePath] = profile
+			}
+
+			counters := m.funcCounters[goCovFuncKey{uint32(pkgIdx), uint32(fnIdx)}]
+			hits := func(unit int) int {
+				if m.granularity == goCovGranularityPerFn {
+					unit = 0
+				}
+				if unit >= len(counters) {
+					return 0
+				}
+				return int(counters[unit])
+			}
+
+			for i, unit := range fn.units {
+				unit.Count = hits(i)
+				profile.Blocks = append(profile.Blocks, unit)
+			}
+
+			if len(fn.units) > 0 {
+				function := &Function{Name: fn.name, StartLine: fn.units[0].StartLine, Hits: hits(0)}
+				for _, unit := range fn.units {
+					if unit.EndLine > function.EndLine {
+						function.EndLine = unit.EndLine
+					}
+				}
+				fileCoverage.Functions = append(fileCoverage.Functions, function)
+			}
+		}
+	}
+
+	for filePath, profile := range profiles {
+		profile.addTo(report.FileCoverage[filePath], readGoSource(filePath))
+	}
+}
+
+// readGoCovMetaFile decodes a covmeta file
+func readGoCovMetaFile(filePath string) (*goCovMeta, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < goCovMetaFileHeaderSize || !bytes.HasPrefix(data, goCovMetaMagic) {
+		return nil, fmt.Errorf("not a Go coverage meta-data file")
+	}
+
+	r := &goCovReader{data: data, off: 4}
+	if version := r.u32(); version > 1 {
+		return nil, fmt.Errorf("unsupported meta-data file version %d", version)
+	}
+	r.u64() // total length
+	entries := r.u64()
+	meta := &goCovMeta{
+		funcCounters: make(map[goCovFuncKey][]uint32),
+	}
+	copy(meta.hash[:], r.bytes(16))
+	r.u32() // string table offset
+	r.u32() // string table length
+	meta.mode = r.u8()
+	meta.granularity = r.u8()
+
+	r.off = goCovMetaFileHeaderSize
+	if entries > uint64(len(data)) {
+		return nil, fmt.Errorf("invalid package count %d", entries)
+	}
+	offsets := make([]uint64, entries)
+	for i := range offsets {
+		offsets[i] = r.u64()
+	}
+	lengths := make([]uint64, entries)
+	for i := range lengths {
+		lengths[i] = r.u64()
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	for i := range offsets {
+		end := offsets[i] + lengths[i]
+		if end > uint64(len(data)) || offsets[i] > end {
+			return nil, fmt.Errorf("package %d lies outside the file", i)
+		}
+		pkg, err := decodeGoCovPackage(data[offsets[i]:end])
+		if err != nil {
+			return nil, fmt.Errorf("package %d: %w", i, err)
+		}
+		meta.packages = append(meta.packages, pkg)
+	}
+
+	return meta, nil
+}
+
+// decodeGoCovPackage decodes the meta-data blob of one package: a header, the
+// offsets of its functions, a string table and the functions themselves
+func decodeGoCovPackage(data []byte) (goCovPackage, error) {
+	var pkg goCovPackage
+	r := &goCovReader{data: data}
+	r.u32() // length
+	r.u32() // package name
+	pkgPathIdx := r.u32()
+	r.u32() // module path
+	r.off = goCovMetaSymbolHeaderSize - 4
+	numFuncs := r.u32()
+	if r.err != nil {
+		return pkg, r.err
+	}
+	if int(numFuncs) > len(data) {
+		return pkg, fmt.Errorf("invalid function count %d", numFuncs)
+	}
+
+	offsets := make([]uint32, numFuncs)
+	for i := range offsets {
+		offsets[i] = r.u32()
+	}
+	strTab := r.stringTable()
+	if r.err != nil {
+		return pkg, r.err
+	}
+	str := func(idx uint64) string {
+		if idx >= uint64(len(strTab)) {
+			r.fail(fmt.Errorf("invalid string table index %d", idx))
+			return ""
+		}
+		return strTab[idx]
+	}
+	pkg.path = str(uint64(pkgPathIdx))
+
+	for _, offset := range offsets {
+		r.off = int(offset)
+		numUnits := r.uleb()
+		fn := goCovFunc{name: str(r.uleb()), file: str(r.uleb())}
+		for k := uint64(0); k < numUnits && r.err == nil; k++ {
+			unit := GoProfileBlock{
+				StartLine: int(r.uleb()),
+				StartCol:  int(r.uleb()),
+				EndLine:   int(r.uleb()),
+				EndCol:    int(r.uleb()),
+				NumStmt:   int(r.uleb()),
+			}
+			fn.units = append(fn.units, unit)
+		}
+		r.uleb() // function literal flag
+		pkg.funcs = append(pkg.funcs, fn)
+	}
+
+	return pkg, r.err
+}
+
+// readGoCovCounterFile decodes a covcounters file and merges its counters into
+// the meta-data it belongs to
+func readGoCovCounterFile(filePath string, metas map[[16]byte]*goCovMeta) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	if len(data) < goCovCounterFileHeaderSize+goCovCounterFileFooterSize || !bytes.HasPrefix(data, goCovCounterMagic) {
+		return fmt.Errorf("not a Go coverage counter file")
+	}
+
+	r := &goCovReader{data: data, off: 4}
+	if version := r.u32(); version > 1 {
+		return fmt.Errorf("unsupported counter file version %d", version)
+	}
+	var hash [16]byte
+	copy(hash[:], r.bytes(16))
+	flavor := r.u8()
+	bigEndian := r.u8() != 0
+
+	meta := metas[hash]
+	if meta == nil {
+		return fmt.Errorf("no meta-data file for counter hash %x", hash)
+	}
+
+	footer := &goCovReader{data: data, off: len(data) - goCovCounterFileFooterSize + 8}
+	numSegments := footer.u32()
+
+	value := func() uint32 {
+		switch flavor {
+		case goCovCounterULeb128:
+			return uint32(r.uleb())
+		case goCovCounterRaw:
+			b := r.bytes(4)
+			if b == nil {
+				return 0
+			}
+			if bigEndian {
+				return binary.BigEndian.Uint32(b)
+			}
+			return binary.LittleEndian.Uint32(b)
+		}
+		r.fail(fmt.Errorf("unknown counter encoding %d", flavor))
+		return 0
+	}
+
+	r.off = goCovCounterFileHeaderSize
+	for seg := uint32(0); seg < numSegments && r.err == nil; seg++ {
+		numFuncs := r.u64()
+		strTabLen := r.u32()
+		argsLen := r.u32()
+		// The string table and the arguments of the run are not needed
+		r.off += int(strTabLen) + int(argsLen)
+		if rem := r.off % 4; rem != 0 {
+			r.off += 4 - rem
+		}
+
+		for i := uint64(0); i < numFuncs && r.err == nil; i++ {
+			numCounters := value()
+			key := goCovFuncKey{pkg: value(), fn: value()}
+			if int(numCounters) > len(data) {
+				return fmt.Errorf("invalid counter count %d", numCounters)
+			}
+			counters := meta.funcCounters[key]
+			for len(counters) < int(numCounters) {
+				counters = append(counters, 0)
+			}
+			for k := 0; k < int(numCounters); k++ {
+				v := value()
+				if meta.mode == goCovModeSet {
+					if v > 0 {
+						counters[k] = 1
+					}
+				} else {
+					counters[k] += v
+				}
+			}
+			meta.funcCounters[key] = counters
+		}
+		r.off += goCovCounterFileFooterSize
+	}
+
+	return r.err
+}
+
+// goCovReader reads the little-endian and ULEB128 values of the binary
+// coverage files; the first out-of-bounds read sets err and later reads return zero
+type goCovReader struct {
+	data []byte
+	off  int
+	err  error
+}
+
+func (r *goCovReader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *goCovReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if r.off < 0 || n > len(r.data)-r.off {
+		r.fail(fmt.Errorf("unexpected end of data at offset %d", r.off))
+		return nil
+	}
+	b := r.data[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *goCovReader) u8() uint8 {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *goCovReader) u32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *goCovReader) u64() uint64 {
+	if b := r.bytes(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *goCovReader) uleb() uint64 {
+	var value uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		b := r.bytes(1)
+		if b == nil {
+			return 0
+		}
+		value |= uint64(b[0]&0x7f) << shift
+		if b[0]&0x80 == 0 {
+			return value
+		}
+	}
+	r.fail(fmt.Errorf("invalid ULEB128 value at offset %d", r.off))
+	return 0
+}
+
+// stringTable reads a ULEB128 count followed by length-prefixed strings
+func (r *goCovReader) stringTable() []string {
+	count := r.uleb()
+	if count > uint64(len(r.data)) {
+		r.fail(fmt.Errorf("invalid string table size %d", count))
+		return nil
+	}
+	table := make([]string, 0, count)
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		n := r.uleb()
+		if n > uint64(len(r.data)) {
+			r.fail(fmt.Errorf("invalid string length %d", n))
+			return nil
+		}
+		table = append(table, string(r.bytes(int(n))))
+	}
+	return table
+}
+
+// isGoCovDataFile returns true if the data starts like a covmeta or covcounters file
+func isGoCovDataFile(data []byte) bool {
+	return bytes.HasPrefix(data, goCovMetaMagic) || bytes.HasPrefix(data, goCovCounterMagic)
+}
@@ -0,0 +1,215 @@
+package coverage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const goCoverDirProgram = `package main
+
+import "os"
+
+func main() {
+	if len(os.Args) > 1 {
+		println("args")
+	}
+	println("done")
+}
+
+func unused() int {
+	return 42
+}
+`
+
+// createGoCoverDir builds a small program with "go build -cover" and runs it
+// once per argument list with GOCOVERDIR set, returning the coverage directory
+func createGoCoverDir(t *testing.T, runs ...[]string) string {
+	t.Helper()
+	srcDir := t.TempDir()
+	coverDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(srcDir, "go.mod"), []byte("module example.com/covdemo\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatalf("failed to write go.mod: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "main.go"), []byte(goCoverDirProgram), 0644); err != nil {
+		t.Fatalf("failed to write main.go: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	binary := filepath.Join(srcDir, "covdemo")
+	build := exec.CommandContext(ctx, "go", "build", "-cover", "-covermode=count", "-o", binary, ".")
+	build.Dir = srcDir
+	if output, err := build.CombinedOutput(); err != nil {
+		t.Skipf("go build -cover failed (this is OK for unit tests): %v\n%s", err, output)
+	}
+
+	for _, args := range runs {
+		run := exec.CommandContext(ctx, binary, args...)
+		run.Env = append(os.Environ(), "GOCOVERDIR="+coverDir)
+		if output, err := run.CombinedOutput(); err != nil {
+			t.Fatalf("instrumented binary failed: %v\n%s", err, output)
+		}
+	}
+
+	return coverDir
+}
+
+func TestParseGoCoverDir(t *testing.T) {
+	coverDir := createGoCoverDir(t, nil, nil, []string{"x"})
+
+	format, err := DetectCoverageFormat(coverDir)
+	if err != nil {
+		t.Fatalf("failed to detect format: %v", err)
+	}
+	if format != "gocoverdir" {
+		t.Errorf("expected format gocoverdir, got %s", format)
+	}
+
+	report, err := ParseGoCoverDir(coverDir)
+	if err != nil {
+		t.Fatalf("failed to parse coverage directory: %v", err)
+	}
+
+	fileCoverage := report.FileCoverage["example.com/covdemo/main.go"]
+	if fileCoverage == nil {
+		t.Fatalf("expected coverage for example.com/covdemo/main.go, got files %v", keys(report.FileCoverage))
+	}
+
+	// Counters of the three runs are added up
+	expectedHits := map[int]int{
+		6:  3, // the if statement ends the first block and starts the second
+		7:  1, // only the run with arguments enters the if body
+		9:  3,
+		13: 0, // unused is never called
+	}
+	for line, expected := range expectedHits {
+		hits, ok := fileCoverage.LineHits[line]
+		if !ok {
+			t.Errorf("expected line %d to be executable", line)
+			continue
+		}
+		if hits != expected {
+			t.Errorf("expected %d hits on line %d, got %d", expected, line, hits)
+		}
+	}
+	if _, ok := fileCoverage.LineHits[3]; ok {
+		t.Error("expected the import on line 3 not to be executable")
+	}
+
+	if len(fileCoverage.Functions) != 2 {
+		t.Fatalf("expected 2 functions, got %d", len(fileCoverage.Functions))
+	}
+	mainFn, unusedFn := fileCoverage.Functions[0], fileCoverage.Functions[1]
+	if mainFn.Name != "main" || mainFn.Hits != 3 || mainFn.EndLine < 9 {
+		t.Errorf("unexpected main function: %+v", *mainFn)
+	}
+	if unusedFn.Name != "unused" || unusedFn.Hits != 0 || unusedFn.StartLine < 12 {
+		t.Errorf("unexpected unused function: %+v", *unusedFn)
+	}
+
+	// The lines match the text profile the toolchain converts the directory to
+	textProfile := filepath.Join(t.TempDir(), "coverage.out")
+	convert := exec.Command("go", "tool", "covdata", "textfmt", "-i="+coverDir, "-o="+textProfile)
+	if output, err := convert.CombinedOutput(); err != nil {
+		t.Logf("go tool covdata failed, skipping comparison: %v\n%s", err, output)
+	} else {
+		expected := textProfileLineHits(t, textProfile, "example.com/covdemo/main.go")
+		if len(expected) != len(fileCoverage.LineHits) {
+			t.Errorf("expected %d executable lines, got %d", len(expected), len(fileCoverage.LineHits))
+		}
+		for line, hits := range expected {
+			if fileCoverage.LineHits[line] != hits {
+				t.Errorf("expected %d hits on line %d, got %d", hits, line, fileCoverage.LineHits[line])
+			}
+		}
+	}
+
+	// A file inside the directory stands for the directory
+	metaFiles, _ := filepath.Glob(filepath.Join(coverDir, "covmeta.*"))
+	if len(metaFiles) != 1 {
+		t.Fatalf("expected 1 meta-data file, got %d", len(metaFiles))
+	}
+	fromFile, err := ParseGoCoverDir(metaFiles[0])
+	if err != nil {
+		t.Fatalf("failed to parse coverage directory from file: %v", err)
+	}
+	if fromFile.FileCoverage["example.com/covdemo/main.go"].LineHits[7] != 1 {
+		t.Error("expected the same coverage when parsing from a meta-data file")
+	}
+
+	// Without counter files nothing ran, but every line is still executable
+	metaOnlyDir := t.TempDir()
+	metaData, err := os.ReadFile(metaFiles[0])
+	if err != nil {
+		t.Fatalf("failed to read meta-data file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(metaOnlyDir, filepath.Base(metaFiles[0])), metaData, 0644); err != nil {
+		t.Fatalf("failed to write meta-data file: %v", err)
+	}
+	metaOnly, err := ParseGoCoverDir(metaOnlyDir)
+	if err != nil {
+		t.Fatalf("failed to parse coverage directory without counters: %v", err)
+	}
+	uncovered := metaOnly.FileCoverage["example.com/covdemo/main.go"]
+	if uncovered == nil || uncovered.CoveredLines != 0 || uncovered.TotalLines != fileCoverage.TotalLines {
+		t.Errorf("expected %d uncovered lines without counter files, got %+v", fileCoverage.TotalLines, uncovered)
+	}
+}
+
+// textProfileLineHits reads the blocks of one file from a Go text profile,
+// keeping the highest count of each line
+func textProfileLineHits(t *testing.T, profile, fileName string) map[int]int {
+	t.Helper()
+	data, err := os.ReadFile(profile)
+	if err != nil {
+		t.Fatalf("failed to read text profile: %v", err)
+	}
+
+	lineHits := make(map[int]int)
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, fileName+":") {
+			continue
+		}
+		var startLine, startCol, endLine, endCol, statements, count int
+		if _, err := fmt.Sscanf(strings.TrimPrefix(line, fileName+":"), "%d.%d,%d.%d %d %d",
+			&startLine, &startCol, &endLine, &endCol, &statements, &count); err != nil {
+			t.Fatalf("invalid text profile line %q: %v", line, err)
+		}
+		for l := startLine; l <= endLine; l++ {
+			if existing, ok := lineHits[l]; !ok || count > existing {
+				lineHits[l] = count
+			}
+		}
+	}
+	return lineHits
+}
+
+func TestParseGoCoverDir_Invalid(t *testing.T) {
+	emptyDir := t.TempDir()
+	if _, err := ParseGoCoverDir(emptyDir); err == nil {
+		t.Error("expected error for directory without meta-data files")
+	}
+	if _, err := DetectCoverageFormat(emptyDir); err == nil {
+		t.Error("expected error detecting format of directory without coverage data")
+	}
+
+	corruptDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(corruptDir, "covmeta.0123"), []byte("\x00cvm\x01\x00\x00\x00truncated"), 0644); err != nil {
+		t.Fatalf("failed to write meta-data file: %v", err)
+	}
+	if _, err := ParseGoCoverDir(corruptDir); err == nil {
+		t.Error("expected error for truncated meta-data file")
+	}
+
+	if _, err := ParseGoCoverDir("/nonexistent/dir"); err == nil {
+		t.Error("expected error for non-existent directory")
+	}
+}
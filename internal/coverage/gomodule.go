@@ -0,0 +1,257 @@
+package coverage
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// goModule is a Go module of the repository: its module path and its directory
+// relative to the repository root ("" for the root itself)
+type goModule struct {
+	Path string
+	Dir  string
+}
+
+var (
+	goModuleRootCache  string
+	goModulesCache     []goModule
+	goModulesCacheOnce sync.Once
+)
+
+// loadGoModules finds the root and the Go modules of the repository being
+// analyzed once: the git repository root, or the enclosing go.work or go.mod
+// directory when not in a git repository
+func loadGoModules() {
+	goModulesCacheOnce.Do(func() {
+		goModuleRootCache = getRepoRoot()
+		if goModuleRootCache == "" {
+			goModuleRootCache = findGoModuleRoot()
+		}
+		if goModuleRootCache != "" {
+			goModulesCache = findGoModules(goModuleRootCache)
+		}
+	})
+}
+
+// goModules returns the Go modules of the repository being analyzed
+func goModules() []goModule {
+	loadGoModules()
+	return goModulesCache
+}
+
+// goModuleRoot returns the directory repository paths of Go files are relative to
+func goModuleRoot() string {
+	loadGoModules()
+	return goModuleRootCache
+}
+
+// findGoModuleRoot looks upwards from the working directory for a go.work file,
+// or failing that the nearest go.mod file, and returns its directory
+func findGoModuleRoot() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+
+	nearestModule := ""
+	for {
+		if fileExists(filepath.Join(dir, "go.work")) {
+			return dir
+		}
+		if nearestModule == "" && fileExists(filepath.Join(dir, "go.mod")) {
+			nearestModule = dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nearestModule
+		}
+		dir = parent
+	}
+}
+
+// findGoModules finds the modules under root: every go.mod file in the tree,
+// including nested modules, and the modules a go.work file uses. Directories the
+// go command ignores (vendor, testdata, and names starting with "." or "_") and
+// node_modules are not searched, but go.work may still use modules in them.
+// Modules are sorted longest path first, so that a nested module's path wins
+// over the module containing it.
+func findGoModules(root string) []goModule {
+	modulesByDir := make(map[string]goModule)
+	addModule := func(dir string) {
+		rel, err := filepath.Rel(root, dir)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			// Workspace modules outside the repository have no repository paths
+			return
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			rel = ""
+		}
+		if _, seen := modulesByDir[rel]; seen {
+			return
+		}
+		if modulePath := readGoModulePath(filepath.Join(dir, "go.mod")); modulePath != "" {
+			modulesByDir[rel] = goModule{Path: modulePath, Dir: rel}
+		}
+	}
+
+	filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if p != root && (name == "vendor" || name == "testdata" || name == "node_modules" ||
+				strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch d.Name() {
+		case "go.mod":
+			addModule(filepath.Dir(p))
+		case "go.work":
+			workDir := filepath.Dir(p)
+			for _, use := range readGoWorkUses(p) {
+				if !filepath.IsAbs(use) {
+					use = filepath.Join(workDir, use)
+				}
+				addModule(use)
+			}
+		}
+		return nil
+	})
+
+	modules := make([]goModule, 0, len(modulesByDir))
+	for _, module := range modulesByDir {
+		modules = append(modules, module)
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		if len(modules[i].Path) != len(modules[j].Path) {
+			return len(modules[i].Path) > len(modules[j].Path)
+		}
+		return modules[i].Dir < modules[j].Dir
+	})
+	return modules
+}
+
+// goModuleFilePath maps a file's import path, as Go coverage profiles record it,
+// to its path relative to the repository root using the module it belongs to
+func goModuleFilePath(importPath string, modules []goModule) (string, bool) {
+	for _, module := range modules {
+		if rest, ok := strings.CutPrefix(importPath, module.Path+"/"); ok {
+			return path.Join(module.Dir, rest), true
+		}
+	}
+	return "", false
+}
+
+// readGoModulePath returns the module path declared in a go.mod file, or "" if
+// the file cannot be read or declares none
+func readGoModulePath(goModPath string) string {
+	file, err := os.Open(goModPath)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := goModFields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "module" {
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// readGoWorkUses returns the directories of the use directives in a go.work
+// file, in both the single-line and the block form
+func readGoWorkUses(goWorkPath string) []string {
+	file, err := os.Open(goWorkPath)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var uses []string
+	inUseBlock := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := goModFields(scanner.Text())
+		switch {
+		case len(fields) == 0:
+		case inUseBlock:
+			if fields[0] == ")" {
+				inUseBlock = false
+			} else {
+				uses = append(uses, fields[0])
+			}
+		case fields[0] == "use" && len(fields) == 2 && fields[1] == "(":
+			inUseBlock = true
+		case fields[0] == "use" && len(fields) == 2:
+			uses = append(uses, fields[1])
+		}
+	}
+	return uses
+}
+
+// goModFields splits a go.mod or go.work line into fields the way the go
+// command does: parentheses are fields of their own, quoted strings are
+// unquoted, and a comment ends the line
+func goModFields(line string) []string {
+	var fields []string
+	for {
+		line = strings.TrimLeft(line, " \t\r")
+		switch {
+		case line == "" || strings.HasPrefix(line, "//"):
+			return fields
+		case line[0] == '(' || line[0] == ')':
+			fields = append(fields, line[:1])
+			line = line[1:]
+		case line[0] == '"' || line[0] == '`':
+			end := strings.IndexByte(line[1:], line[0])
+			if line[0] == '"' {
+				// Skip escaped quotes
+				for end != -1 && line[end] == '\\' {
+					next := strings.IndexByte(line[end+2:], '"')
+					if next == -1 {
+						end = -1
+					} else {
+						end += 1 + next
+					}
+				}
+			}
+			if end == -1 {
+				return append(fields, line)
+			}
+			quoted := line[:end+2]
+			if unquoted, err := strconv.Unquote(quoted); err == nil {
+				fields = append(fields, unquoted)
+			} else {
+				fields = append(fields, quoted)
+			}
+			line = line[end+2:]
+		default:
+			end := strings.IndexAny(line, " \t\r()")
+			if end == -1 {
+				end = len(line)
+			}
+			fields = append(fields, line[:end])
+			line = line[end:]
+		}
+	}
+}
+
+// fileExists returns true if filePath exists and is not a directory
+func fileExists(filePath string) bool {
+	info, err := os.Stat(filePath)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,103 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeTestFile writes a file below root, creating its directories
+func writeTestFile(t *testing.T, root, name, content string) {
+	t.Helper()
+	filePath := filepath.Join(root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+}
+
+func TestFindGoModules(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "go.mod", "// Root module\nmodule github.com/acme/platform\n\ngo 1.21\n")
+	writeTestFile(t, root, "services/billing/go.mod", "module github.com/acme/platform/services/billing // nested\n")
+	writeTestFile(t, root, "tools/go.mod", "module \"github.com/acme/tools\"\n")
+	writeTestFile(t, root, "_shared/go.mod", "module github.com/acme/shared\n")
+	writeTestFile(t, root, "go.work", "go 1.21\n\nuse (\n\t.\n\t./_shared // ignored by the walk, used by the workspace\n\t../outside\n)\nuse ./tools\n")
+	// The go command ignores these directories
+	writeTestFile(t, root, "vendor/github.com/dep/go.mod", "module github.com/dep\n")
+	writeTestFile(t, root, "internal/testdata/go.mod", "module example.com/fixture\n")
+	writeTestFile(t, root, "web/node_modules/pkg/go.mod", "module example.com/npm\n")
+
+	modules := findGoModules(root)
+	expected := []goModule{
+		{Path: "github.com/acme/platform/services/billing", Dir: "services/billing"},
+		{Path: "github.com/acme/platform", Dir: ""},
+		{Path: "github.com/acme/shared", Dir: "_shared"},
+		{Path: "github.com/acme/tools", Dir: "tools"},
+	}
+	if !reflect.DeepEqual(modules, expected) {
+		t.Errorf("expected modules %+v, got %+v", expected, modules)
+	}
+}
+
+func TestGoModuleFilePath(t *testing.T) {
+	modules := []goModule{
+		{Path: "github.com/acme/platform/services/billing", Dir: "services/billing"},
+		{Path: "github.com/acme/platform", Dir: ""},
+		{Path: "github.com/acme/lib/v2", Dir: "lib"},
+	}
+
+	tests := []struct {
+		importPath string
+		expected   string
+		ok         bool
+	}{
+		{"github.com/acme/platform/cmd/server/main.go", "cmd/server/main.go", true},
+		{"github.com/acme/platform/main.go", "main.go", true},
+		{"github.com/acme/platform/services/billing/invoice.go", "services/billing/invoice.go", true},
+		{"github.com/acme/platform/services/billing/internal/tax/tax.go", "services/billing/internal/tax/tax.go", true},
+		{"github.com/acme/lib/v2/parse.go", "lib/parse.go", true},
+		{"github.com/acme/platformx/main.go", "", false},
+		{"github.com/other/repo/main.go", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.importPath, func(t *testing.T) {
+			result, ok := goModuleFilePath(tt.importPath, modules)
+			if ok != tt.ok || result != tt.expected {
+				t.Errorf("expected (%q, %v), got (%q, %v)", tt.expected, tt.ok, result, ok)
+			}
+		})
+	}
+}
+
+func TestReadGoWorkUses(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "go.work", "go 1.22\n\ntoolchain go1.22.1\n\nuse ./api\nuse (\n\t./cli\n\t\"./with space\"\n)\n\nreplace example.com/x => ./x\n")
+
+	uses := readGoWorkUses(filepath.Join(root, "go.work"))
+	expected := []string{"./api", "./cli", "./with space"}
+	if !reflect.DeepEqual(uses, expected) {
+		t.Errorf("expected uses %v, got %v", expected, uses)
+	}
+}
+
+func TestNormalizeGoFilePath_RepoModule(t *testing.T) {
+	// The tests run inside this repository, whose go.mod declares github.com/swantron/difftron
+	if getRepoRoot() == "" {
+		t.Skip("not running inside a git repository")
+	}
+
+	result := normalizeGoFilePath("github.com/swantron/difftron/internal/hunk/parser.go")
+	if result != "internal/hunk/parser.go" {
+		t.Errorf("expected internal/hunk/parser.go, got %s", result)
+	}
+
+	result = normalizeGoFilePath("example.com/elsewhere/file.go")
+	if result != "example.com/elsewhere/file.go" {
+		t.Errorf("expected paths outside the repository's modules to be kept, got %s", result)
+	}
+}
@@ -0,0 +1,217 @@
+package coverage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// IstanbulFileCoverage represents the coverage of one file in an Istanbul
+// coverage-final.json, as written by nyc, Jest and Vitest
+type IstanbulFileCoverage struct {
+	Path         string                    `json:"path"`
+	StatementMap map[string]IstanbulRange  `json:"statementMap"`
+	FnMap        map[string]IstanbulFn     `json:"fnMap"`
+	BranchMap    map[string]IstanbulBranch `json:"branchMap"`
+	// S, F and B hold the counts of the statements, functions and branch
+	// locations, keyed like the maps above
+	S map[string]int   `json:"s"`
+	F map[string]int   `json:"f"`
+	B map[string][]int `json:"b"`
+}
+
+// IstanbulRange is a source range; lines are 1-based
+type IstanbulRange struct {
+	Start IstanbulPosition `json:"start"`
+	End   IstanbulPosition `json:"end"`
+}
+
+// IstanbulPosition is a line and column in a source file
+type IstanbulPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// after reports whether p comes after other in the file
+func (p IstanbulPosition) after(other IstanbulPosition) bool {
+	return p.Line > other.Line || (p.Line == other.Line && p.Column > other.Column)
+}
+
+// IstanbulFn describes a function; Decl is its name, Loc its whole body
+type IstanbulFn struct {
+	Name string        `json:"name"`
+	Line int           `json:"line"`
+	Decl IstanbulRange `json:"decl"`
+	Loc  IstanbulRange `json:"loc"`
+}
+
+// IstanbulBranch describes a conditional and the locations of its outcomes
+type IstanbulBranch struct {
+	Type      string          `json:"type"`
+	Line      int             `json:"line"`
+	Loc       IstanbulRange   `json:"loc"`
+	Locations []IstanbulRange `json:"locations"`
+}
+
+// detectIstanbul recognizes coverage-final.json, a JSON object keyed by file path
+func detectIstanbul(path string, head []byte) float64 {
+	content := string(head)
+	if strings.HasPrefix(strings.TrimSpace(content), "{") && strings.Contains(content, `"statementMap"`) {
+		return ConfidenceCertain
+	}
+	return ConfidenceNone
+}
+
+// ParseIstanbul parses an Istanbul coverage-final.json file
+// Returns a Report containing coverage data for all files
+func ParseIstanbul(filePath string) (*Report, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Istanbul file: %w", err)
+	}
+	defer file.Close()
+
+	return decodeIstanbul(file)
+}
+
+// decodeIstanbul reads Istanbul coverage JSON from r
+func decodeIstanbul(r io.Reader) (*Report, error) {
+	var entries map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("failed to parse Istanbul JSON: %w", err)
+	}
+
+	report := &Report{
+		FileCoverage: make(map[string]*CoverageData),
+	}
+
+	for key, raw := range entries {
+		fileCoverage, err := decodeIstanbulFile(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Istanbul coverage for %s: %w", key, err)
+		}
+
+		path := fileCoverage.Path
+		if path == "" {
+			path = key
+		}
+		report.FileCoverage[NormalizePath(path)] = fileCoverage.toCoverageData()
+	}
+
+	return report, nil
+}
+
+// decodeIstanbulFile decodes the coverage of one file. Coverage serialized from
+// istanbul-lib-coverage FileCoverage objects is wrapped in a "data" field.
+func decodeIstanbulFile(raw json.RawMessage) (*IstanbulFileCoverage, error) {
+	var wrapped struct {
+		Data *IstanbulFileCoverage `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &wrapped); err == nil && wrapped.Data != nil {
+		return wrapped.Data, nil
+	}
+
+	var fileCoverage IstanbulFileCoverage
+	if err := json.Unmarshal(raw, &fileCoverage); err != nil {
+		return nil, err
+	}
+	return &fileCoverage, nil
+}
+
+// toCoverageData converts statement ranges into line hits and keeps the branch
+// and function maps. Statements nest, e.g. a function expression inside a
+// declaration, so a line takes the highest count of the statements starting on
+// it, like istanbul-lib-coverage's getLineCoverage; a line inside a statement
+// where none start takes the count of the innermost statement spanning it.
+func (f *IstanbulFileCoverage) toCoverageData() *CoverageData {
+	coverage := &CoverageData{
+		LineHits: make(map[int]int),
+	}
+
+	ids := sortedIstanbulIDs(f.StatementMap)
+	for _, id := range ids {
+		statement := f.StatementMap[id]
+		if existing, exists := coverage.LineHits[statement.Start.Line]; !exists || f.S[id] > existing {
+			coverage.LineHits[statement.Start.Line] = f.S[id]
+		}
+	}
+
+	// innermost holds the start of the latest-starting statement spanning each
+	// line that no statement starts on
+	starts := make(map[int]bool, len(coverage.LineHits))
+	for line := range coverage.LineHits {
+		starts[line] = true
+	}
+	innermost := make(map[int]IstanbulPosition)
+	for _, id := range ids {
+		statement := f.StatementMap[id]
+		for line := statement.Start.Line + 1; line <= statement.End.Line; line++ {
+			if starts[line] {
+				continue
+			}
+			if start, exists := innermost[line]; exists && !statement.Start.after(start) {
+				continue
+			}
+			innermost[line] = statement.Start
+			coverage.LineHits[line] = f.S[id]
+		}
+	}
+	for _, hits := range coverage.LineHits {
+		coverage.TotalLines++
+		if hits > 0 {
+			coverage.CoveredLines++
+		}
+	}
+
+	for _, id := range sortedIstanbulIDs(f.BranchMap) {
+		branch := f.BranchMap[id]
+		line := branch.Line
+		if line == 0 {
+			line = branch.Loc.Start.Line
+		}
+		for i, taken := range f.B[id] {
+			coverage.addBranch(line, id+","+strconv.Itoa(i), taken)
+		}
+	}
+
+	for _, id := range sortedIstanbulIDs(f.FnMap) {
+		fn := f.FnMap[id]
+		startLine := fn.Decl.Start.Line
+		if startLine == 0 {
+			startLine = fn.Line
+		}
+		if startLine == 0 {
+			continue
+		}
+		coverage.Functions = append(coverage.Functions, &Function{
+			Name:      fn.Name,
+			StartLine: startLine,
+			EndLine:   fn.Loc.End.Line,
+			Hits:      f.F[id],
+		})
+	}
+	coverage.finishFunctions()
+
+	return coverage
+}
+
+// sortedIstanbulIDs returns the keys of an Istanbul map in numeric order
+func sortedIstanbulIDs[T any](m map[string]T) []string {
+	ids := make([]string, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, errA := strconv.Atoi(ids[i])
+		b, errB := strconv.Atoi(ids[j])
+		if errA != nil || errB != nil {
+			return ids[i] < ids[j]
+		}
+		return a < b
+	})
+	return ids
+}
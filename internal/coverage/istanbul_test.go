@@ -0,0 +1,148 @@
+package coverage
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseIstanbul(t *testing.T) {
+	istanbulContent := `{
+  "src/app.js": {
+    "path": "src/app.js",
+    "statementMap": {
+      "0": {"start": {"line": 1, "column": 0}, "end": {"line": 1, "column": 30}},
+      "1": {"start": {"line": 3, "column": 2}, "end": {"line": 5, "column": 4}},
+      "2": {"start": {"line": 4, "column": 4}, "end": {"line": 4, "column": 20}},
+      "3": {"start": {"line": 8, "column": 2}, "end": {"line": 8, "column": 12}},
+      "10": {"start": {"line": 12, "column": 2}, "end": {"line": 12, "column": 12}}
+    },
+    "fnMap": {
+      "0": {"name": "render", "decl": {"start": {"line": 2, "column": 9}, "end": {"line": 2, "column": 15}}, "loc": {"start": {"line": 2, "column": 0}, "end": {"line": 9, "column": 1}}, "line": 2},
+      "1": {"name": "unused", "decl": {"start": {"line": 11, "column": 9}, "end": {"line": 11, "column": 15}}, "loc": {"start": {"line": 11, "column": 0}, "end": {"line": 13, "column": 1}}, "line": 11}
+    },
+    "branchMap": {
+      "0": {"type": "if", "line": 3, "loc": {"start": {"line": 3, "column": 2}, "end": {"line": 5, "column": 4}}, "locations": [{"start": {"line": 3, "column": 2}, "end": {"line": 5, "column": 4}}, {"start": {"line": 3, "column": 2}, "end": {"line": 5, "column": 4}}]}
+    },
+    "s": {"0": 1, "1": 3, "2": 0, "3": 0, "10": 0},
+    "f": {"0": 3, "1": 0},
+    "b": {"0": [3, 0]}
+  },
+  "src/util.js": {
+    "data": {
+      "path": "src/util.js",
+      "statementMap": {"0": {"start": {"line": 1, "column": 0}, "end": {"line": 1, "column": 10}}},
+      "fnMap": {},
+      "branchMap": {},
+      "s": {"0": 2},
+      "f": {},
+      "b": {}
+    }
+  }
+}`
+
+	tmpfile, err := os.CreateTemp("", "coverage-final-*.json")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := tmpfile.Write([]byte(istanbulContent)); err != nil {
+		t.Fatalf("failed to write test data: %v", err)
+	}
+	tmpfile.Close()
+
+	report, err := ParseIstanbul(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fileCoverage := report.GetCoverageForFile("src/app.js")
+	if fileCoverage == nil {
+		t.Fatal("expected coverage data for src/app.js")
+	}
+
+	// Line 4 takes the count of statement 2 starting on it, not of statement 1
+	// around it
+	expectedHits := map[int]int{1: 1, 3: 3, 4: 0, 5: 3, 8: 0, 12: 0}
+	for line, hits := range expectedHits {
+		if got, ok := fileCoverage.LineHits[line]; !ok || got != hits {
+			t.Errorf("line %d: expected %d hits, got %d (found %v)", line, hits, got, ok)
+		}
+	}
+	if fileCoverage.TotalLines != 6 || fileCoverage.CoveredLines != 3 {
+		t.Errorf("expected 3 of 6 lines covered, got %d of %d", fileCoverage.CoveredLines, fileCoverage.TotalLines)
+	}
+
+	if covered, total := report.GetBranchCoverageForLine("src/app.js", 3); covered != 1 || total != 2 {
+		t.Errorf("expected 1 of 2 branches on line 3, got %d of %d", covered, total)
+	}
+
+	functions := report.GetFunctionsForFile("src/app.js")
+	if len(functions) != 2 {
+		t.Fatalf("expected 2 functions, got %d", len(functions))
+	}
+	if f := functions[0]; f.Name != "render" || f.StartLine != 2 || f.EndLine != 9 || f.Hits != 3 {
+		t.Errorf("unexpected function %+v", f)
+	}
+	if f := functions[1]; f.Name != "unused" || f.Hits != 0 {
+		t.Errorf("unexpected function %+v", f)
+	}
+
+	// Coverage wrapped in "data" is read too
+	if hits := report.GetCoverageForLine("src/util.js", 1); hits != 2 {
+		t.Errorf("expected 2 hits on src/util.js line 1, got %d", hits)
+	}
+}
+
+func TestParseIstanbul_NestedFunction(t *testing.T) {
+	// const f = function() {
+	//   a(
+	//     b);
+	//   c();
+	// };
+	report, err := decodeIstanbul(strings.NewReader(`{
+  "src/f.js": {
+    "path": "src/f.js",
+    "statementMap": {
+      "0": {"start": {"line": 1, "column": 0}, "end": {"line": 5, "column": 2}},
+      "1": {"start": {"line": 2, "column": 2}, "end": {"line": 3, "column": 7}},
+      "2": {"start": {"line": 4, "column": 2}, "end": {"line": 4, "column": 6}}
+    },
+    "fnMap": {
+      "0": {"name": "(anonymous_0)", "decl": {"start": {"line": 1, "column": 10}, "end": {"line": 1, "column": 20}}, "loc": {"start": {"line": 1, "column": 10}, "end": {"line": 5, "column": 1}}, "line": 1}
+    },
+    "branchMap": {},
+    "s": {"0": 1, "1": 0, "2": 0},
+    "f": {"0": 0},
+    "b": {}
+  }
+}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The declaration ran but the function body never did
+	expectedHits := map[int]int{1: 1, 2: 0, 3: 0, 4: 0, 5: 1}
+	if got := report.FileCoverage["src/f.js"].LineHits; !reflect.DeepEqual(got, expectedHits) {
+		t.Errorf("expected line hits %v, got %v", expectedHits, got)
+	}
+}
+
+func TestParseIstanbul_InvalidJSON(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "coverage-final-*.json")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := tmpfile.Write([]byte(`{"src/app.js": "not coverage"}`)); err != nil {
+		t.Fatalf("failed to write test data: %v", err)
+	}
+	tmpfile.Close()
+
+	if _, err := ParseIstanbul(tmpfile.Name()); err == nil {
+		t.Error("expected error for invalid Istanbul coverage")
+	}
+}
@@ -0,0 +1,266 @@
+package coverage
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// JaCoCoReport represents the root element of a JaCoCo XML report
+type JaCoCoReport struct {
+	XMLName  xml.Name        `xml:"report"`
+	Name     string          `xml:"name,attr"`
+	Groups   []JaCoCoGroup   `xml:"group"`
+	Packages []JaCoCoPackage `xml:"package"`
+}
+
+// JaCoCoGroup represents a group of bundles or packages; groups can nest
+type JaCoCoGroup struct {
+	Name     string          `xml:"name,attr"`
+	Groups   []JaCoCoGroup   `xml:"group"`
+	Packages []JaCoCoPackage `xml:"package"`
+}
+
+// JaCoCoPackage represents a package, named with slashes (e.g. "com/example/app")
+type JaCoCoPackage struct {
+	Name        string             `xml:"name,attr"`
+	Classes     []JaCoCoClass      `xml:"class"`
+	SourceFiles []JaCoCoSourceFile `xml:"sourcefile"`
+}
+
+// JaCoCoClass represents a class and the source file it was compiled from
+type JaCoCoClass struct {
+	Name           string         `xml:"name,attr"`
+	SourceFileName string         `xml:"sourcefilename,attr"`
+	Methods        []JaCoCoMethod `xml:"method"`
+}
+
+// JaCoCoMethod represents a method with its first line and counters
+type JaCoCoMethod struct {
+	Name     string          `xml:"name,attr"`
+	Desc     string          `xml:"desc,attr"`
+	Line     int             `xml:"line,attr"`
+	Counters []JaCoCoCounter `xml:"counter"`
+}
+
+// JaCoCoCounter represents a missed/covered counter of one type, such as INSTRUCTION or METHOD
+type JaCoCoCounter struct {
+	Type    string `xml:"type,attr"`
+	Missed  int    `xml:"missed,attr"`
+	Covered int    `xml:"covered,attr"`
+}
+
+// JaCoCoSourceFile represents a source file with its per-line counters
+type JaCoCoSourceFile struct {
+	Name  string       `xml:"name,attr"`
+	Lines []JaCoCoLine `xml:"line"`
+}
+
+// JaCoCoLine holds the missed (mi) and covered (ci) instructions and the missed
+// (mb) and covered (cb) branches of a source line
+type JaCoCoLine struct {
+	Number              int `xml:"nr,attr"`
+	MissedInstructions  int `xml:"mi,attr"`
+	CoveredInstructions int `xml:"ci,attr"`
+	MissedBranches      int `xml:"mb,attr"`
+	CoveredBranches     int `xml:"cb,attr"`
+}
+
+// jvmSourceExtensions lists the source files JaCoCo reports on
+var jvmSourceExtensions = map[string]bool{
+	".java": true, ".kt": true, ".groovy": true, ".scala": true,
+}
+
+// detectJaCoCo recognizes JaCoCo XML, which has to be told apart from Cobertura
+// as both use <package> and <class>
+func detectJaCoCo(path string, head []byte) float64 {
+	content := string(head)
+	if strings.Contains(content, "<report") &&
+		(strings.Contains(content, "JACOCO") || strings.Contains(content, "<sessioninfo") ||
+			strings.Contains(content, "<sourcefile")) {
+		return ConfidenceCertain
+	}
+	return ConfidenceNone
+}
+
+// ParseJaCoCo parses a JaCoCo XML report (jacoco.xml)
+// Returns a Report containing coverage data for all files
+func ParseJaCoCo(filePath string) (*Report, error) {
+	return parseJaCoCoFile(filePath, jacocoSourceRoot())
+}
+
+// jacocoSourceRoot returns the directory JaCoCo paths are resolved against: the
+// repository root, or the working directory outside a repository
+func jacocoSourceRoot() string {
+	if root := getRepoRoot(); root != "" {
+		return root
+	}
+	return "."
+}
+
+// parseJaCoCoFile parses a JaCoCo report, resolving its paths against the sources below root
+func parseJaCoCoFile(filePath, root string) (*Report, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open JaCoCo file: %w", err)
+	}
+	defer file.Close()
+
+	return decodeJaCoCo(file, root)
+}
+
+// decodeJaCoCo reads a JaCoCo report from r, resolving its paths against the sources below root
+func decodeJaCoCo(r io.Reader, root string) (*Report, error) {
+	var jacoco JaCoCoReport
+	decoder := xml.NewDecoder(r)
+	// JaCoCo declares a DTD that is not needed to read the report
+	decoder.Strict = false
+	if err := decoder.Decode(&jacoco); err != nil {
+		return nil, fmt.Errorf("failed to parse JaCoCo XML: %w", err)
+	}
+
+	report := &Report{
+		FileCoverage: make(map[string]*CoverageData),
+	}
+	resolver := &jacocoPathResolver{root: root}
+
+	for _, pkg := range collectJaCoCoPackages(jacoco.Packages, jacoco.Groups) {
+		for _, sourceFile := range pkg.SourceFiles {
+			fileCoverage := &CoverageData{
+				LineHits: make(map[int]int),
+			}
+
+			// A line with instructions is executable; JaCoCo has no hit counts,
+			// so the number of covered instructions stands in for them
+			for _, line := range sourceFile.Lines {
+				if line.MissedInstructions+line.CoveredInstructions == 0 {
+					continue
+				}
+				fileCoverage.LineHits[line.Number] = line.CoveredInstructions
+				fileCoverage.TotalLines++
+				if line.CoveredInstructions > 0 {
+					fileCoverage.CoveredLines++
+				}
+
+				// Branches are counted, not identified, so they are numbered in order
+				for i := 0; i < line.MissedBranches+line.CoveredBranches; i++ {
+					taken := 0
+					if i < line.CoveredBranches {
+						taken = 1
+					}
+					fileCoverage.addBranch(line.Number, strconv.Itoa(i), taken)
+				}
+			}
+
+			for _, class := range pkg.Classes {
+				if class.SourceFileName != sourceFile.Name {
+					continue
+				}
+				for _, method := range class.Methods {
+					if function := newJaCoCoFunction(class, method); function != nil {
+						fileCoverage.Functions = append(fileCoverage.Functions, function)
+					}
+				}
+			}
+			fileCoverage.finishFunctions()
+
+			report.FileCoverage[resolver.resolve(pkg.Name, sourceFile.Name)] = fileCoverage
+		}
+	}
+
+	return report, nil
+}
+
+// collectJaCoCoPackages flattens the packages of a report and its nested groups
+func collectJaCoCoPackages(packages []JaCoCoPackage, groups []JaCoCoGroup) []JaCoCoPackage {
+	result := append([]JaCoCoPackage(nil), packages...)
+	for _, group := range groups {
+		result = append(result, collectJaCoCoPackages(group.Packages, group.Groups)...)
+	}
+	return result
+}
+
+// newJaCoCoFunction builds a function record from a method, using its METHOD
+// counter to tell whether it was entered. It returns nil for methods without a
+// line, such as synthetic ones.
+func newJaCoCoFunction(class JaCoCoClass, method JaCoCoMethod) *Function {
+	if method.Line <= 0 {
+		return nil
+	}
+	className := class.Name[strings.LastIndex(class.Name, "/")+1:]
+	function := &Function{Name: className + "." + method.Name + method.Desc, StartLine: method.Line}
+	for _, counter := range method.Counters {
+		if counter.Type == "METHOD" {
+			function.Hits = counter.Covered
+		}
+	}
+	return function
+}
+
+// jacocoPathResolver maps the package-relative paths of a JaCoCo report to
+// repository-relative paths. JaCoCo only records the package and file name, so
+// the path is looked up among the JVM sources below root, which also finds files
+// in src/main/java, src/main/kotlin and the source roots of nested modules.
+type jacocoPathResolver struct {
+	root   string
+	byName map[string][]string // file name -> slash-separated paths below root
+}
+
+// resolve returns the repository-relative path of a source file, or the
+// package-relative path if no single source file below root matches it
+func (r *jacocoPathResolver) resolve(packageName, fileName string) string {
+	packagePath := fileName
+	if packageName != "" {
+		packagePath = strings.Trim(packageName, "/") + "/" + fileName
+	}
+
+	if r.byName == nil {
+		r.index()
+	}
+	var match string
+	for _, candidate := range r.byName[fileName] {
+		if candidate == packagePath || strings.HasSuffix(candidate, "/"+packagePath) {
+			if match != "" {
+				// Ambiguous, e.g. the same class in two modules
+				return packagePath
+			}
+			match = candidate
+		}
+	}
+	if match == "" {
+		return packagePath
+	}
+	return match
+}
+
+// index lists the JVM source files below the root by file name
+func (r *jacocoPathResolver) index() {
+	r.byName = make(map[string][]string)
+	_ = filepath.WalkDir(r.root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			name := entry.Name()
+			if p != r.root && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "build" || name == "target") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !jvmSourceExtensions[path.Ext(entry.Name())] {
+			return nil
+		}
+		rel, err := filepath.Rel(r.root, p)
+		if err != nil {
+			return nil
+		}
+		r.byName[entry.Name()] = append(r.byName[entry.Name()], filepath.ToSlash(rel))
+		return nil
+	})
+}
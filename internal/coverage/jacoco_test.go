@@ -0,0 +1,152 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const jacocoContent = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<!DOCTYPE report PUBLIC "-//JACOCO//DTD Report 1.1//EN" "report.dtd">
+<report name="app">
+  <sessioninfo id="host-1" start="1700000000000" dump="1700000001000"/>
+  <package name="com/example/app">
+    <class name="com/example/app/Greeter" sourcefilename="Greeter.java">
+      <method name="&lt;init&gt;" desc="()V" line="3">
+        <counter type="INSTRUCTION" missed="0" covered="3"/>
+        <counter type="METHOD" missed="0" covered="1"/>
+      </method>
+      <method name="greet" desc="(Ljava/lang/String;)Ljava/lang/String;" line="5">
+        <counter type="INSTRUCTION" missed="2" covered="6"/>
+        <counter type="METHOD" missed="0" covered="1"/>
+      </method>
+      <method name="farewell" desc="()Ljava/lang/String;" line="12">
+        <counter type="INSTRUCTION" missed="2" covered="0"/>
+        <counter type="METHOD" missed="1" covered="0"/>
+      </method>
+    </class>
+    <sourcefile name="Greeter.java">
+      <line nr="3" mi="0" ci="3" mb="0" cb="0"/>
+      <line nr="5" mi="0" ci="2" mb="1" cb="1"/>
+      <line nr="6" mi="0" ci="4" mb="0" cb="0"/>
+      <line nr="8" mi="2" ci="0" mb="0" cb="0"/>
+      <line nr="9" mi="0" ci="0" mb="0" cb="0"/>
+      <line nr="12" mi="2" ci="0" mb="0" cb="0"/>
+      <counter type="LINE" missed="2" covered="3"/>
+    </sourcefile>
+  </package>
+  <group name="util">
+    <package name="com/example/util">
+      <sourcefile name="Strings.kt">
+        <line nr="4" mi="0" ci="5" mb="2" cb="2"/>
+      </sourcefile>
+    </package>
+  </group>
+</report>
+`
+
+func writeJaCoCoReport(t *testing.T) string {
+	t.Helper()
+	tmpfile, err := os.CreateTemp("", "jacoco-*.xml")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	t.Cleanup(func() { os.Remove(tmpfile.Name()) })
+
+	if _, err := tmpfile.Write([]byte(jacocoContent)); err != nil {
+		t.Fatalf("failed to write test data: %v", err)
+	}
+	tmpfile.Close()
+	return tmpfile.Name()
+}
+
+func TestParseJaCoCo(t *testing.T) {
+	report, err := parseJaCoCoFile(writeJaCoCoReport(t), t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fileCoverage := report.GetCoverageForFile("com/example/app/Greeter.java")
+	if fileCoverage == nil {
+		t.Fatal("expected coverage data for com/example/app/Greeter.java")
+	}
+
+	// Line 9 has no instructions and is not executable
+	if fileCoverage.TotalLines != 5 || fileCoverage.CoveredLines != 3 {
+		t.Errorf("expected 3 of 5 lines covered, got %d of %d", fileCoverage.CoveredLines, fileCoverage.TotalLines)
+	}
+	if _, ok := fileCoverage.LineHits[9]; ok {
+		t.Error("expected line 9 to be non-executable")
+	}
+	if hits, ok := fileCoverage.LineHits[8]; !ok || hits != 0 {
+		t.Errorf("expected line 8 uncovered, got %d hits (found %v)", hits, ok)
+	}
+
+	if covered, total := report.GetBranchCoverageForLine("com/example/app/Greeter.java", 5); covered != 1 || total != 2 {
+		t.Errorf("expected 1 of 2 branches on line 5, got %d of %d", covered, total)
+	}
+	if !report.IsLinePartiallyCovered("com/example/app/Greeter.java", 5) {
+		t.Error("expected line 5 to be partially covered")
+	}
+
+	functions := report.GetFunctionsForFile("com/example/app/Greeter.java")
+	if len(functions) != 3 {
+		t.Fatalf("expected 3 functions, got %d", len(functions))
+	}
+	if f := functions[2]; f.Name != "Greeter.farewell()Ljava/lang/String;" || f.StartLine != 12 || f.Hits != 0 {
+		t.Errorf("unexpected function %+v", f)
+	}
+	if f := functions[1]; f.StartLine != 5 || f.EndLine != 11 || f.Hits != 1 {
+		t.Errorf("unexpected function %+v", f)
+	}
+
+	// Packages inside groups are read too
+	if covered, total := report.GetBranchCoverageForLine("com/example/util/Strings.kt", 4); covered != 2 || total != 4 {
+		t.Errorf("expected 2 of 4 branches on Strings.kt line 4, got %d of %d", covered, total)
+	}
+}
+
+func TestParseJaCoCo_ResolvesSourceRoots(t *testing.T) {
+	root := t.TempDir()
+	for _, path := range []string{
+		"service/src/main/java/com/example/app/Greeter.java",
+		"service/target/classes/com/example/app/Greeter.java",
+		// Two modules with the same class are ambiguous
+		"a/src/main/kotlin/com/example/util/Strings.kt",
+		"b/src/main/kotlin/com/example/util/Strings.kt",
+	} {
+		full := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte("// source\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := parseJaCoCoFile(writeJaCoCoReport(t), root)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := report.FileCoverage["service/src/main/java/com/example/app/Greeter.java"]; !ok {
+		t.Errorf("expected Greeter.java to resolve to its source root, got %v", keys(report.FileCoverage))
+	}
+	if _, ok := report.FileCoverage["com/example/util/Strings.kt"]; !ok {
+		t.Errorf("expected ambiguous Strings.kt to keep its package path, got %v", keys(report.FileCoverage))
+	}
+}
+
+func TestParseJaCoCo_InvalidFile(t *testing.T) {
+	if _, err := ParseJaCoCo("/nonexistent/jacoco.xml"); err == nil {
+		t.Error("expected error for non-existent file")
+	}
+}
+
+func keys(m map[string]*CoverageData) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	return result
+}
@@ -3,9 +3,11 @@ package coverage
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -19,6 +21,123 @@ type CoverageData struct {
 	TotalLines int
 	// CoveredLines is the number of lines with hits > 0
 	CoveredLines int
+	// Branches maps line number -> outcomes of the branches at that line
+	Branches map[int][]Branch
+	// TotalBranches is the total number of branches
+	TotalBranches int
+	// CoveredBranches is the number of branches taken at least once
+	CoveredBranches int
+	// Functions lists the functions of the file in order of their start line
+	Functions []*Function
+	// UnexecutedBlocks holds the lines that ran but contain a basic block that
+	// never did, as flagged by gcov
+	UnexecutedBlocks map[int]bool
+	// ExcludedLines holds the lines the coverage tool was told not to measure,
+	// such as those marked "# pragma: no cover"; they are neither covered nor uncovered
+	ExcludedLines map[int]bool
+}
+
+// Function records how often a function was called
+type Function struct {
+	Name string
+	// StartLine is the line the function is declared on
+	StartLine int
+	// EndLine is the last line of the function; when the coverage format does not
+	// record it, it is the line before the next function or the last line with data
+	EndLine int
+	// Hits is the number of times the function was entered
+	Hits int
+}
+
+// Contains returns true if the line is part of the function
+func (f *Function) Contains(lineNum int) bool {
+	return lineNum >= f.StartLine && lineNum <= f.EndLine
+}
+
+// Branch is one outcome of a conditional, such as the else-path of an if
+type Branch struct {
+	// ID identifies the branch within its line, e.g. "0,1" for LCOV block 0, branch 1
+	ID string
+	// Taken is the number of times the branch was taken
+	Taken int
+}
+
+// setLineHits records the hits of a line, keeping the higher count if the line
+// was already recorded
+func (c *CoverageData) setLineHits(lineNum, hits int) {
+	existing, exists := c.LineHits[lineNum]
+	if !exists {
+		c.TotalLines++
+	}
+	if hits > 0 && existing == 0 {
+		c.CoveredLines++
+	}
+	if !exists || hits > existing {
+		c.LineHits[lineNum] = hits
+	}
+}
+
+// addBranch records a branch outcome at a line, adding to the count of a branch
+// that was already recorded under the same ID
+func (c *CoverageData) addBranch(lineNum int, id string, taken int) {
+	if c.Branches == nil {
+		c.Branches = make(map[int][]Branch)
+	}
+	for i, branch := range c.Branches[lineNum] {
+		if branch.ID == id {
+			if branch.Taken == 0 && taken > 0 {
+				c.CoveredBranches++
+			}
+			c.Branches[lineNum][i].Taken += taken
+			return
+		}
+	}
+	c.Branches[lineNum] = append(c.Branches[lineNum], Branch{ID: id, Taken: taken})
+	c.TotalBranches++
+	if taken > 0 {
+		c.CoveredBranches++
+	}
+}
+
+// finishFunctions sorts the functions by start line and fills in the end lines
+// the coverage format did not record
+func (c *CoverageData) finishFunctions() {
+	sort.SliceStable(c.Functions, func(i, j int) bool {
+		return c.Functions[i].StartLine < c.Functions[j].StartLine
+	})
+
+	lastLine := 0
+	for lineNum := range c.LineHits {
+		if lineNum > lastLine {
+			lastLine = lineNum
+		}
+	}
+	for i, function := range c.Functions {
+		if function.EndLine >= function.StartLine {
+			continue
+		}
+		function.EndLine = lastLine
+		for _, next := range c.Functions[i+1:] {
+			if next.StartLine > function.StartLine {
+				function.EndLine = next.StartLine - 1
+				break
+			}
+		}
+		if function.EndLine < function.StartLine {
+			function.EndLine = function.StartLine
+		}
+	}
+}
+
+// BranchCoverage returns how many of the branches at a line were taken and how many there are
+func (c *CoverageData) BranchCoverage(lineNum int) (covered, total int) {
+	for _, branch := range c.Branches[lineNum] {
+		total++
+		if branch.Taken > 0 {
+			covered++
+		}
+	}
+	return covered, total
 }
 
 // Report contains coverage data for multiple files
@@ -27,6 +146,21 @@ type Report struct {
 	FileCoverage map[string]*CoverageData
 }
 
+// detectLCOV recognizes LCOV by its record markers. As LCOV is the most common
+// format, files no other parser recognizes are tried as LCOV too.
+func detectLCOV(path string, head []byte) float64 {
+	if head == nil {
+		return ConfidenceNone
+	}
+	content := string(head)
+	trimmed := strings.TrimSpace(content)
+	if strings.HasPrefix(trimmed, "TN:") || strings.HasPrefix(trimmed, "SF:") ||
+		(strings.Contains(content, "SF:") && strings.Contains(content, "DA:")) {
+		return ConfidenceCertain
+	}
+	return ConfidenceFallback
+}
+
 // ParseLCOV parses an LCOV format coverage file (.info)
 // Returns a Report containing coverage data for all files
 func ParseLCOV(filePath string) (*Report, error) {
@@ -36,13 +170,22 @@ func ParseLCOV(filePath string) (*Report, error) {
 	}
 	defer file.Close()
 
+	return decodeLCOV(file)
+}
+
+// decodeLCOV reads an LCOV tracefile from r
+func decodeLCOV(r io.Reader) (*Report, error) {
 	report := &Report{
 		FileCoverage: make(map[string]*CoverageData),
 	}
 
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
 	var currentFile string
 	var currentCoverage *CoverageData
+	var branchesFound, branchesHit int
+	// Functions by name, and by index for the FNL/FNA records of lcov 2.2
+	var functionsByName map[string]*Function
+	var functionsByIndex map[string]*Function
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -61,6 +204,8 @@ func ParseLCOV(filePath string) (*Report, error) {
 				LineHits: make(map[int]int),
 			}
 			report.FileCoverage[currentFile] = currentCoverage
+			functionsByName = make(map[string]*Function)
+			functionsByIndex = make(map[string]*Function)
 			continue
 		}
 
@@ -97,10 +242,147 @@ func ParseLCOV(filePath string) (*Report, error) {
 			continue
 		}
 
+		// BRDA: Branch data
+		// Format: BRDA:line_number,block,branch,taken where taken is "-" if the
+		// block was never executed. Newer lcov versions allow commas in the branch
+		// name, so the line comes first and the count last.
+		if strings.HasPrefix(line, "BRDA:") {
+			data := strings.TrimPrefix(line, "BRDA:")
+			lineStr, rest, ok := strings.Cut(data, ",")
+			lastComma := strings.LastIndex(rest, ",")
+			if !ok || lastComma == -1 {
+				continue
+			}
+
+			lineNum, err := strconv.Atoi(lineStr)
+			if err != nil {
+				continue
+			}
+
+			taken := 0
+			if takenStr := rest[lastComma+1:]; takenStr != "-" {
+				taken, err = strconv.Atoi(takenStr)
+				if err != nil {
+					continue
+				}
+			}
+
+			currentCoverage.addBranch(lineNum, rest[:lastComma], taken)
+			continue
+		}
+
+		// BRF/BRH: Branches found and hit. They only summarize the BRDA records,
+		// so they are used for files whose producer left the records out.
+		if strings.HasPrefix(line, "BRF:") || strings.HasPrefix(line, "BRH:") {
+			count, err := strconv.Atoi(line[4:])
+			if err != nil {
+				continue
+			}
+			if strings.HasPrefix(line, "BRF:") {
+				branchesFound = count
+			} else {
+				branchesHit = count
+			}
+			continue
+		}
+
+		// FN: Function declaration
+		// Format: FN:start_line,name or FN:start_line,end_line,name
+		if strings.HasPrefix(line, "FN:") {
+			fields := strings.SplitN(strings.TrimPrefix(line, "FN:"), ",", 3)
+			if len(fields) < 2 {
+				continue
+			}
+			start, err := strconv.Atoi(fields[0])
+			if err != nil {
+				continue
+			}
+			function := &Function{Name: fields[len(fields)-1], StartLine: start}
+			if len(fields) == 3 {
+				if end, err := strconv.Atoi(fields[1]); err == nil {
+					function.EndLine = end
+				} else {
+					// The name itself contains a comma
+					function.Name = fields[1] + "," + fields[2]
+				}
+			}
+			if existing, ok := functionsByName[function.Name]; ok {
+				// Keep the hits already recorded for a repeated declaration
+				existing.StartLine, existing.EndLine = function.StartLine, function.EndLine
+				continue
+			}
+			functionsByName[function.Name] = function
+			currentCoverage.Functions = append(currentCoverage.Functions, function)
+			continue
+		}
+
+		// FNDA: Function hits
+		// Format: FNDA:hit_count,name
+		if strings.HasPrefix(line, "FNDA:") {
+			hitsStr, name, ok := strings.Cut(strings.TrimPrefix(line, "FNDA:"), ",")
+			if !ok {
+				continue
+			}
+			hits, err := strconv.Atoi(hitsStr)
+			if err != nil {
+				continue
+			}
+			if function, ok := functionsByName[name]; ok {
+				function.Hits += hits
+			}
+			continue
+		}
+
+		// FNL: Function location (lcov 2.2), shared by aliases of the same function
+		// Format: FNL:index,start_line[,end_line]
+		if strings.HasPrefix(line, "FNL:") {
+			fields := strings.Split(strings.TrimPrefix(line, "FNL:"), ",")
+			if len(fields) < 2 {
+				continue
+			}
+			start, err := strconv.Atoi(fields[1])
+			if err != nil {
+				continue
+			}
+			function := &Function{StartLine: start}
+			if len(fields) > 2 {
+				function.EndLine, _ = strconv.Atoi(fields[2])
+			}
+			functionsByIndex[fields[0]] = function
+			continue
+		}
+
+		// FNA: Function alias and hits (lcov 2.2)
+		// Format: FNA:index,hit_count,name
+		if strings.HasPrefix(line, "FNA:") {
+			fields := strings.SplitN(strings.TrimPrefix(line, "FNA:"), ",", 3)
+			if len(fields) != 3 {
+				continue
+			}
+			location, ok := functionsByIndex[fields[0]]
+			if !ok {
+				continue
+			}
+			hits, err := strconv.Atoi(fields[1])
+			if err != nil {
+				continue
+			}
+			function := &Function{Name: fields[2], StartLine: location.StartLine, EndLine: location.EndLine, Hits: hits}
+			functionsByName[function.Name] = function
+			currentCoverage.Functions = append(currentCoverage.Functions, function)
+			continue
+		}
+
 		// end_of_record marks the end of a file's coverage data
 		if line == "end_of_record" {
+			currentCoverage.finishFunctions()
+			if len(currentCoverage.Branches) == 0 {
+				currentCoverage.TotalBranches = branchesFound
+				currentCoverage.CoveredBranches = branchesHit
+			}
 			currentFile = ""
 			currentCoverage = nil
+			branchesFound, branchesHit = 0, 0
 			continue
 		}
 	}
@@ -118,6 +400,16 @@ func (r *Report) GetCoverageForFile(filePath string) *CoverageData {
 	return r.FileCoverage[filePath]
 }
 
+// sortedPaths returns the file paths of the report in order
+func (r *Report) sortedPaths() []string {
+	paths := make([]string, 0, len(r.FileCoverage))
+	for filePath := range r.FileCoverage {
+		paths = append(paths, filePath)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
 // GetCoverageForLine returns the hit count for a specific line in a file
 // Returns 0 if the line is not covered or file doesn't exist
 func (r *Report) GetCoverageForLine(filePath string, lineNum int) int {
@@ -128,11 +420,53 @@ func (r *Report) GetCoverageForLine(filePath string, lineNum int) int {
 	return coverage.LineHits[lineNum]
 }
 
+// IsLineExcluded returns true if the coverage tool excluded the line from measurement
+func (r *Report) IsLineExcluded(filePath string, lineNum int) bool {
+	coverage := r.GetCoverageForFile(filePath)
+	if coverage == nil {
+		return false
+	}
+	return coverage.ExcludedLines[lineNum]
+}
+
 // IsLineCovered returns true if a line has been executed (hits > 0)
 func (r *Report) IsLineCovered(filePath string, lineNum int) bool {
 	return r.GetCoverageForLine(filePath, lineNum) > 0
 }
 
+// GetBranchCoverageForLine returns how many of the branches at a line were taken
+// and how many there are. Both are 0 if the line has no branch data.
+func (r *Report) GetBranchCoverageForLine(filePath string, lineNum int) (covered, total int) {
+	coverage := r.GetCoverageForFile(filePath)
+	if coverage == nil {
+		return 0, 0
+	}
+	return coverage.BranchCoverage(lineNum)
+}
+
+// GetFunctionsForFile returns the functions recorded for a file in order of their start line
+func (r *Report) GetFunctionsForFile(filePath string) []*Function {
+	coverage := r.GetCoverageForFile(filePath)
+	if coverage == nil {
+		return nil
+	}
+	return coverage.Functions
+}
+
+// IsLinePartiallyCovered returns true if a line was executed but not all of
+// its branches were taken, such as an if whose else-path never ran, or gcov
+// flagged a block on it that never ran
+func (r *Report) IsLinePartiallyCovered(filePath string, lineNum int) bool {
+	if !r.IsLineCovered(filePath, lineNum) {
+		return false
+	}
+	if r.GetCoverageForFile(filePath).UnexecutedBlocks[lineNum] {
+		return true
+	}
+	covered, total := r.GetBranchCoverageForLine(filePath, lineNum)
+	return total > 0 && covered < total
+}
+
 var (
 	repoRootCache     string
 	repoRootCacheOnce sync.Once
@@ -90,6 +90,145 @@ end_of_record
 	}
 }
 
+func TestParseLCOV_Branches(t *testing.T) {
+	lcovContent := `SF:file1.c
+DA:10,4
+DA:11,4
+DA:12,0
+BRDA:10,0,0,4
+BRDA:10,0,1,0
+BRDA:11,0,0,2
+BRDA:11,0,1,2
+BRDA:12,1,0,-
+BRDA:12,1,1,-
+BRDA:11,0,1,1
+BRF:6
+BRH:3
+end_of_record
+SF:file2.c
+DA:5,1
+BRF:4
+BRH:1
+end_of_record
+`
+
+	tmpfile, err := os.CreateTemp("", "test-branches-*.info")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := tmpfile.Write([]byte(lcovContent)); err != nil {
+		t.Fatalf("failed to write test data: %v", err)
+	}
+	tmpfile.Close()
+
+	report, err := ParseLCOV(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	file1 := report.GetCoverageForFile("file1.c")
+	if file1.TotalBranches != 6 || file1.CoveredBranches != 3 {
+		t.Errorf("expected 3 of 6 branches covered, got %d of %d", file1.CoveredBranches, file1.TotalBranches)
+	}
+	if covered, total := report.GetBranchCoverageForLine("file1.c", 10); covered != 1 || total != 2 {
+		t.Errorf("expected 1 of 2 branches taken at line 10, got %d of %d", covered, total)
+	}
+	if got := file1.Branches[11][1]; got.ID != "0,1" || got.Taken != 3 {
+		t.Errorf("expected repeated records of a branch to be summed, got %+v", got)
+	}
+
+	if !report.IsLinePartiallyCovered("file1.c", 10) {
+		t.Error("expected line 10 to be partially covered")
+	}
+	if report.IsLinePartiallyCovered("file1.c", 11) {
+		t.Error("expected line 11 to be fully covered")
+	}
+	if report.IsLinePartiallyCovered("file1.c", 12) {
+		t.Error("expected line 12 to be uncovered, not partially covered")
+	}
+
+	// Without BRDA records the summary counts are used
+	file2 := report.GetCoverageForFile("file2.c")
+	if file2.TotalBranches != 4 || file2.CoveredBranches != 1 || len(file2.Branches) != 0 {
+		t.Errorf("expected the BRF/BRH summary only, got %d of %d and %v", file2.CoveredBranches, file2.TotalBranches, file2.Branches)
+	}
+}
+
+func TestParseLCOV_Functions(t *testing.T) {
+	lcovContent := `SF:app.js
+FN:12,render
+FN:3,setup
+FN:20,30,teardown
+FNDA:4,setup
+FNDA:0,render
+FNDA:1,teardown
+FNDA:2,setup
+FNF:3
+FNH:2
+DA:3,4
+DA:4,4
+DA:12,0
+DA:13,0
+DA:21,1
+end_of_record
+SF:lib.c
+FNL:0,5,9
+FNA:0,2,helper
+FNA:0,1,helper_alias
+FNL:1,11
+FNA:1,0,unused
+DA:5,2
+DA:11,0
+DA:12,0
+end_of_record
+`
+
+	tmpfile, err := os.CreateTemp("", "test-functions-*.info")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := tmpfile.Write([]byte(lcovContent)); err != nil {
+		t.Fatalf("failed to write test data: %v", err)
+	}
+	tmpfile.Close()
+
+	report, err := ParseLCOV(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Sorted by start line, with inferred end lines where FN has none
+	want := []Function{
+		{Name: "setup", StartLine: 3, EndLine: 11, Hits: 6},
+		{Name: "render", StartLine: 12, EndLine: 19, Hits: 0},
+		{Name: "teardown", StartLine: 20, EndLine: 30, Hits: 1},
+	}
+	functions := report.GetFunctionsForFile("app.js")
+	if len(functions) != len(want) {
+		t.Fatalf("expected %d functions, got %d", len(want), len(functions))
+	}
+	for i, function := range functions {
+		if *function != want[i] {
+			t.Errorf("function %d: expected %+v, got %+v", i, want[i], *function)
+		}
+	}
+	if !functions[1].Contains(19) || functions[1].Contains(20) {
+		t.Error("expected render to end before teardown starts")
+	}
+
+	functions = report.GetFunctionsForFile("lib.c")
+	if len(functions) != 3 {
+		t.Fatalf("expected 3 functions from FNL/FNA records, got %d", len(functions))
+	}
+	if f := functions[2]; f.Name != "unused" || f.StartLine != 11 || f.EndLine != 12 || f.Hits != 0 {
+		t.Errorf("unexpected last function %+v", *f)
+	}
+}
+
 func TestParseLCOV_EmptyFile(t *testing.T) {
 	tmpfile, err := os.CreateTemp("", "test-empty-*.info")
 	if err != nil {
@@ -0,0 +1,291 @@
+package coverage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LLVMCovExport represents the output of "llvm-cov export -format=text",
+// which is also what "cargo llvm-cov --json" writes
+type LLVMCovExport struct {
+	Type    string        `json:"type"`
+	Version string        `json:"version"`
+	Data    []LLVMCovData `json:"data"`
+}
+
+// LLVMCovData holds the files and functions of one export
+type LLVMCovData struct {
+	Files     []LLVMCovFile     `json:"files"`
+	Functions []LLVMCovFunction `json:"functions"`
+}
+
+// LLVMCovFile holds the coverage segments and branches of one source file
+type LLVMCovFile struct {
+	Filename string `json:"filename"`
+	// Segments are [line, col, count, hasCount, isRegionEntry, isGapRegion]
+	// arrays sorted by position; exports before version 2.0.1 have no isGapRegion
+	Segments [][]interface{} `json:"segments"`
+	// Branches are [lineStart, colStart, lineEnd, colEnd, trueCount, falseCount,
+	// fileID, expandedFileID, kind] arrays
+	Branches [][]interface{} `json:"branches"`
+}
+
+// LLVMCovFunction describes a function; its first region spans its body in Filenames[0]
+type LLVMCovFunction struct {
+	Name      string   `json:"name"`
+	Count     int      `json:"count"`
+	Filenames []string `json:"filenames"`
+	// Regions are [lineStart, colStart, lineEnd, colEnd, count, fileID, expandedFileID, kind] arrays
+	Regions [][]interface{} `json:"regions"`
+}
+
+// llvmSegment is the start of a span of code with one execution count
+type llvmSegment struct {
+	Line          int
+	Count         int
+	HasCount      bool
+	IsRegionEntry bool
+	IsGapRegion   bool
+}
+
+// detectLLVMCov recognizes llvm-cov export JSON. Its "type" key comes last, after
+// the data, so in large exports only the "data" and "files" keys are seen.
+func detectLLVMCov(path string, head []byte) float64 {
+	content := string(head)
+	if !strings.HasPrefix(strings.TrimSpace(content), "{") {
+		return ConfidenceNone
+	}
+	if strings.Contains(content, "llvm.coverage.json.export") {
+		return ConfidenceCertain
+	}
+	if strings.Contains(content, `"data"`) && strings.Contains(content, `"files"`) {
+		return ConfidenceLikely
+	}
+	return ConfidenceNone
+}
+
+// ParseLLVMCov parses an llvm-cov export JSON file
+// Returns a Report containing coverage data for all files
+func ParseLLVMCov(filePath string) (*Report, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open llvm-cov file: %w", err)
+	}
+	defer file.Close()
+
+	return decodeLLVMCov(file)
+}
+
+// decodeLLVMCov reads an llvm-cov export from r
+func decodeLLVMCov(r io.Reader) (*Report, error) {
+	var export LLVMCovExport
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	if err := decoder.Decode(&export); err != nil {
+		return nil, fmt.Errorf("failed to parse llvm-cov JSON: %w", err)
+	}
+	if export.Data == nil {
+		return nil, fmt.Errorf("not an llvm-cov export (no data found)")
+	}
+
+	report := &Report{
+		FileCoverage: make(map[string]*CoverageData),
+	}
+
+	for _, data := range export.Data {
+		for _, llvmFile := range data.Files {
+			fileCoverage := report.llvmFileCoverage(llvmFile.Filename)
+
+			segments, err := parseLLVMSegments(llvmFile.Segments)
+			if err != nil {
+				return nil, fmt.Errorf("invalid segments for %s: %w", llvmFile.Filename, err)
+			}
+			for lineNum, hits := range llvmLineCounts(segments) {
+				fileCoverage.setLineHits(lineNum, hits)
+			}
+
+			// Each branch record is a condition with a true and a false outcome
+			for i, branch := range llvmFile.Branches {
+				values, err := llvmInts(branch, 6)
+				if err != nil {
+					return nil, fmt.Errorf("invalid branch for %s: %w", llvmFile.Filename, err)
+				}
+				id := strconv.Itoa(i)
+				fileCoverage.addBranch(values[0], id+",0", values[4])
+				fileCoverage.addBranch(values[0], id+",1", values[5])
+			}
+		}
+
+		for _, function := range data.Functions {
+			if err := report.addLLVMFunction(function); err != nil {
+				return nil, fmt.Errorf("invalid function %s: %w", function.Name, err)
+			}
+		}
+	}
+
+	for _, fileCoverage := range report.FileCoverage {
+		fileCoverage.finishFunctions()
+	}
+
+	return report, nil
+}
+
+// llvmFileCoverage returns the coverage of a file, creating it if needed
+func (r *Report) llvmFileCoverage(filename string) *CoverageData {
+	filePath := NormalizePath(filename)
+	fileCoverage := r.FileCoverage[filePath]
+	if fileCoverage == nil {
+		fileCoverage = &CoverageData{
+			LineHits: make(map[int]int),
+		}
+		r.FileCoverage[filePath] = fileCoverage
+	}
+	return fileCoverage
+}
+
+// addLLVMFunction records a function in the file its body is in. Template and
+// generic instantiations share their source range and are counted as one function.
+func (r *Report) addLLVMFunction(function LLVMCovFunction) error {
+	if len(function.Regions) == 0 || len(function.Filenames) == 0 {
+		return nil
+	}
+	body, err := llvmInts(function.Regions[0], 6)
+	if err != nil {
+		return err
+	}
+	if body[5] >= len(function.Filenames) {
+		return fmt.Errorf("region refers to unknown file %d", body[5])
+	}
+
+	fileCoverage := r.llvmFileCoverage(function.Filenames[body[5]])
+	for _, existing := range fileCoverage.Functions {
+		if existing.StartLine == body[0] && existing.EndLine == body[2] {
+			existing.Hits += function.Count
+			return nil
+		}
+	}
+	fileCoverage.Functions = append(fileCoverage.Functions, &Function{
+		Name:      function.Name,
+		StartLine: body[0],
+		EndLine:   body[2],
+		Hits:      function.Count,
+	})
+	return nil
+}
+
+// parseLLVMSegments converts the segment arrays of a file
+func parseLLVMSegments(raw [][]interface{}) ([]llvmSegment, error) {
+	segments := make([]llvmSegment, 0, len(raw))
+	for _, values := range raw {
+		if len(values) < 5 {
+			return nil, fmt.Errorf("segment has %d fields, expected at least 5", len(values))
+		}
+		ints, err := llvmInts(values, len(values))
+		if err != nil {
+			return nil, err
+		}
+		segment := llvmSegment{
+			Line:          ints[0],
+			Count:         ints[2],
+			HasCount:      ints[3] != 0,
+			IsRegionEntry: ints[4] != 0,
+		}
+		if len(ints) > 5 {
+			segment.IsGapRegion = ints[5] != 0
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+// llvmLineCounts computes the execution count of each mapped line the way
+// "llvm-cov show" does. A line's count is the highest count of the regions
+// starting on it and of the segment wrapping into it from an earlier line; gap
+// regions, which cover the space between regions such as a closing brace,
+// only count when they wrap into a line. Lines starting a skipped region
+// (e.g. code removed by the preprocessor) and lines without counts are not mapped.
+func llvmLineCounts(segments []llvmSegment) map[int]int {
+	counts := make(map[int]int)
+	if len(segments) == 0 {
+		return counts
+	}
+
+	isStartOfRegion := func(s llvmSegment) bool {
+		return !s.IsGapRegion && s.HasCount && s.IsRegionEntry
+	}
+
+	var wrapped *llvmSegment
+	var lineSegments []llvmSegment
+	next := 0
+	for line := segments[0].Line; next < len(segments); line++ {
+		if len(lineSegments) > 0 {
+			last := lineSegments[len(lineSegments)-1]
+			wrapped = &last
+		}
+		lineSegments = lineSegments[:0]
+		for next < len(segments) && segments[next].Line == line {
+			lineSegments = append(lineSegments, segments[next])
+			next++
+		}
+
+		regionStarts := 0
+		mappedByEntry := false
+		for _, s := range lineSegments {
+			if isStartOfRegion(s) {
+				regionStarts++
+			}
+			if s.IsRegionEntry && s.HasCount {
+				mappedByEntry = true
+			}
+		}
+		startsSkippedRegion := len(lineSegments) > 0 && !lineSegments[0].HasCount && lineSegments[0].IsRegionEntry
+
+		mapped := !startsSkippedRegion && ((wrapped != nil && wrapped.HasCount) || regionStarts > 0)
+		if !mapped && !mappedByEntry {
+			continue
+		}
+
+		count := 0
+		if wrapped != nil {
+			count = wrapped.Count
+		}
+		for _, s := range lineSegments {
+			if isStartOfRegion(s) && s.Count > count {
+				count = s.Count
+			}
+		}
+		counts[line] = count
+	}
+
+	return counts
+}
+
+// llvmInts converts the fields of a record, which must have at least n of them,
+// to integers; flags are written as booleans and become 0 or 1
+func llvmInts(values []interface{}, n int) ([]int, error) {
+	if len(values) < n {
+		return nil, fmt.Errorf("record has %d fields, expected at least %d", len(values), n)
+	}
+	ints := make([]int, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case json.Number:
+			n, err := v.Int64()
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", v)
+			}
+			ints[i] = int(n)
+		case bool:
+			if v {
+				ints[i] = 1
+			}
+		default:
+			return nil, fmt.Errorf("invalid field %v", value)
+		}
+	}
+	return ints, nil
+}
@@ -0,0 +1,120 @@
+package coverage
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseLLVMCov(t *testing.T) {
+	// main.c:
+	//  1 int main() {
+	//  2   int x = 0;
+	//  3   if (x)
+	//  4     return 1;
+	//  5   return 0;
+	//  6 }
+	//  7
+	//  8 #if 0
+	//  9 #endif
+	llvmContent := `{
+  "data": [{
+    "files": [{
+      "filename": "src/main.c",
+      "segments": [
+        [1, 12, 1, true, true, false],
+        [3, 9, 0, true, true, true],
+        [4, 5, 0, true, true, false],
+        [4, 14, 1, true, true, true],
+        [5, 3, 1, true, false, false],
+        [6, 2, 0, false, false, false],
+        [8, 1, 0, false, true, false],
+        [9, 7, 0, false, false, false]
+      ],
+      "branches": [[3, 7, 3, 8, 0, 1, 0, 0, 4]]
+    }, {
+      "filename": "src/lib.rs",
+      "segments": [
+        [1, 26, 2, true, true],
+        [3, 2, 0, false, false]
+      ]
+    }],
+    "functions": [
+      {"name": "main", "count": 1, "regions": [[1, 12, 6, 2, 1, 0, 0, 0]], "filenames": ["src/main.c"]},
+      {"name": "_RINvCs1_3lib8identitylEB2_", "count": 0, "regions": [[1, 26, 3, 2, 0, 0, 0, 0]], "filenames": ["src/lib.rs"]},
+      {"name": "_RINvCs1_3lib8identitymEB2_", "count": 2, "regions": [[1, 26, 3, 2, 2, 0, 0, 0]], "filenames": ["src/lib.rs"]}
+    ]
+  }],
+  "type": "llvm.coverage.json.export",
+  "version": "2.0.1"
+}`
+
+	tmpfile, err := os.CreateTemp("", "llvm-cov-*.json")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := tmpfile.Write([]byte(llvmContent)); err != nil {
+		t.Fatalf("failed to write test data: %v", err)
+	}
+	tmpfile.Close()
+
+	report, err := ParseLLVMCov(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fileCoverage := report.GetCoverageForFile("src/main.c")
+	if fileCoverage == nil {
+		t.Fatal("expected coverage data for src/main.c")
+	}
+
+	// Line 4 only has the gap after "return 1;" counted, which does not start a
+	// region; line 6 gets the count wrapping in from line 5; the blank line 7 and
+	// the skipped lines 8 and 9 are not mapped
+	expected := map[int]int{1: 1, 2: 1, 3: 1, 4: 0, 5: 1, 6: 1}
+	if !reflect.DeepEqual(fileCoverage.LineHits, expected) {
+		t.Errorf("expected line hits %v, got %v", expected, fileCoverage.LineHits)
+	}
+	if fileCoverage.TotalLines != 6 || fileCoverage.CoveredLines != 5 {
+		t.Errorf("expected 5 of 6 lines covered, got %d of %d", fileCoverage.CoveredLines, fileCoverage.TotalLines)
+	}
+
+	if covered, total := report.GetBranchCoverageForLine("src/main.c", 3); covered != 1 || total != 2 {
+		t.Errorf("expected 1 of 2 branches on line 3, got %d of %d", covered, total)
+	}
+
+	// Segments without the gap flag, from older exports, are read too
+	if got := report.GetCoverageForFile("src/lib.rs").LineHits; !reflect.DeepEqual(got, map[int]int{1: 2, 2: 2, 3: 2}) {
+		t.Errorf("unexpected line hits for src/lib.rs: %v", got)
+	}
+
+	functions := report.GetFunctionsForFile("src/main.c")
+	if len(functions) != 1 || functions[0].Name != "main" || functions[0].EndLine != 6 || functions[0].Hits != 1 {
+		t.Errorf("unexpected functions %+v", functions)
+	}
+
+	// The two instantiations of the generic function are one function
+	functions = report.GetFunctionsForFile("src/lib.rs")
+	if len(functions) != 1 || functions[0].Hits != 2 {
+		t.Errorf("expected one function with 2 hits for src/lib.rs, got %+v", functions)
+	}
+}
+
+func TestParseLLVMCov_InvalidSegment(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "llvm-cov-*.json")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := tmpfile.Write([]byte(`{"data": [{"files": [{"filename": "a.c", "segments": [[1, 2]]}]}]}`)); err != nil {
+		t.Fatalf("failed to write test data: %v", err)
+	}
+	tmpfile.Close()
+
+	if _, err := ParseLLVMCov(tmpfile.Name()); err == nil {
+		t.Error("expected error for a segment with too few fields")
+	}
+}
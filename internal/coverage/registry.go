@@ -0,0 +1,265 @@
+package coverage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Parser reads one coverage format. The commands pick a parser by name
+// (--coverage-format) or by detection; Register makes further formats available.
+type Parser interface {
+	// Name identifies the format, e.g. "lcov"
+	Name() string
+	// Detect returns how confident the parser is that the file at path is in its
+	// format, from ConfidenceNone to ConfidenceCertain, given the start of its
+	// content (decompressed if gzipped). head is nil if path is a directory.
+	Detect(path string, head []byte) float64
+	// Parse reads a report from the content of a coverage file
+	Parse(r io.Reader) (*Report, error)
+}
+
+// FileParser is implemented by parsers that read coverage from paths rather
+// than from a single stream, such as Go profiles, which can be merged, and
+// GOCOVERDIR directories
+type FileParser interface {
+	Parser
+	// ParseFiles reads the coverage at paths, merging it if there are several
+	ParseFiles(paths ...string) (*Report, error)
+}
+
+// Detection confidences. A parser is certain when the content has markers only
+// its format uses, and likely when the markers are shared with other formats or
+// it goes by the file name; LCOV is the fallback for unrecognized files.
+const (
+	ConfidenceNone     = 0.0
+	ConfidenceFallback = 0.1
+	ConfidenceLikely   = 0.5
+	ConfidenceCertain  = 1.0
+)
+
+// detectHeadSize is how much of a file's content detection looks at
+const detectHeadSize = 1000
+
+var (
+	parsersMu sync.RWMutex
+	// parsers holds the registered parsers. The built-in formats are listed in
+	// the order they are checked in, as a tie in confidence goes to the parser
+	// registered first.
+	parsers = []Parser{
+		goCoverDirParser{},
+		&formatParser{name: "jacoco", detect: detectJaCoCo, decode: func(r io.Reader) (*Report, error) {
+			return decodeJaCoCo(r, jacocoSourceRoot())
+		}},
+		&formatParser{name: "clover", detect: detectClover, decode: decodeClover},
+		&formatParser{name: "sonar", detect: detectSonarGeneric, decode: decodeSonarGeneric},
+		&formatParser{name: "cobertura", detect: detectCobertura, decode: decodeCobertura},
+		&formatParser{name: "istanbul", detect: detectIstanbul, decode: decodeIstanbul},
+		&formatParser{name: "coveragepy", detect: detectCoveragePy, decode: decodeCoveragePy},
+		gcovParser{},
+		&formatParser{name: "llvmcov", detect: detectLLVMCov, decode: decodeLLVMCov},
+		&formatParser{name: "lcov", detect: detectLCOV, decode: decodeLCOV},
+		goProfileParser{},
+	}
+)
+
+// formatParser is a Parser made of a format's detection and decoding functions
+type formatParser struct {
+	name   string
+	detect func(path string, head []byte) float64
+	decode func(r io.Reader) (*Report, error)
+}
+
+func (p *formatParser) Name() string {
+	return p.name
+}
+
+func (p *formatParser) Detect(path string, head []byte) float64 {
+	return p.detect(path, head)
+}
+
+func (p *formatParser) Parse(r io.Reader) (*Report, error) {
+	return p.decode(r)
+}
+
+// Register makes a parser available by name and for detection. It panics if a
+// parser with the same name is already registered.
+func Register(parser Parser) {
+	parsersMu.Lock()
+	defer parsersMu.Unlock()
+
+	for _, existing := range parsers {
+		if existing.Name() == parser.Name() {
+			panic("coverage: Register called twice for format " + parser.Name())
+		}
+	}
+	parsers = append(parsers, parser)
+}
+
+// Parsers returns the registered parsers in registration order
+func Parsers() []Parser {
+	parsersMu.RLock()
+	defer parsersMu.RUnlock()
+
+	return append([]Parser(nil), parsers...)
+}
+
+// ParserNames returns the names of the registered formats
+func ParserNames() []string {
+	var names []string
+	for _, parser := range Parsers() {
+		names = append(names, parser.Name())
+	}
+	return names
+}
+
+// LookupParser returns the parser of the named format
+func LookupParser(name string) (Parser, error) {
+	for _, parser := range Parsers() {
+		if parser.Name() == name {
+			return parser, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown coverage format %q (supported: %s)", name, strings.Join(ParserNames(), ", "))
+}
+
+// detection is a parser that recognized a file, with its confidence
+type detection struct {
+	parser     Parser
+	confidence float64
+}
+
+// detectParsers returns the parsers recognizing the file or directory at path,
+// most confident first
+func detectParsers(path string) ([]detection, error) {
+	head, err := readDetectHead(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var detections []detection
+	for _, parser := range Parsers() {
+		if confidence := parser.Detect(path, head); confidence > ConfidenceNone {
+			detections = append(detections, detection{parser: parser, confidence: confidence})
+		}
+	}
+	sort.SliceStable(detections, func(i, j int) bool {
+		return detections[i].confidence > detections[j].confidence
+	})
+
+	if len(detections) == 0 {
+		if head == nil {
+			return nil, fmt.Errorf("%s is a directory without coverage data", path)
+		}
+		return nil, fmt.Errorf("unrecognized coverage format in %s", path)
+	}
+	return detections, nil
+}
+
+// readDetectHead returns the start of a file's content, decompressed if it is
+// gzipped, or nil for a directory
+func readDetectHead(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, nil
+	}
+
+	reader, err := openMaybeGzipped(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	head, err := io.ReadAll(io.LimitReader(reader, detectHeadSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if head == nil {
+		head = []byte{}
+	}
+	return head, nil
+}
+
+// DetectCoverageFormat returns the name of the most likely format of a coverage
+// file or directory among the registered parsers
+func DetectCoverageFormat(filePath string) (string, error) {
+	detections, err := detectParsers(filePath)
+	if err != nil {
+		return "", err
+	}
+	return detections[0].parser.Name(), nil
+}
+
+// ParseCoverage parses the coverage at paths, merging it if there are several
+// paths. format names the parser to use; if it is "" or "auto" the format is
+// detected from the first path, and the others must be in the same format. When
+// the most likely parser fails on a file it is not certain about, the other
+// parsers recognizing the file are tried in turn, ending with LCOV.
+func ParseCoverage(paths []string, format string) (*Report, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no coverage file given")
+	}
+
+	if format != "" && format != "auto" {
+		parser, err := LookupParser(format)
+		if err != nil {
+			return nil, err
+		}
+		return parseCoverageFiles(parser, paths)
+	}
+
+	detections, err := detectParsers(paths[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths[1:] {
+		other, err := detectParsers(path)
+		if err != nil {
+			return nil, err
+		}
+		if other[0].parser.Name() != detections[0].parser.Name() {
+			return nil, fmt.Errorf("cannot merge %s coverage (%s) with %s coverage (%s)",
+				other[0].parser.Name(), path, detections[0].parser.Name(), paths[0])
+		}
+	}
+
+	var firstErr error
+	for _, detection := range detections {
+		report, err := parseCoverageFiles(detection.parser, paths)
+		if err == nil {
+			return report, nil
+		}
+		if firstErr == nil {
+			firstErr = fmt.Errorf("failed to parse %s coverage: %w", detection.parser.Name(), err)
+		}
+		// A file certainly in one format is not retried as another
+		if detection.confidence >= ConfidenceCertain {
+			break
+		}
+	}
+	return nil, firstErr
+}
+
+// parseCoverageFiles parses the coverage at paths with one parser
+func parseCoverageFiles(parser Parser, paths []string) (*Report, error) {
+	if fileParser, ok := parser.(FileParser); ok {
+		return fileParser.ParseFiles(paths...)
+	}
+	if len(paths) > 1 {
+		return nil, fmt.Errorf("%s coverage files cannot be merged", parser.Name())
+	}
+
+	reader, err := openMaybeGzipped(paths[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to open coverage file: %w", err)
+	}
+	defer reader.Close()
+
+	return parser.Parse(reader)
+}
@@ -0,0 +1,210 @@
+package coverage
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testParser is a parser of files with the .tfmt extension
+type testParser struct {
+	name       string
+	confidence float64
+	err        error
+}
+
+func (p *testParser) Name() string {
+	return p.name
+}
+
+func (p *testParser) Detect(path string, head []byte) float64 {
+	if filepath.Ext(path) == ".tfmt" {
+		return p.confidence
+	}
+	return ConfidenceNone
+}
+
+func (p *testParser) Parse(r io.Reader) (*Report, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{FileCoverage: make(map[string]*CoverageData)}
+	report.FileCoverage[strings.TrimSpace(string(data))] = &CoverageData{
+		LineHits:     map[int]int{1: 1},
+		TotalLines:   1,
+		CoveredLines: 1,
+	}
+	return report, nil
+}
+
+// registerTestParser registers a parser for the duration of the test
+func registerTestParser(t *testing.T, parser Parser) {
+	t.Helper()
+	Register(parser)
+	t.Cleanup(func() {
+		parsersMu.Lock()
+		defer parsersMu.Unlock()
+		for i, registered := range parsers {
+			if registered.Name() == parser.Name() {
+				parsers = append(parsers[:i:i], parsers[i+1:]...)
+				return
+			}
+		}
+	})
+}
+
+func TestRegister(t *testing.T) {
+	registerTestParser(t, &testParser{name: "tfmt", confidence: ConfidenceCertain})
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "coverage.tfmt", "src/app.x\n")
+	path := filepath.Join(dir, "coverage.tfmt")
+
+	format, err := DetectCoverageFormat(path)
+	if err != nil || format != "tfmt" {
+		t.Errorf("DetectCoverageFormat() = %q, %v, want tfmt", format, err)
+	}
+	report, err := ParseCoverage([]string{path}, "auto")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.FileCoverage["src/app.x"] == nil {
+		t.Errorf("expected coverage from the registered parser, got %v", report.FileCoverage)
+	}
+
+	if _, err := LookupParser("tfmt"); err != nil {
+		t.Errorf("expected the registered parser to be found: %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering a format twice to panic")
+		}
+	}()
+	Register(&testParser{name: "lcov"})
+}
+
+func TestLookupParser_Unknown(t *testing.T) {
+	_, err := LookupParser("nope")
+	if err == nil {
+		t.Fatal("expected error for an unknown format")
+	}
+	if !strings.Contains(err.Error(), "lcov") || !strings.Contains(err.Error(), "gocoverdir") {
+		t.Errorf("expected the error to list the supported formats, got %v", err)
+	}
+}
+
+func TestParseCoverage_Format(t *testing.T) {
+	dir := t.TempDir()
+	// A .out file without LCOV markers at the start is detected as a Go profile
+	writeTestFile(t, dir, "lcov.out", "some header\n"+strings.Repeat("\n", detectHeadSize)+"SF:a.go\nDA:3,1\nend_of_record\n")
+	path := filepath.Join(dir, "lcov.out")
+
+	if format, _ := DetectCoverageFormat(path); format != "go" {
+		t.Fatalf("expected the file to be detected as go, got %q", format)
+	}
+
+	report, err := ParseCoverage([]string{path}, "lcov")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if coverage := report.FileCoverage["a.go"]; coverage == nil || coverage.LineHits[3] != 1 {
+		t.Errorf("expected a.go line 3 to be covered, got %v", report.FileCoverage)
+	}
+
+	// A named format is not retried as another format
+	if _, err := ParseCoverage([]string{path}, "cobertura"); err == nil {
+		t.Error("expected error parsing LCOV as Cobertura")
+	}
+	if _, err := ParseCoverage([]string{path}, "nope"); err == nil {
+		t.Error("expected error for an unknown format")
+	}
+}
+
+func TestParseCoverage_Fallback(t *testing.T) {
+	parseErr := errors.New("not really tfmt")
+	registerTestParser(t, &testParser{name: "tfmt", confidence: ConfidenceLikely, err: parseErr})
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "coverage.tfmt", "DA:3,1\n")
+	path := filepath.Join(dir, "coverage.tfmt")
+
+	// The likely parser fails, so the file is read as LCOV
+	if _, err := ParseCoverage([]string{path}, ""); err != nil {
+		t.Errorf("expected fallback to LCOV, got %v", err)
+	}
+}
+
+func TestParseCoverage_NoFallbackWhenCertain(t *testing.T) {
+	parseErr := errors.New("broken tfmt")
+	registerTestParser(t, &testParser{name: "tfmt", confidence: ConfidenceCertain, err: parseErr})
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "coverage.tfmt", "DA:3,1\n")
+
+	_, err := ParseCoverage([]string{filepath.Join(dir, "coverage.tfmt")}, "")
+	if !errors.Is(err, parseErr) {
+		t.Errorf("expected the tfmt error, got %v", err)
+	}
+}
+
+func TestParseCoverage_Ambiguous(t *testing.T) {
+	dir := t.TempDir()
+	// Cobertura XML in a .out file: both Cobertura and Go profiles are likely
+	writeTestFile(t, dir, "coverage.out", `<?xml version="1.0" ?>
+<coverage line-rate="1">
+  <packages>
+    <package name="app">
+      <classes>
+        <class name="app" filename="app/main.go">
+          <lines><line number="4" hits="2"/></lines>
+        </class>
+      </classes>
+    </package>
+  </packages>
+</coverage>
+`)
+
+	report, err := ParseCoverage([]string{filepath.Join(dir, "coverage.out")}, "auto")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if coverage := report.FileCoverage["app/main.go"]; coverage == nil || coverage.LineHits[4] != 2 {
+		t.Errorf("expected app/main.go line 4 to have 2 hits, got %v", report.FileCoverage)
+	}
+}
+
+func TestParseCoverage_Merge(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "shard1.out", "mode: set\nexample.com/app/a.go:3.10,5.2 2 1\n")
+	writeTestFile(t, dir, "shard2.out", "mode: set\nexample.com/app/a.go:3.10,5.2 2 0\n")
+	writeTestFile(t, dir, "coverage.info", "SF:a.go\nDA:3,1\nend_of_record\n")
+	writeTestFile(t, dir, "other.info", "SF:b.go\nDA:3,1\nend_of_record\n")
+	shard1 := filepath.Join(dir, "shard1.out")
+	shard2 := filepath.Join(dir, "shard2.out")
+	lcov := filepath.Join(dir, "coverage.info")
+	otherLCOV := filepath.Join(dir, "other.info")
+
+	if _, err := ParseCoverage([]string{shard1, shard2}, "auto"); err != nil {
+		t.Errorf("unexpected error merging Go profiles: %v", err)
+	}
+	if _, err := ParseCoverage([]string{shard1, lcov}, "auto"); err == nil {
+		t.Error("expected error merging a Go profile with LCOV coverage")
+	}
+	if _, err := ParseCoverage([]string{lcov, otherLCOV}, "auto"); err == nil {
+		t.Error("expected error merging LCOV files")
+	}
+}
+
+func TestDetectCoverageFormat_Directory(t *testing.T) {
+	_, err := DetectCoverageFormat(t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "directory without coverage data") {
+		t.Errorf("expected error for a directory without coverage data, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package coverage
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestParseSonarGeneric(t *testing.T) {
+	sonarContent := `<coverage version="1">
+  <file path="src/app.js">
+    <lineToCover lineNumber="2" covered="true"/>
+    <lineToCover lineNumber="3" covered="true" branchesToCover="4" coveredBranches="3"/>
+    <lineToCover lineNumber="6" covered="false"/>
+  </file>
+  <file path="src/empty.js"/>
+</coverage>
+`
+
+	tmpfile, err := os.CreateTemp("", "sonar-*.xml")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := tmpfile.Write([]byte(sonarContent)); err != nil {
+		t.Fatalf("failed to write test data: %v", err)
+	}
+	tmpfile.Close()
+
+	report, err := ParseSonarGeneric(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fileCoverage := report.GetCoverageForFile("src/app.js")
+	if fileCoverage == nil {
+		t.Fatal("expected coverage data for src/app.js")
+	}
+	if fileCoverage.TotalLines != 3 || fileCoverage.CoveredLines != 2 {
+		t.Errorf("expected 2 of 3 lines covered, got %d of %d", fileCoverage.CoveredLines, fileCoverage.TotalLines)
+	}
+	if covered, total := report.GetBranchCoverageForLine("src/app.js", 3); covered != 3 || total != 4 {
+		t.Errorf("expected 3 of 4 branches on line 3, got %d of %d", covered, total)
+	}
+	if report.GetCoverageForFile("src/empty.js") == nil {
+		t.Error("expected a file without lines to be listed")
+	}
+}
+
+func TestWriteSonarGeneric(t *testing.T) {
+	report := &Report{
+		FileCoverage: map[string]*CoverageData{
+			"b.go": {LineHits: map[int]int{1: 0}},
+			"a.go": {
+				LineHits: map[int]int{4: 2, 3: 1},
+				Branches: map[int][]Branch{4: {{ID: "0", Taken: 2}, {ID: "1", Taken: 0}}},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSonarGeneric(&buf, report); err != nil {
+		t.Fatalf("WriteSonarGeneric() error = %v", err)
+	}
+
+	expected := `<coverage version="1">
+  <file path="a.go">
+    <lineToCover lineNumber="3" covered="true"></lineToCover>
+    <lineToCover lineNumber="4" covered="true" branchesToCover="2" coveredBranches="1"></lineToCover>
+  </file>
+  <file path="b.go">
+    <lineToCover lineNumber="1" covered="false"></lineToCover>
+  </file>
+</coverage>
+`
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
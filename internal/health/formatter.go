@@ -14,6 +14,7 @@ type FormatHealthReport struct {
 	Files           []FileSection           `json:"files"`
 	Insights        []InsightSection        `json:"insights"`
 	Recommendations []RecommendationSection `json:"recommendations"`
+	Skipped         []SkippedSection        `json:"skipped,omitempty"`
 }
 
 type SummarySection struct {
@@ -44,20 +45,20 @@ type ChangesSection struct {
 }
 
 type FileSection struct {
-	FilePath               string  `json:"file_path"`
-	IsNewFile              bool    `json:"is_new_file"`
-	OverallCoverage        float64 `json:"overall_coverage"`
-	ChangedCoverage        float64 `json:"changed_coverage"`
-	BaselineCoverage       float64 `json:"baseline_coverage"`
-	CoverageDelta          float64 `json:"coverage_delta"`
-	ChangedLines           int     `json:"changed_lines"`
-	CoveredLines           int     `json:"covered_lines"`
-	UncoveredLines         int     `json:"uncovered_lines"`
-	UnitTestCoverage       float64 `json:"unit_test_coverage"`
-	APITestCoverage        float64 `json:"api_test_coverage"`
-	FunctionalTestCoverage float64 `json:"functional_test_coverage"`
-	Status                 string  `json:"status"` // "healthy", "at_risk", "regressing"
-	UncoveredLineNumbers   []int   `json:"uncovered_line_numbers"`
+	FilePath               string   `json:"file_path"`
+	IsNewFile              bool     `json:"is_new_file"`
+	OverallCoverage        float64  `json:"overall_coverage"`
+	ChangedCoverage        float64  `json:"changed_coverage"`
+	BaselineCoverage       *float64 `json:"baseline_coverage,omitempty"`
+	CoverageDelta          *float64 `json:"coverage_delta,omitempty"`
+	ChangedLines           int      `json:"changed_lines"`
+	CoveredLines           int      `json:"covered_lines"`
+	UncoveredLines         int      `json:"uncovered_lines"`
+	UnitTestCoverage       float64  `json:"unit_test_coverage"`
+	APITestCoverage        float64  `json:"api_test_coverage"`
+	FunctionalTestCoverage float64  `json:"functional_test_coverage"`
+	Status                 string   `json:"status"` // "healthy", "at_risk", "regressing"
+	UncoveredLineNumbers   []int    `json:"uncovered_line_numbers"`
 }
 
 type InsightSection struct {
@@ -69,6 +70,12 @@ type InsightSection struct {
 	Severity    string `json:"severity"`
 }
 
+type SkippedSection struct {
+	FilePath string `json:"file_path"`
+	Reason   string `json:"reason"`
+	Detail   string `json:"detail,omitempty"`
+}
+
 type RecommendationSection struct {
 	Priority    string   `json:"priority"`
 	Category    string   `json:"category"`
@@ -145,14 +152,15 @@ func (r *HealthReport) ToMarkdown() string {
 				testTypesStr = "None"
 			}
 
-			deltaStr := fmt.Sprintf("%+.1f%%", fileHealth.CoverageDelta)
-			if fileHealth.IsNewFile {
-				deltaStr = "N/A"
+			baselineStr, deltaStr := "N/A", "N/A"
+			if fileHealth.HasBaseline {
+				baselineStr = fmt.Sprintf("%.1f%%", fileHealth.BaselineCoveragePercentage)
+				deltaStr = fmt.Sprintf("%+.1f%%", fileHealth.CoverageDelta)
 			}
 
-			sb.WriteString(fmt.Sprintf("| `%s` | %s | %.1f%% | %.1f%% | %s | %s |\n",
+			sb.WriteString(fmt.Sprintf("| `%s` | %s | %.1f%% | %s | %s | %s |\n",
 				filePath, status, fileHealth.ChangedCoveragePercentage,
-				fileHealth.BaselineCoveragePercentage, deltaStr, testTypesStr))
+				baselineStr, deltaStr, testTypesStr))
 		}
 		sb.WriteString("\n")
 	}
@@ -206,6 +214,16 @@ func (r *HealthReport) ToMarkdown() string {
 		}
 	}
 
+	// Skipped files
+	if len(r.SkippedFiles) > 0 {
+		sb.WriteString("## Skipped Files\n\n")
+		sb.WriteString("These files changed but have no lines to analyze:\n\n")
+		for _, skipped := range r.SkippedFiles {
+			sb.WriteString(fmt.Sprintf("- `%s`: %s\n", skipped.Path, skipped.Description()))
+		}
+		sb.WriteString("\n")
+	}
+
 	return sb.String()
 }
 
@@ -243,7 +261,7 @@ func (r *HealthReport) ToStructuredText() string {
 		}
 		sb.WriteString(fmt.Sprintf("  Changed Lines Coverage: %.1f%% (%d/%d lines covered)\n",
 			fileHealth.ChangedCoveragePercentage, fileHealth.ChangedCoveredLines, fileHealth.ChangedLines))
-		if !fileHealth.IsNewFile {
+		if fileHealth.HasBaseline {
 			sb.WriteString(fmt.Sprintf("  Baseline Coverage: %.1f%%\n", fileHealth.BaselineCoveragePercentage))
 			sb.WriteString(fmt.Sprintf("  Coverage Delta: %+.1f%%\n", fileHealth.CoverageDelta))
 		}
@@ -291,6 +309,15 @@ func (r *HealthReport) ToStructuredText() string {
 		}
 	}
 
+	// Skipped files
+	if len(r.SkippedFiles) > 0 {
+		sb.WriteString("\n\nSKIPPED FILES (NOT EVALUATED)\n")
+		sb.WriteString("-----------------------------\n")
+		for _, skipped := range r.SkippedFiles {
+			sb.WriteString(fmt.Sprintf("  %s: %s\n", skipped.Path, skipped.Description()))
+		}
+	}
+
 	return sb.String()
 }
 
@@ -335,13 +362,11 @@ func (r *HealthReport) toFormatted() *FormatHealthReport {
 			status = "at_risk"
 		}
 
-		formatted.Files = append(formatted.Files, FileSection{
+		fileSection := FileSection{
 			FilePath:               filePath,
 			IsNewFile:              fileHealth.IsNewFile,
 			OverallCoverage:        fileHealth.CoveragePercentage,
 			ChangedCoverage:        fileHealth.ChangedCoveragePercentage,
-			BaselineCoverage:       fileHealth.BaselineCoveragePercentage,
-			CoverageDelta:          fileHealth.CoverageDelta,
 			ChangedLines:           fileHealth.ChangedLines,
 			CoveredLines:           fileHealth.ChangedCoveredLines,
 			UncoveredLines:         fileHealth.ChangedUncoveredLines,
@@ -349,7 +374,13 @@ func (r *HealthReport) toFormatted() *FormatHealthReport {
 			APITestCoverage:        fileHealth.APITestCoverage,
 			FunctionalTestCoverage: fileHealth.FunctionalTestCoverage,
 			Status:                 status,
-		})
+		}
+		if fileHealth.HasBaseline {
+			baseline, delta := fileHealth.BaselineCoveragePercentage, fileHealth.CoverageDelta
+			fileSection.BaselineCoverage = &baseline
+			fileSection.CoverageDelta = &delta
+		}
+		formatted.Files = append(formatted.Files, fileSection)
 	}
 
 	// Convert insights
@@ -377,5 +408,14 @@ func (r *HealthReport) toFormatted() *FormatHealthReport {
 		})
 	}
 
+	// Convert skipped files
+	for _, skipped := range r.SkippedFiles {
+		formatted.Skipped = append(formatted.Skipped, SkippedSection{
+			FilePath: skipped.Path,
+			Reason:   string(skipped.Reason),
+			Detail:   skipped.Detail,
+		})
+	}
+
 	return formatted
 }
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"strings"
 	"testing"
+
+	"github.com/swantron/difftron/internal/hunk"
 )
 
 func TestHealthReport_ToJSON(t *testing.T) {
@@ -125,3 +127,38 @@ func TestHealthReport_WithFileHealth(t *testing.T) {
 		t.Errorf("expected file path 'test.go', got %s", formatted.Files[0].FilePath)
 	}
 }
+
+func TestHealthReport_WithSkippedFiles(t *testing.T) {
+	report := &HealthReport{
+		FileHealth:      make(map[string]*FileHealth),
+		Insights:        []Insight{},
+		Recommendations: []Recommendation{},
+		SkippedFiles: []*hunk.SkippedFile{
+			{Path: "logo.png", Reason: hunk.SkipBinary},
+			{Path: "run.sh", Reason: hunk.SkipModeChange, Detail: "100644 → 100755"},
+		},
+	}
+
+	markdown := report.ToMarkdown()
+	if !strings.Contains(markdown, "## Skipped Files") || !strings.Contains(markdown, "`run.sh`: mode change only (100644 → 100755)") {
+		t.Errorf("markdown should list skipped files, got:\n%s", markdown)
+	}
+
+	text := report.ToStructuredText()
+	if !strings.Contains(text, "SKIPPED FILES") || !strings.Contains(text, "logo.png: binary file") {
+		t.Errorf("text should list skipped files, got:\n%s", text)
+	}
+
+	jsonData, err := report.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+
+	var formatted FormatHealthReport
+	if err := json.Unmarshal(jsonData, &formatted); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(formatted.Skipped) != 2 || formatted.Skipped[1].Reason != "mode-change" {
+		t.Errorf("expected 2 skipped files in JSON, got %+v", formatted.Skipped)
+	}
+}
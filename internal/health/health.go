@@ -48,7 +48,8 @@ type FileHealth struct {
 	ChangedUncoveredLines     int
 	ChangedCoveragePercentage float64
 
-	// Baseline comparison
+	// Baseline comparison, only made if HasBaseline
+	HasBaseline                bool // Some changed lines replaced lines measured in the baseline
 	BaselineCoveragePercentage float64
 	CoverageDelta              float64 // Current - Baseline
 
@@ -101,6 +102,9 @@ type HealthReport struct {
 	// Insights and recommendations
 	Insights        []Insight
 	Recommendations []Recommendation
+
+	// SkippedFiles lists binary, mode-only and submodule changes that were not evaluated
+	SkippedFiles []*hunk.SkippedFile
 }
 
 // Insight provides actionable information about testing health
@@ -210,6 +214,7 @@ func AnalyzeHealth(
 		FileHealth:      make(map[string]*FileHealth),
 		Insights:        make([]Insight, 0),
 		Recommendations: make([]Recommendation, 0),
+		SkippedFiles:    diffResult.GetSkippedFiles(),
 	}
 
 	// Calculate overall project metrics
@@ -282,8 +287,9 @@ func (r *HealthReport) analyzeFileHealth(
 	threshold float64,
 ) *FileHealth {
 	health := &FileHealth{
-		FilePath:  filePath,
-		IsNewFile: diffResult.IsNewFile(filePath),
+		FilePath: filePath,
+		// Renamed and copied files existed in base under their old path
+		IsNewFile: diffResult.IsNewFile(filePath) && !diffResult.IsRenamedFile(filePath),
 	}
 
 	// Aggregate current coverage for this file
@@ -358,24 +364,35 @@ func (r *HealthReport) analyzeFileHealth(
 
 	// Calculate baseline comparison
 	if baselineReport != nil && !health.IsNewFile {
-		baselineFileCoverage := baselineReport.GetCoverageForFile(filePath)
+		baselinePath := diffResult.OldPath(filePath)
+		baselineFileCoverage := baselineReport.GetCoverageForFile(baselinePath)
 		if baselineFileCoverage == nil {
-			normalizedPath := coverage.NormalizePath(filePath)
+			normalizedPath := coverage.NormalizePath(baselinePath)
 			baselineFileCoverage = baselineReport.GetCoverageForFile(normalizedPath)
 		}
 
 		if baselineFileCoverage != nil {
+			// Compare against the base line each changed line replaced;
+			// purely added lines have no baseline counterpart
 			baselineCovered := 0
+			baselineTotal := 0
 			for lineNum := range changedLines {
-				if baselineReport.IsLineCovered(filePath, lineNum) || baselineReport.IsLineCovered(coverage.NormalizePath(filePath), lineNum) {
+				oldLineNum, ok := diffResult.OldLineNumber(filePath, lineNum)
+				if !ok {
+					continue
+				}
+				baselineTotal++
+				if baselineReport.IsLineCovered(baselinePath, oldLineNum) || baselineReport.IsLineCovered(coverage.NormalizePath(baselinePath), oldLineNum) {
 					baselineCovered++
 				}
 			}
-			if len(changedLines) > 0 {
-				health.BaselineCoveragePercentage = float64(baselineCovered) / float64(len(changedLines)) * 100
+			// A change that only adds lines has nothing to compare against
+			if baselineTotal > 0 {
+				health.HasBaseline = true
+				health.BaselineCoveragePercentage = float64(baselineCovered) / float64(baselineTotal) * 100
+				health.CoverageDelta = health.ChangedCoveragePercentage - health.BaselineCoveragePercentage
+				health.HasRegression = health.CoverageDelta < 0 && health.ChangedCoveragePercentage < threshold
 			}
-			health.CoverageDelta = health.ChangedCoveragePercentage - health.BaselineCoveragePercentage
-			health.HasRegression = health.CoverageDelta < 0 && health.ChangedCoveragePercentage < threshold
 		}
 	}
 
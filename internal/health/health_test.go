@@ -156,7 +156,7 @@ func TestAnalyzeHealth(t *testing.T) {
 index 123..456 100644
 --- a/file.go
 +++ b/file.go
-@@ -5,3 +5,5 @@ func main() {
+@@ -5,2 +5,4 @@ func main() {
  	fmt.Println("hello")
 +	fmt.Println("new line 1")
 +	fmt.Println("new line 2")
@@ -198,6 +198,55 @@ index 123..456 100644
 	})
 }
 
+func TestAnalyzeHealth_BaselineOnlyAddedLines(t *testing.T) {
+	diffResult, err := hunk.ParseGitDiff(`diff --git a/file.go b/file.go
+index 123..456 100644
+--- a/file.go
++++ b/file.go
+@@ -5,2 +5,4 @@ func main() {
+ 	fmt.Println("hello")
++	fmt.Println("new line 1")
++	fmt.Println("new line 2")
+ 	fmt.Println("world")
+`)
+	if err != nil {
+		t.Fatalf("failed to parse diff: %v", err)
+	}
+
+	testReports := []*TestCoverageReport{{
+		TestType: TestTypeUnit,
+		CoverageReport: &coverage.Report{FileCoverage: map[string]*coverage.CoverageData{
+			"file.go": {LineHits: map[int]int{5: 1, 6: 1, 7: 1, 8: 1}, TotalLines: 4, CoveredLines: 4},
+		}},
+	}}
+	baselineReports := []*TestCoverageReport{{
+		TestType: TestTypeUnit,
+		CoverageReport: &coverage.Report{FileCoverage: map[string]*coverage.CoverageData{
+			"file.go": {LineHits: map[int]int{5: 1, 6: 0}, TotalLines: 2, CoveredLines: 1},
+		}},
+	}}
+
+	report, err := AnalyzeHealth(diffResult, testReports, baselineReports, 80.0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// No changed line replaced a baseline line, so there is no delta to report
+	fileHealth := report.FileHealth["file.go"]
+	if fileHealth == nil {
+		t.Fatal("expected health for file.go")
+	}
+	if fileHealth.HasBaseline || fileHealth.CoverageDelta != 0 || fileHealth.HasRegression {
+		t.Errorf("expected no baseline comparison, got baseline %v, delta %.1f, regression %v",
+			fileHealth.HasBaseline, fileHealth.CoverageDelta, fileHealth.HasRegression)
+	}
+
+	formatted := report.toFormatted()
+	if len(formatted.Files) != 1 || formatted.Files[0].BaselineCoverage != nil || formatted.Files[0].CoverageDelta != nil {
+		t.Errorf("expected the formatted report to leave out baseline and delta, got %+v", formatted.Files)
+	}
+}
+
 func TestHealthReport_calculateOverallMetrics(t *testing.T) {
 	report := &HealthReport{
 		FileHealth: make(map[string]*FileHealth),
@@ -0,0 +1,181 @@
+package hunk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// cutCombinedHeader returns the path part of a "diff --cc" or "diff --combined" line
+func cutCombinedHeader(line string) (string, bool) {
+	if rest, ok := strings.CutPrefix(line, "diff --cc "); ok {
+		return rest, true
+	}
+	return strings.CutPrefix(line, "diff --combined ")
+}
+
+// parseCombinedHeader builds the header of a combined diff, which names the
+// merge result's path once and without a prefix
+func parseCombinedHeader(rest string) (gitHeader, error) {
+	path, err := unquotePath(rest)
+	if err != nil {
+		return gitHeader{}, err
+	}
+	return gitHeader{kind: sectionExact, OldPath: path, NewPath: path, known: true}, nil
+}
+
+// startCombinedHunk parses a combined diff hunk header, which has one range per
+// parent followed by the range in the merge result
+func (p *diffParser) startCombinedHunk(line string) error {
+	parts := strings.Fields(line)
+	marker := parts[0]
+	parents := len(marker) - 1
+	if strings.Trim(marker, "@") != "" || len(parts) < parents+3 || parts[parents+2] != marker {
+		return fmt.Errorf("corrupt diff at line %d: malformed combined hunk header %q", p.lineNo, line)
+	}
+
+	ranges := make([]Range, parents)
+	for i := 0; i < parents; i++ {
+		value, ok := strings.CutPrefix(parts[1+i], "-")
+		if !ok {
+			return fmt.Errorf("corrupt diff at line %d: malformed combined hunk header %q", p.lineNo, line)
+		}
+		start, count, err := parseRange(value)
+		if err != nil {
+			return fmt.Errorf("failed to parse parent %d range in hunk header at line %d: %w", i+1, p.lineNo, err)
+		}
+		ranges[i] = Range{Start: start, Count: count}
+	}
+
+	value, ok := strings.CutPrefix(parts[parents+1], "+")
+	if !ok {
+		return fmt.Errorf("corrupt diff at line %d: malformed combined hunk header %q", p.lineNo, line)
+	}
+	startLine, newCount, err := parseRange(value)
+	if err != nil {
+		return fmt.Errorf("failed to parse line number in hunk header at line %d: %w", p.lineNo, err)
+	}
+
+	// Old line numbers follow the first parent, like "git diff <first parent>"
+	p.currentLine = startLine - 1
+	p.currentOldLine = ranges[0].Start - 1
+	p.remainingOld = ranges[0].Count
+	p.remainingNew = newCount
+	p.parents = parents
+	p.remainingOther = make([]int, parents-1)
+	for i := 1; i < parents; i++ {
+		p.remainingOther[i-1] = ranges[i].Count
+	}
+	p.hunkHeader = line
+
+	p.currentHunk = nil
+	if p.currentFile != "" {
+		p.currentHunk = &Hunk{
+			File:     p.currentFile,
+			OldStart: ranges[0].Start,
+			OldCount: ranges[0].Count,
+			NewStart: startLine,
+			NewCount: newCount,
+			Section:  parseSection(line),
+			Parents:  ranges,
+		}
+		p.result.Hunks[p.currentFile] = append(p.result.Hunks[p.currentFile], p.currentHunk)
+	}
+	p.afterHunk = !p.inHunk()
+	return nil
+}
+
+// parseCombinedHunkLine processes a line of a combined hunk body. Each line starts
+// with one column per parent. For lines in the merge result, "+" means the line is
+// not in that parent and " " that it is. Lines with a "-" in any column were
+// removed: "-" marks the parents that had the line.
+func (p *diffParser) parseCombinedHunkLine(line string) error {
+	if strings.HasPrefix(line, "\\") {
+		// "\ No newline at end of file" describes the previous line and is not counted
+		return nil
+	}
+
+	var columns, content string
+	switch {
+	case len(line) >= p.parents:
+		columns, content = line[:p.parents], line[p.parents:]
+	case strings.TrimSpace(line) == "":
+		// Some tools strip the trailing spaces of blank context lines
+		columns = strings.Repeat(" ", p.parents)
+	default:
+		return p.hunkEnded()
+	}
+
+	inResult := true
+	addedToAll := true
+	for i := 0; i < len(columns); i++ {
+		switch columns[i] {
+		case '+':
+		case '-':
+			inResult = false
+			addedToAll = false
+		case ' ':
+			addedToAll = false
+		default:
+			return p.hunkEnded()
+		}
+	}
+
+	kind := LineContext
+	if addedToAll {
+		kind = LineAdded
+	} else if !inResult {
+		kind = LineRemoved
+	}
+
+	// A parent contains the line if its column is "-", or " " on a line that
+	// is also in the merge result. For removed lines " " means "not in this parent".
+	inParent := func(i int) bool {
+		return columns[i] == '-' || (inResult && columns[i] == ' ')
+	}
+
+	// Validate the line against every side's remaining count before consuming it
+	if inResult && p.remainingNew == 0 {
+		return p.hunkMismatch(kind.String())
+	}
+	if inParent(0) && p.remainingOld == 0 {
+		return p.hunkMismatch(kind.String())
+	}
+	for i := 1; i < p.parents; i++ {
+		if inParent(i) && p.remainingOther[i-1] == 0 {
+			return p.hunkMismatch(kind.String())
+		}
+	}
+
+	oldLine := 0
+	if inParent(0) {
+		p.remainingOld--
+		p.currentOldLine++
+		oldLine = p.currentOldLine
+	}
+	for i := 1; i < p.parents; i++ {
+		if inParent(i) {
+			p.remainingOther[i-1]--
+		}
+	}
+	newLine := 0
+	if inResult {
+		p.remainingNew--
+		p.currentLine++
+		newLine = p.currentLine
+	}
+
+	// Only lines the merge introduced relative to all parents count as changed
+	if p.currentFile != "" {
+		if kind == LineAdded {
+			p.result.ChangedLines[p.currentFile][newLine] = true
+			p.result.AddedLines[p.currentFile][newLine] = true
+		}
+		if kind == LineRemoved && oldLine != 0 {
+			p.result.RemovedLines[p.currentFile][oldLine] = true
+		}
+	}
+	p.currentHunk.addLine(Line{Kind: kind, OldLine: oldLine, NewLine: newLine, Content: content})
+
+	p.afterHunk = !p.inHunk()
+	return nil
+}
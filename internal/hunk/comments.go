@@ -0,0 +1,215 @@
+package hunk
+
+import (
+	"path"
+	"strings"
+)
+
+// commentSyntax describes how a language writes comments
+type commentSyntax struct {
+	line       []string // Prefixes that comment out the rest of the line
+	blockStart string   // Opening delimiter of a block comment, empty if none
+	blockEnd   string
+	quotes     string // Characters that delimit string and character literals
+}
+
+var (
+	cStyleComments    = &commentSyntax{line: []string{"//"}, blockStart: "/*", blockEnd: "*/", quotes: "\"'`"}
+	hashComments      = &commentSyntax{line: []string{"#"}, quotes: "\"'"}
+	dashComments      = &commentSyntax{line: []string{"--"}, quotes: "\""}
+	sqlComments       = &commentSyntax{line: []string{"--"}, blockStart: "/*", blockEnd: "*/", quotes: "\"'"}
+	cssComments       = &commentSyntax{blockStart: "/*", blockEnd: "*/", quotes: "\"'"}
+	markupComments    = &commentSyntax{blockStart: "<!--", blockEnd: "-->", quotes: "\""}
+	phpComments       = &commentSyntax{line: []string{"//", "#"}, blockStart: "/*", blockEnd: "*/", quotes: "\"'`"}
+	luaComments       = &commentSyntax{line: []string{"--"}, blockStart: "--[[", blockEnd: "]]", quotes: "\"'"}
+	haskellComments   = &commentSyntax{line: []string{"--"}, blockStart: "{-", blockEnd: "-}", quotes: "\""}
+	semicolonComments = &commentSyntax{line: []string{";"}, quotes: "\""}
+	percentComments   = &commentSyntax{line: []string{"%"}}
+)
+
+// commentSyntaxByExtension maps a file extension to its language's comment syntax
+var commentSyntaxByExtension = map[string]*commentSyntax{
+	".go":     cStyleComments,
+	".c":      cStyleComments,
+	".h":      cStyleComments,
+	".cc":     cStyleComments,
+	".cpp":    cStyleComments,
+	".cxx":    cStyleComments,
+	".hh":     cStyleComments,
+	".hpp":    cStyleComments,
+	".mm":     cStyleComments,
+	".java":   cStyleComments,
+	".kt":     cStyleComments,
+	".kts":    cStyleComments,
+	".scala":  cStyleComments,
+	".groovy": cStyleComments,
+	".gradle": cStyleComments,
+	".cs":     cStyleComments,
+	".js":     cStyleComments,
+	".jsx":    cStyleComments,
+	".mjs":    cStyleComments,
+	".cjs":    cStyleComments,
+	".ts":     cStyleComments,
+	".tsx":    cStyleComments,
+	".rs":     cStyleComments,
+	".swift":  cStyleComments,
+	".dart":   cStyleComments,
+	".proto":  cStyleComments,
+	".scss":   cStyleComments,
+	".less":   cStyleComments,
+	".css":    cssComments,
+	".php":    phpComments,
+	".py":     hashComments,
+	".pyi":    hashComments,
+	".rb":     hashComments,
+	".pl":     hashComments,
+	".pm":     hashComments,
+	".r":      hashComments,
+	".sh":     hashComments,
+	".bash":   hashComments,
+	".zsh":    hashComments,
+	".ps1":    hashComments,
+	".yaml":   hashComments,
+	".yml":    hashComments,
+	".toml":   hashComments,
+	".tf":     phpComments,
+	".ex":     hashComments,
+	".exs":    hashComments,
+	".jl":     hashComments,
+	".nim":    hashComments,
+	".cmake":  hashComments,
+	".sql":    sqlComments,
+	".ada":    dashComments,
+	".lua":    luaComments,
+	".hs":     haskellComments,
+	".elm":    haskellComments,
+	".clj":    semicolonComments,
+	".lisp":   semicolonComments,
+	".el":     semicolonComments,
+	".erl":    percentComments,
+	".tex":    percentComments,
+	".html":   markupComments,
+	".htm":    markupComments,
+	".xml":    markupComments,
+	".vue":    markupComments,
+	".svg":    markupComments,
+}
+
+// commentSyntaxByName maps file names without a telling extension to their comment syntax
+var commentSyntaxByName = map[string]*commentSyntax{
+	"Makefile":       hashComments,
+	"GNUmakefile":    hashComments,
+	"Dockerfile":     hashComments,
+	"Containerfile":  hashComments,
+	"CMakeLists.txt": hashComments,
+	"Rakefile":       hashComments,
+	"Gemfile":        hashComments,
+	"BUILD":          hashComments,
+	"BUILD.bazel":    hashComments,
+	"WORKSPACE":      hashComments,
+}
+
+// commentSyntaxFor returns the comment syntax of a file, or nil for unknown languages
+func commentSyntaxFor(file string) *commentSyntax {
+	base := path.Base(file)
+	if syntax, ok := commentSyntaxByName[base]; ok {
+		return syntax
+	}
+	return commentSyntaxByExtension[strings.ToLower(path.Ext(base))]
+}
+
+// classify reports whether a line holds only comments, given whether it starts
+// inside a block comment, and returns whether the next line starts inside one.
+// Comment delimiters inside string and character literals are ignored. Literals
+// spanning lines, such as Python's triple-quoted strings, are not tracked.
+func (s *commentSyntax) classify(line string, inBlock bool) (commentOnly bool, nextInBlock bool) {
+	rest := strings.TrimSpace(line)
+	hasComment, hasCode := false, false
+
+	for rest != "" {
+		if inBlock {
+			hasComment = true
+			idx := strings.Index(rest, s.blockEnd)
+			if idx == -1 {
+				break
+			}
+			rest = strings.TrimSpace(rest[idx+len(s.blockEnd):])
+			inBlock = false
+			continue
+		}
+
+		// A block comment opener can share a prefix with a line comment, as in Lua's "--[["
+		if s.blockStart != "" && strings.HasPrefix(rest, s.blockStart) {
+			hasComment = true
+			rest = rest[len(s.blockStart):]
+			inBlock = true
+			continue
+		}
+		if s.startsLineComment(rest) {
+			hasComment = true
+			break
+		}
+
+		// Code up to the next comment on the line
+		hasCode = true
+		next := s.nextComment(rest)
+		if next == -1 {
+			break
+		}
+		rest = rest[next:]
+	}
+
+	return hasComment && !hasCode, inBlock
+}
+
+// startsLineComment returns true if text begins with a line comment prefix
+func (s *commentSyntax) startsLineComment(text string) bool {
+	for _, prefix := range s.line {
+		if strings.HasPrefix(text, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextComment returns the index of the first comment delimiter after the start
+// of text that is outside string and character literals, or -1
+func (s *commentSyntax) nextComment(text string) int {
+	delimiters := append([]string{s.blockStart}, s.line...)
+	for i := 0; i < len(text); i++ {
+		if strings.IndexByte(s.quotes, text[i]) != -1 {
+			end := literalEnd(text, i)
+			if end == -1 {
+				// The rest of the line is inside the literal
+				return -1
+			}
+			i = end
+			continue
+		}
+		if i == 0 {
+			continue
+		}
+		for _, delimiter := range delimiters {
+			if delimiter != "" && strings.HasPrefix(text[i:], delimiter) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// literalEnd returns the index of the quote closing the literal opened at
+// text[start], or -1 if it does not close on this line. Backslash escapes are
+// honored except in back-quoted literals, which are raw strings in Go.
+func literalEnd(text string, start int) int {
+	quote := text[start]
+	for i := start + 1; i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && quote != '`':
+			i++
+		case text[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,137 @@
+package hunk
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Format is the dialect of a diff
+type Format string
+
+const (
+	// FormatAuto recognizes every dialect below, section by section
+	FormatAuto Format = "auto"
+	// FormatGit is "git diff" output, also produced by "hg diff --git"
+	FormatGit Format = "git"
+	// FormatUnified is a plain unified diff such as "diff -u" or "diff -ruN"
+	FormatUnified Format = "unified"
+	// FormatHg is Mercurial's "hg diff" output with "diff -r" headers
+	FormatHg Format = "hg"
+	// FormatSVN is "svn diff" output with "Index:" headers
+	FormatSVN Format = "svn"
+)
+
+// Formats lists the supported diff formats
+var Formats = []Format{FormatAuto, FormatGit, FormatUnified, FormatHg, FormatSVN}
+
+// ParseFormat returns the Format with the given name
+func ParseFormat(name string) (Format, error) {
+	for _, format := range Formats {
+		if string(format) == name {
+			return format, nil
+		}
+	}
+	names := make([]string, len(Formats))
+	for i, format := range Formats {
+		names[i] = string(format)
+	}
+	return "", fmt.Errorf("unknown diff format %q (expected one of: %s)", name, strings.Join(names, ", "))
+}
+
+// ParseDiff parses a diff in the given format. Every format produces the same
+// ParseResult as ParseGitDiff.
+func ParseDiff(diffOutput string, format Format) (*ParseResult, error) {
+	return ParseDiffReader(strings.NewReader(diffOutput), format)
+}
+
+// ParseDiffReader parses a diff in the given format read from r, streaming it file by file
+func ParseDiffReader(r io.Reader, format Format) (*ParseResult, error) {
+	reader, err := NewFormatReader(r, format)
+	if err != nil {
+		return nil, err
+	}
+	return collect(reader)
+}
+
+// acceptsGit returns true if "diff --git" and "diff --cc" sections are recognized
+func (f Format) acceptsGit() bool {
+	// hg diff --git produces git sections
+	return f == FormatGit || f == FormatHg || f == FormatAuto
+}
+
+// acceptsHg returns true if Mercurial "diff -r" sections are recognized
+func (f Format) acceptsHg() bool {
+	return f == FormatHg || f == FormatAuto
+}
+
+// acceptsSVN returns true if Subversion "Index:" sections are recognized
+func (f Format) acceptsSVN() bool {
+	return f == FormatSVN || f == FormatAuto
+}
+
+// acceptsUnified returns true if plain unified diff sections are recognized
+func (f Format) acceptsUnified() bool {
+	return f == FormatUnified || f == FormatAuto
+}
+
+// parseHgHeader parses a Mercurial section header, which names the file after
+// one or two revisions
+// Format: diff -r 1a2b3c4d5e6f -r 6f5e4d3c2b1a path/to/file.go
+func parseHgHeader(line string) (gitHeader, bool) {
+	rest, ok := strings.CutPrefix(line, "diff ")
+	if !ok {
+		return gitHeader{}, false
+	}
+	revisions := 0
+	for {
+		value, ok := strings.CutPrefix(rest, "-r ")
+		if !ok {
+			break
+		}
+		revision, remainder, found := strings.Cut(value, " ")
+		if !found || !isHexRevision(revision) {
+			return gitHeader{}, false
+		}
+		rest = remainder
+		revisions++
+	}
+	if revisions == 0 || rest == "" {
+		return gitHeader{}, false
+	}
+	return gitHeader{kind: sectionExact, OldPath: rest, NewPath: rest, known: true}, true
+}
+
+// isHexRevision returns true for the abbreviated or full node IDs hg diff prints
+func isHexRevision(revision string) bool {
+	if len(revision) < 12 {
+		return false
+	}
+	for _, c := range revision {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseSVNHeader parses a Subversion section header
+// Format: Index: path/to/file.go
+func parseSVNHeader(line string) (gitHeader, bool) {
+	path, ok := strings.CutPrefix(line, "Index: ")
+	if !ok || path == "" {
+		return gitHeader{}, false
+	}
+	return gitHeader{kind: sectionExact, OldPath: path, NewPath: path, known: true}, true
+}
+
+// isUnifiedHeader returns true for the command line that GNU diff prints before
+// each file of a recursive diff. The section headers of git, including the
+// "diff --cc" and "diff --combined" headers of combined diffs, are not.
+// Format: diff -ruN old/path/to/file.go new/path/to/file.go
+func isUnifiedHeader(line string) bool {
+	if _, ok := cutCombinedHeader(line); ok {
+		return false
+	}
+	return strings.HasPrefix(line, "diff ") && !strings.HasPrefix(line, "diff --git ")
+}
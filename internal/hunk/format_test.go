@@ -0,0 +1,369 @@
+package hunk
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Each dialect below describes the same change: app/main.go gains a line,
+// app/new.go is added and app/old.go is deleted
+var dialectDiffs = []struct {
+	format     Format
+	diffOutput string
+}{
+	{
+		format: FormatGit,
+		diffOutput: `diff --git a/app/main.go b/app/main.go
+index 1234567..abcdefg 100644
+--- a/app/main.go
++++ b/app/main.go
+@@ -1,2 +1,3 @@ package app
+ package app
++var x = 1
+ func main() {}
+diff --git a/app/new.go b/app/new.go
+new file mode 100644
+index 0000000..1111111
+--- /dev/null
++++ b/app/new.go
+@@ -0,0 +1,2 @@
++package app
++var y = 2
+diff --git a/app/old.go b/app/old.go
+deleted file mode 100644
+index 2222222..0000000
+--- a/app/old.go
++++ /dev/null
+@@ -1,1 +0,0 @@
+-package app
+`,
+	},
+	{
+		format: FormatUnified,
+		diffOutput: "diff -ruN orig/app/main.go new/app/main.go\n" +
+			"--- orig/app/main.go\t2026-10-01 12:00:00.000000000 +0000\n" +
+			"+++ new/app/main.go\t2026-10-02 12:00:00.000000000 +0000\n" +
+			"@@ -1,2 +1,3 @@ package app\n" +
+			" package app\n" +
+			"+var x = 1\n" +
+			" func main() {}\n" +
+			"diff -ruN orig/app/new.go new/app/new.go\n" +
+			"--- orig/app/new.go\t1970-01-01 00:00:00.000000000 +0000\n" +
+			"+++ new/app/new.go\t2026-10-02 12:00:00.000000000 +0000\n" +
+			"@@ -0,0 +1,2 @@\n" +
+			"+package app\n" +
+			"+var y = 2\n" +
+			"diff -ruN orig/app/old.go new/app/old.go\n" +
+			"--- orig/app/old.go\t2026-10-01 12:00:00.000000000 +0000\n" +
+			"+++ new/app/old.go\t1970-01-01 00:00:00.000000000 +0000\n" +
+			"@@ -1,1 +0,0 @@\n" +
+			"-package app\n",
+	},
+	{
+		format: FormatHg,
+		diffOutput: `diff -r 1a2b3c4d5e6f -r 6f5e4d3c2b1a app/main.go
+--- a/app/main.go	Thu Oct 01 12:00:00 2026 +0000
++++ b/app/main.go	Fri Oct 02 12:00:00 2026 +0000
+@@ -1,2 +1,3 @@ package app
+ package app
++var x = 1
+ func main() {}
+diff -r 1a2b3c4d5e6f -r 6f5e4d3c2b1a app/new.go
+--- /dev/null	Thu Jan 01 00:00:00 1970 +0000
++++ b/app/new.go	Fri Oct 02 12:00:00 2026 +0000
+@@ -0,0 +1,2 @@
++package app
++var y = 2
+diff -r 1a2b3c4d5e6f -r 6f5e4d3c2b1a app/old.go
+--- a/app/old.go	Thu Oct 01 12:00:00 2026 +0000
++++ /dev/null	Thu Jan 01 00:00:00 1970 +0000
+@@ -1,1 +0,0 @@
+-package app
+`,
+	},
+	{
+		format: FormatHg,
+		diffOutput: `diff --git a/app/main.go b/app/main.go
+--- a/app/main.go
++++ b/app/main.go
+@@ -1,2 +1,3 @@ package app
+ package app
++var x = 1
+ func main() {}
+diff --git a/app/new.go b/app/new.go
+new file mode 100644
+--- /dev/null
++++ b/app/new.go
+@@ -0,0 +1,2 @@
++package app
++var y = 2
+diff --git a/app/old.go b/app/old.go
+deleted file mode 100644
+--- a/app/old.go
++++ /dev/null
+@@ -1,1 +0,0 @@
+-package app
+`,
+	},
+	{
+		format: FormatSVN,
+		diffOutput: "Index: app/main.go\n" +
+			"===================================================================\n" +
+			"--- app/main.go\t(revision 12)\n" +
+			"+++ app/main.go\t(working copy)\n" +
+			"@@ -1,2 +1,3 @@ package app\n" +
+			" package app\n" +
+			"+var x = 1\n" +
+			" func main() {}\n" +
+			"Index: app/new.go\n" +
+			"===================================================================\n" +
+			"--- app/new.go\t(nonexistent)\n" +
+			"+++ app/new.go\t(working copy)\n" +
+			"@@ -0,0 +1,2 @@\n" +
+			"+package app\n" +
+			"+var y = 2\n" +
+			"\n" +
+			"Property changes on: app/new.go\n" +
+			"___________________________________________________________________\n" +
+			"Added: svn:eol-style\n" +
+			"## -0,0 +1 ##\n" +
+			"+native\n" +
+			"\\ No newline at end of property\n" +
+			"Index: app/old.go\n" +
+			"===================================================================\n" +
+			"--- app/old.go\t(revision 12)\n" +
+			"+++ app/old.go\t(working copy)\n" +
+			"@@ -1,1 +0,0 @@\n" +
+			"-package app\n",
+	},
+}
+
+func TestParseDiff_Formats(t *testing.T) {
+	for _, tt := range dialectDiffs {
+		for _, format := range []Format{tt.format, FormatAuto} {
+			firstLine, _, _ := strings.Cut(tt.diffOutput, "\n")
+			t.Run(string(tt.format)+"/"+string(format)+"/"+firstLine, func(t *testing.T) {
+				result, err := ParseDiff(tt.diffOutput, format)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				files := result.GetChangedFiles()
+				sort.Strings(files)
+				if !reflect.DeepEqual(files, []string{"app/main.go", "app/new.go"}) {
+					t.Fatalf("expected app/main.go and app/new.go to change, got %v", files)
+				}
+				if !result.IsModifiedFile("app/main.go") || result.IsNewFile("app/main.go") {
+					t.Error("expected app/main.go to be modified")
+				}
+				if !result.IsNewFile("app/new.go") || result.IsModifiedFile("app/new.go") {
+					t.Error("expected app/new.go to be new")
+				}
+				if got := result.GetAddedLinesForFile("app/main.go"); !reflect.DeepEqual(got, map[int]bool{2: true}) {
+					t.Errorf("expected line 2 added to app/main.go, got %v", got)
+				}
+				if got := result.GetAddedLinesForFile("app/new.go"); !reflect.DeepEqual(got, map[int]bool{1: true, 2: true}) {
+					t.Errorf("expected lines 1-2 added to app/new.go, got %v", got)
+				}
+				hunks := result.GetHunksForFile("app/main.go")
+				if len(hunks) != 1 || hunks[0].Section != "package app" {
+					t.Errorf("expected one hunk in package app, got %+v", hunks)
+				}
+				if len(result.SkippedFiles) != 0 {
+					t.Errorf("expected no skipped files, got %v", result.GetSkippedFiles())
+				}
+			})
+		}
+	}
+}
+
+func TestParseDiff_PlainUnified(t *testing.T) {
+	tests := []struct {
+		name       string
+		diffOutput string
+		wantFile   string
+		wantNew    bool
+	}{
+		{
+			name: "diff -u of two files",
+			diffOutput: "--- main.go.orig\t2026-10-01 12:00:00.000000000 +0000\n" +
+				"+++ main.go\t2026-10-02 12:00:00.000000000 +0000\n" +
+				"@@ -1,1 +1,2 @@\n" +
+				" package main\n" +
+				"+var x = 1\n",
+			wantFile: "main.go",
+		},
+		{
+			name: "same name on both sides",
+			diffOutput: "--- internal/app.go\n" +
+				"+++ internal/app.go\n" +
+				"@@ -1,1 +1,2 @@\n" +
+				" package app\n" +
+				"+var x = 1\n",
+			wantFile: "internal/app.go",
+		},
+		{
+			name: "new file against /dev/null",
+			diffOutput: "--- /dev/null\n" +
+				"+++ b/app.go\n" +
+				"@@ -0,0 +1,1 @@\n" +
+				"+package app\n",
+			wantFile: "app.go",
+			wantNew:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseDiff(tt.diffOutput, FormatUnified)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			files := result.GetChangedFiles()
+			if len(files) != 1 || files[0] != tt.wantFile {
+				t.Fatalf("expected file %q, got %v", tt.wantFile, files)
+			}
+			if result.IsNewFile(tt.wantFile) != tt.wantNew {
+				t.Errorf("expected IsNewFile=%v", tt.wantNew)
+			}
+		})
+	}
+}
+
+func TestParseDiff_CombinedNotUnified(t *testing.T) {
+	for _, header := range []string{"diff --cc app/main.go", "diff --combined app/main.go"} {
+		if isUnifiedHeader(header) {
+			t.Errorf("expected %q not to be a unified header", header)
+		}
+
+		diffOutput := header + "\n" +
+			"index 1111111,2222222..3333333\n" +
+			"--- a/app/main.go\n" +
+			"+++ b/app/main.go\n" +
+			"@@@ -1,1 -1,1 +1,2 @@@\n" +
+			"  package app\n" +
+			"++var x = 1\n"
+
+		_, err := ParseDiff(diffOutput, FormatUnified)
+		if err == nil || !strings.Contains(err.Error(), "combined diff") {
+			t.Errorf("%s: expected a combined diff error as unified, got %v", header, err)
+		}
+		if _, err := ParseDiff(diffOutput, FormatGit); err != nil {
+			t.Errorf("%s: unexpected error as git: %v", header, err)
+		}
+	}
+}
+
+func TestParseDiff_BinaryFiles(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     Format
+		diffOutput string
+	}{
+		{
+			name:   "gnu diff",
+			format: FormatUnified,
+			diffOutput: `diff -ruN orig/assets/logo.png new/assets/logo.png
+Binary files orig/assets/logo.png and new/assets/logo.png differ
+`,
+		},
+		{
+			name:       "gnu diff without command line",
+			format:     FormatUnified,
+			diffOutput: "Binary files orig/assets/logo.png and new/assets/logo.png differ\n",
+		},
+		{
+			name:   "svn",
+			format: FormatSVN,
+			diffOutput: `Index: assets/logo.png
+===================================================================
+Cannot display: file marked as a binary type.
+svn:mime-type = application/octet-stream
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseDiff(tt.diffOutput, tt.format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			skipped := result.GetSkippedFiles()
+			if len(skipped) != 1 || skipped[0].Path != "assets/logo.png" || skipped[0].Reason != SkipBinary {
+				t.Errorf("expected assets/logo.png skipped as binary, got %v", skipped)
+			}
+		})
+	}
+}
+
+func TestParseDiff_FormatIsRespected(t *testing.T) {
+	// Subversion sections are not recognized in git diffs, so the names are
+	// read as plain git "---"/"+++" lines, which require the a/ and b/ prefixes
+	diffOutput := "Index: app/main.go\n" +
+		"===================================================================\n" +
+		"--- app/main.go\t(revision 12)\n" +
+		"+++ app/main.go\t(working copy)\n" +
+		"@@ -1,1 +1,2 @@\n" +
+		" package app\n" +
+		"+var x = 1\n"
+
+	result, err := ParseDiff(diffOutput, FormatGit)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.HasChanges() {
+		t.Errorf("expected no changes from an svn diff parsed as git, got %v", result.GetChangedFiles())
+	}
+
+	result, err = ParseDiff(diffOutput, FormatSVN)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if files := result.GetChangedFiles(); len(files) != 1 || files[0] != "app/main.go" {
+		t.Errorf("expected app/main.go to change, got %v", files)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, format := range Formats {
+		got, err := ParseFormat(string(format))
+		if err != nil || got != format {
+			t.Errorf("ParseFormat(%q) = %q, %v", format, got, err)
+		}
+	}
+	if _, err := ParseFormat("cvs"); err == nil || !strings.Contains(err.Error(), "unknown diff format") {
+		t.Errorf("expected an unknown format error, got %v", err)
+	}
+	if _, err := NewFormatReader(strings.NewReader(""), Format("cvs")); err == nil {
+		t.Error("expected NewFormatReader to reject an unknown format")
+	}
+}
+
+func TestParseHgHeader(t *testing.T) {
+	tests := []struct {
+		line   string
+		want   string
+		wantOK bool
+	}{
+		{line: "diff -r 1a2b3c4d5e6f app/main.go", want: "app/main.go", wantOK: true},
+		{line: "diff -r 1a2b3c4d5e6f -r 6f5e4d3c2b1a my dir/app.go", want: "my dir/app.go", wantOK: true},
+		{line: "diff -ruN orig/app.go new/app.go"},
+		{line: "diff -r -u orig new"},
+		{line: "diff --git a/app.go b/app.go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			header, ok := parseHgHeader(tt.line)
+			if ok != tt.wantOK {
+				t.Fatalf("expected ok=%v, got %v", tt.wantOK, ok)
+			}
+			if ok && (header.OldPath != tt.want || header.NewPath != tt.want) {
+				t.Errorf("expected path %q, got %+v", tt.want, header)
+			}
+		})
+	}
+}
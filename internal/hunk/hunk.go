@@ -0,0 +1,103 @@
+package hunk
+
+// LineKind identifies the role of a line inside a hunk
+type LineKind int
+
+const (
+	// LineContext is an unchanged line present in both versions
+	LineContext LineKind = iota
+	// LineAdded is a line that only exists in the new version
+	LineAdded
+	// LineRemoved is a line that only exists in the old version
+	LineRemoved
+)
+
+// String returns the diff marker name for the line kind
+func (k LineKind) String() string {
+	switch k {
+	case LineAdded:
+		return "added"
+	case LineRemoved:
+		return "removed"
+	default:
+		return "context"
+	}
+}
+
+// Line represents a single line inside a hunk.
+// In a combined diff hunk a line is added only if it is new relative to every
+// parent, removed if it is missing from the merge result, and context otherwise.
+type Line struct {
+	Kind LineKind
+	// OldLine is the line number in the old version (0 for added lines).
+	// For combined diffs it refers to the first parent and is 0 if the line is not there.
+	OldLine int
+	// NewLine is the line number in the new version (0 for removed lines)
+	NewLine int
+	// Content is the line text without the leading diff marker
+	Content string
+}
+
+// Hunk represents a contiguous block of changes in a file, as introduced by
+// an "@@ -oldStart,oldCount +newStart,newCount @@ section" header
+type Hunk struct {
+	File     string
+	OldStart int
+	OldCount int
+	NewStart int
+	NewCount int
+	// Section is the heading git prints after the closing @@,
+	// usually the signature of the enclosing function
+	Section string
+	// Lines holds the added, removed and context lines in diff order
+	Lines []Line
+	// Parents holds the range in each parent of a combined diff hunk, with
+	// OldStart and OldCount repeating the first parent; nil for ordinary hunks
+	Parents []Range
+}
+
+// Range is the start line and line count of one side of a hunk
+type Range struct {
+	Start int
+	Count int
+}
+
+// addLine appends a line to the hunk; it is a no-op on a nil hunk so that
+// lines outside any hunk header can be passed through safely
+func (h *Hunk) addLine(line Line) {
+	if h == nil {
+		return
+	}
+	h.Lines = append(h.Lines, line)
+}
+
+// AddedLines returns the new-version line numbers of lines added in this hunk
+func (h *Hunk) AddedLines() []int {
+	return h.lineNumbers(LineAdded)
+}
+
+// RemovedLines returns the old-version line numbers of lines removed in this hunk
+func (h *Hunk) RemovedLines() []int {
+	return h.lineNumbers(LineRemoved)
+}
+
+// ContextLines returns the new-version line numbers of unchanged lines in this hunk
+func (h *Hunk) ContextLines() []int {
+	return h.lineNumbers(LineContext)
+}
+
+// lineNumbers collects line numbers of the given kind, using old numbers for removed lines
+func (h *Hunk) lineNumbers(kind LineKind) []int {
+	numbers := make([]int, 0)
+	for _, line := range h.Lines {
+		if line.Kind != kind {
+			continue
+		}
+		if kind == LineRemoved {
+			numbers = append(numbers, line.OldLine)
+		} else {
+			numbers = append(numbers, line.NewLine)
+		}
+	}
+	return numbers
+}
@@ -0,0 +1,119 @@
+package hunk
+
+// LineMap maps line numbers between the old (base) and new version of a file.
+// Lines outside any hunk are shifted by the net size change of the hunks before them.
+// Inside a hunk, context lines map one-to-one, and modified lines map to the line
+// they replaced (the i-th added line of a change block pairs with its i-th removed line).
+// Purely added or purely removed lines have no counterpart.
+type LineMap struct {
+	hunks []*lineMapHunk
+}
+
+// lineMapHunk holds the explicit mapping for the lines inside a single hunk
+type lineMapHunk struct {
+	*Hunk
+	newToOld map[int]int
+	oldToNew map[int]int
+}
+
+// NewLineMap builds the line mapping for a file from its hunks, which must be
+// in file order as git prints them
+func NewLineMap(hunks []*Hunk) *LineMap {
+	lineMap := &LineMap{
+		hunks: make([]*lineMapHunk, 0, len(hunks)),
+	}
+
+	for _, h := range hunks {
+		mapped := &lineMapHunk{
+			Hunk:     h,
+			newToOld: make(map[int]int),
+			oldToNew: make(map[int]int),
+		}
+
+		// Walk change blocks (runs of removed/added lines between context lines).
+		// Lines are classified by which versions they exist in, so lines of a
+		// combined diff that only one parent lacks are handled like the rest.
+		var blockRemoved []int
+		blockAdded := 0
+		for _, line := range h.Lines {
+			switch {
+			case line.OldLine != 0 && line.NewLine != 0:
+				mapped.pair(line.OldLine, line.NewLine)
+				blockRemoved = blockRemoved[:0]
+				blockAdded = 0
+			case line.OldLine != 0:
+				blockRemoved = append(blockRemoved, line.OldLine)
+			case line.NewLine != 0:
+				if blockAdded < len(blockRemoved) {
+					mapped.pair(blockRemoved[blockAdded], line.NewLine)
+				}
+				blockAdded++
+			}
+		}
+
+		lineMap.hunks = append(lineMap.hunks, mapped)
+	}
+
+	return lineMap
+}
+
+// pair records that oldLine and newLine refer to the same line
+func (h *lineMapHunk) pair(oldLine, newLine int) {
+	h.newToOld[newLine] = oldLine
+	h.oldToNew[oldLine] = newLine
+}
+
+// rangeBounds returns the half-open line range [begin, end) a hunk covers on one side.
+// A zero count means the hunk is empty on that side and sits right after start.
+func rangeBounds(start, count int) (int, int) {
+	if count == 0 {
+		return start + 1, start + 1
+	}
+	return start, start + count
+}
+
+// OldLine returns the line number in the old version that corresponds to newLine.
+// The second return value is false if the line was added and has no counterpart.
+func (m *LineMap) OldLine(newLine int) (int, bool) {
+	if m == nil {
+		return 0, false
+	}
+
+	offset := 0
+	for _, h := range m.hunks {
+		newBegin, newEnd := rangeBounds(h.NewStart, h.NewCount)
+		if newLine < newBegin {
+			break
+		}
+		if newLine < newEnd {
+			oldLine, ok := h.newToOld[newLine]
+			return oldLine, ok
+		}
+		_, oldEnd := rangeBounds(h.OldStart, h.OldCount)
+		offset = oldEnd - newEnd
+	}
+	return newLine + offset, true
+}
+
+// NewLine returns the line number in the new version that corresponds to oldLine.
+// The second return value is false if the line was removed and has no counterpart.
+func (m *LineMap) NewLine(oldLine int) (int, bool) {
+	if m == nil {
+		return 0, false
+	}
+
+	offset := 0
+	for _, h := range m.hunks {
+		oldBegin, oldEnd := rangeBounds(h.OldStart, h.OldCount)
+		if oldLine < oldBegin {
+			break
+		}
+		if oldLine < oldEnd {
+			newLine, ok := h.oldToNew[oldLine]
+			return newLine, ok
+		}
+		_, newEnd := rangeBounds(h.NewStart, h.NewCount)
+		offset = newEnd - oldEnd
+	}
+	return oldLine + offset, true
+}
@@ -0,0 +1,270 @@
+package hunk
+
+import "strings"
+
+// contextLines is the number of unchanged lines shown around each change, as git does by default
+const contextLines = 3
+
+// maxSectionLength is the longest section heading git prints after "@@"
+const maxSectionLength = 80
+
+// lineMatch pairs a line of the old version with an identical line of the new version
+type lineMatch struct {
+	old int
+	new int
+}
+
+// myers computes a shortest edit script between two sequences of line IDs with
+// the linear-space variant of Myers' O(ND) algorithm, which recursively splits
+// the problem at the middle snake of an optimal path
+type myers struct {
+	a, b    []int
+	matches []lineMatch
+}
+
+// diffLineIDs returns the matching lines of a longest common subsequence of a and b, in order
+func diffLineIDs(a, b []int) []lineMatch {
+	m := &myers{a: a, b: b}
+	m.compare(0, len(a), 0, len(b))
+	return m.matches
+}
+
+// compare records the matches between a[aLo:aHi] and b[bLo:bHi]
+func (m *myers) compare(aLo, aHi, bLo, bHi int) {
+	// Common prefix and suffix need no search
+	for aLo < aHi && bLo < bHi && m.a[aLo] == m.b[bLo] {
+		m.matches = append(m.matches, lineMatch{aLo, bLo})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && m.a[aHi-suffix-1] == m.b[bHi-suffix-1] {
+		suffix++
+	}
+	aHi -= suffix
+	bHi -= suffix
+
+	if aLo < aHi && bLo < bHi {
+		x, y, u, v := m.middleSnake(aLo, aHi, bLo, bHi)
+		m.compare(aLo, x, bLo, y)
+		for i := x; i < u; i++ {
+			m.matches = append(m.matches, lineMatch{i, y + i - x})
+		}
+		m.compare(u, aHi, v, bHi)
+	}
+
+	for i := 0; i < suffix; i++ {
+		m.matches = append(m.matches, lineMatch{aHi + i, bHi + i})
+	}
+}
+
+// middleSnake finds the middle snake of an optimal path from (aLo, bLo) to (aHi, bHi)
+// by searching forward from the start and backward from the end until the two
+// searches overlap. It returns the start (x, y) and end (u, v) of the snake.
+func (m *myers) middleSnake(aLo, aHi, bLo, bHi int) (int, int, int, int) {
+	aLen, bLen := aHi-aLo, bHi-bLo
+	delta := aLen - bLen
+	odd := delta%2 != 0
+	maxD := (aLen + bLen + 1) / 2
+	offset := maxD + 1
+
+	// forward[k] and backward[k] hold the furthest x reached on diagonal k;
+	// the backward search measures x and y from the end
+	forward := make([]int, 2*maxD+3)
+	backward := make([]int, 2*maxD+3)
+
+	for d := 0; d <= maxD; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < aLen && y < bLen && m.a[aLo+x] == m.b[bLo+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			if odd && k >= delta-(d-1) && k <= delta+(d-1) && x+backward[offset+delta-k] >= aLen {
+				return aLo + x0, bLo + y0, aLo + x, bLo + y
+			}
+		}
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < aLen && y < bLen && m.a[aHi-1-x] == m.b[bHi-1-y] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+			if !odd && delta-k >= -d && delta-k <= d && x+forward[offset+delta-k] >= aLen {
+				return aHi - x, bHi - y, aHi - x0, bHi - y0
+			}
+		}
+	}
+
+	// Unreachable: the searches always meet within maxD steps
+	return aLo, bLo, aLo, bLo
+}
+
+// diffHunks compares two versions of a file and returns git-style hunks with
+// three lines of context. Lines keep their line ending for the comparison, so a
+// missing newline at the end of the file counts as a change, like in git.
+func diffHunks(path string, oldContent, newContent string) []*Hunk {
+	oldLines := splitLines(oldContent)
+	newLines := splitLines(newContent)
+
+	// Compare small integer IDs instead of strings
+	ids := make(map[string]int)
+	lineIDs := func(lines []string) []int {
+		result := make([]int, len(lines))
+		for i, line := range lines {
+			id, ok := ids[line]
+			if !ok {
+				id = len(ids)
+				ids[line] = id
+			}
+			result[i] = id
+		}
+		return result
+	}
+	matches := diffLineIDs(lineIDs(oldLines), lineIDs(newLines))
+
+	// Expand the matches into the full line sequence, removals before additions
+	lines := make([]Line, 0, len(oldLines)+len(newLines))
+	oldIdx, newIdx := 0, 0
+	emitChanges := func(oldEnd, newEnd int) {
+		for ; oldIdx < oldEnd; oldIdx++ {
+			lines = append(lines, Line{Kind: LineRemoved, OldLine: oldIdx + 1, Content: lineContent(oldLines[oldIdx])})
+		}
+		for ; newIdx < newEnd; newIdx++ {
+			lines = append(lines, Line{Kind: LineAdded, NewLine: newIdx + 1, Content: lineContent(newLines[newIdx])})
+		}
+	}
+	for _, match := range matches {
+		emitChanges(match.old, match.new)
+		lines = append(lines, Line{Kind: LineContext, OldLine: oldIdx + 1, NewLine: newIdx + 1, Content: lineContent(oldLines[oldIdx])})
+		oldIdx++
+		newIdx++
+	}
+	emitChanges(len(oldLines), len(newLines))
+
+	return groupHunks(path, lines, oldLines)
+}
+
+// groupHunks groups changed lines with their surrounding context into hunks,
+// merging changes that are separated by no more than twice the context
+func groupHunks(path string, lines []Line, oldLines []string) []*Hunk {
+	hunks := make([]*Hunk, 0)
+	for i := 0; i < len(lines); {
+		if lines[i].Kind == LineContext {
+			i++
+			continue
+		}
+
+		// Extend the hunk while the next change is close enough
+		start := i - contextLines
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(lines); j++ {
+			if lines[j].Kind != LineContext {
+				end = j + 1
+			} else if j-end >= 2*contextLines {
+				break
+			}
+		}
+		stop := end + contextLines
+		if stop > len(lines) {
+			stop = len(lines)
+		}
+
+		hunks = append(hunks, newDiffHunk(path, lines[start:stop], lines[:start], oldLines))
+		i = stop
+	}
+	return hunks
+}
+
+// newDiffHunk builds a hunk from its lines; before holds the lines that precede it
+func newDiffHunk(path string, lines, before []Line, oldLines []string) *Hunk {
+	h := &Hunk{File: path, Lines: append([]Line(nil), lines...)}
+
+	// Ranges start at the first line of each side, or at the line before for an empty side
+	oldBefore, newBefore := 0, 0
+	for _, line := range before {
+		if line.OldLine != 0 {
+			oldBefore = line.OldLine
+		}
+		if line.NewLine != 0 {
+			newBefore = line.NewLine
+		}
+	}
+	for _, line := range lines {
+		if line.OldLine != 0 {
+			h.OldCount++
+		}
+		if line.NewLine != 0 {
+			h.NewCount++
+		}
+	}
+	h.OldStart = oldBefore + 1
+	if h.OldCount == 0 {
+		h.OldStart = oldBefore
+	}
+	h.NewStart = newBefore + 1
+	if h.NewCount == 0 {
+		h.NewStart = newBefore
+	}
+
+	h.Section = sectionHeading(oldLines, oldBefore)
+	return h
+}
+
+// sectionHeading finds the heading git would print for a hunk starting after
+// the first before lines of the old version: with no funcname pattern configured,
+// the closest preceding line that starts with a letter, "_" or "$"
+func sectionHeading(oldLines []string, before int) string {
+	if before > len(oldLines) {
+		before = len(oldLines)
+	}
+	for i := before - 1; i >= 0; i-- {
+		line := lineContent(oldLines[i])
+		if line == "" {
+			continue
+		}
+		c := line[0]
+		if c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+			line = strings.TrimRight(line, " \t")
+			if len(line) > maxSectionLength {
+				line = line[:maxSectionLength]
+			}
+			return line
+		}
+	}
+	return ""
+}
+
+// splitLines splits file content into lines that keep their line ending
+func splitLines(content string) []string {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineContent returns a line without its line ending
+func lineContent(line string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+}
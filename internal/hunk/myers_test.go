@@ -0,0 +1,194 @@
+package hunk
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffLineIDs_ShortestEditScript(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		a := randomIDs(rng, rng.Intn(30), 4)
+		b := randomIDs(rng, rng.Intn(30), 4)
+
+		matches := diffLineIDs(a, b)
+
+		// Matches must pair equal lines in increasing order on both sides
+		prevOld, prevNew := -1, -1
+		for _, m := range matches {
+			if m.old <= prevOld || m.new <= prevNew || a[m.old] != b[m.new] {
+				t.Fatalf("invalid matches %v for a=%v b=%v", matches, a, b)
+			}
+			prevOld, prevNew = m.old, m.new
+		}
+
+		// A shortest edit script keeps a longest common subsequence
+		if want := lcsLength(a, b); len(matches) != want {
+			t.Fatalf("expected %d matches, got %d for a=%v b=%v", want, len(matches), a, b)
+		}
+	}
+}
+
+func TestDiffHunks(t *testing.T) {
+	oldContent := `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println("one")
+	fmt.Println("two")
+	fmt.Println("three")
+}
+
+func helper() int {
+	a := 1
+	b := 2
+	c := 3
+	d := 4
+	e := 5
+	return a + b + c + d + e
+}
+`
+	newContent := `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println("one")
+	fmt.Println("2")
+	fmt.Println("three")
+}
+
+func helper() int {
+	a := 1
+	b := 2
+	c := 3
+	d := 4
+	e := 5
+	f := 6
+	return a + b + c + d + e + f
+}
+`
+
+	// Expected output of git diff for the same change
+	gitDiff := `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -4,7 +4,7 @@ import "fmt"
+
+ func main() {
+ 	fmt.Println("one")
+-	fmt.Println("two")
++	fmt.Println("2")
+ 	fmt.Println("three")
+ }
+
+@@ -14,5 +14,6 @@ func helper() int {
+ 	c := 3
+ 	d := 4
+ 	e := 5
+-	return a + b + c + d + e
++	f := 6
++	return a + b + c + d + e + f
+ }
+`
+	want, err := ParseGitDiff(gitDiff)
+	if err != nil {
+		t.Fatalf("failed to parse git diff: %v", err)
+	}
+
+	got := diffHunks("main.go", oldContent, newContent)
+	if !reflect.DeepEqual(got, want.Hunks["main.go"]) {
+		t.Errorf("hunks differ from git\ngot:  %s\nwant: %s", describeHunks(got), describeHunks(want.Hunks["main.go"]))
+	}
+}
+
+func TestDiffHunks_EdgeCases(t *testing.T) {
+	tests := []struct {
+		name       string
+		oldContent string
+		newContent string
+		want       []string // "-old,count +new,count" per hunk
+	}{
+		{name: "identical", oldContent: "a\nb\n", newContent: "a\nb\n"},
+		{name: "new file", oldContent: "", newContent: "a\nb\n", want: []string{"-0,0 +1,2"}},
+		{name: "emptied file", oldContent: "a\nb\n", newContent: "", want: []string{"-1,2 +0,0"}},
+		{name: "missing final newline", oldContent: "a\nb\n", newContent: "a\nb", want: []string{"-1,2 +1,2"}},
+		{name: "append", oldContent: "1\n2\n3\n4\n5\n", newContent: "1\n2\n3\n4\n5\n6\n", want: []string{"-3,3 +3,4"}},
+		{name: "separate hunks", oldContent: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n", newContent: "x\n2\n3\n4\n5\n6\n7\n8\n9\ny\n", want: []string{"-1,4 +1,4", "-7,4 +7,4"}},
+		{name: "merged hunks", oldContent: "1\n2\n3\n4\n5\n6\n7\n8\n", newContent: "x\n2\n3\n4\n5\n6\n7\ny\n", want: []string{"-1,8 +1,8"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hunks := diffHunks("f", tt.oldContent, tt.newContent)
+			got := make([]string, 0, len(hunks))
+			for _, h := range hunks {
+				got = append(got, rangeString(h))
+			}
+			if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("expected hunks %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSectionHeading(t *testing.T) {
+	lines := splitLines("package x\n\nfunc a() {\n\tx := 1\n\n\ty := 2\n")
+	if got := sectionHeading(lines, 6); got != "func a() {" {
+		t.Errorf("expected the enclosing function, got %q", got)
+	}
+	if got := sectionHeading(lines, 0); got != "" {
+		t.Errorf("expected no heading before the first line, got %q", got)
+	}
+	long := splitLines(strings.Repeat("x", 100) + "\n\tbody\n")
+	if got := sectionHeading(long, 2); len(got) != maxSectionLength {
+		t.Errorf("expected the heading to be cut to %d bytes, got %d", maxSectionLength, len(got))
+	}
+}
+
+// randomIDs returns n line IDs drawn from a small alphabet so that lines repeat
+func randomIDs(rng *rand.Rand, n, alphabet int) []int {
+	ids := make([]int, n)
+	for i := range ids {
+		ids[i] = rng.Intn(alphabet)
+	}
+	return ids
+}
+
+// lcsLength computes the length of a longest common subsequence by dynamic programming
+func lcsLength(a, b []int) int {
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else if table[i+1][j] > table[i][j+1] {
+				table[i][j] = table[i+1][j]
+			} else {
+				table[i][j] = table[i][j+1]
+			}
+		}
+	}
+	return table[0][0]
+}
+
+// rangeString formats the ranges of a hunk like its header
+func rangeString(h *Hunk) string {
+	return fmt.Sprintf("-%d,%d +%d,%d", h.OldStart, h.OldCount, h.NewStart, h.NewCount)
+}
+
+// describeHunks formats hunks for test failure messages
+func describeHunks(hunks []*Hunk) string {
+	parts := make([]string, 0, len(hunks))
+	for _, h := range hunks {
+		parts = append(parts, rangeString(h)+" "+h.Section)
+	}
+	return strings.Join(parts, "; ")
+}
@@ -1,145 +1,820 @@
 package hunk
 
 import (
-	"bufio"
 	"fmt"
+	"io"
+	"sort"
 	"strconv"
 	"strings"
 )
 
-// Hunk represents a changed line in a file
-type Hunk struct {
-	File      string
-	Line      int
-	IsAdded   bool
-	IsRemoved bool
-}
-
 // ParseResult contains the parsed diff information
 type ParseResult struct {
 	// ChangedLines maps file path -> line number -> true if changed
 	ChangedLines map[string]map[int]bool
 	// AddedLines maps file path -> line number -> true if added
 	AddedLines map[string]map[int]bool
-	// RemovedLines maps file path -> line number -> true if removed
+	// RemovedLines maps file path -> old line number -> true if removed
+	// Line numbers refer to the old (base) version of the file
 	RemovedLines map[string]map[int]bool
 	// NewFiles tracks which files are new (didn't exist in base)
 	NewFiles map[string]bool
 	// ModifiedFiles tracks which files existed in base and were modified
 	ModifiedFiles map[string]bool
+	// DeletedFiles tracks which base files were deleted, by their old path
+	DeletedFiles map[string]bool
+	// Renames maps new file path -> old file path for files git reported as
+	// renamed. It is keyed by the new path like the other maps; NewPaths looks
+	// up the new paths of an old path.
+	Renames map[string]string
+	// Copies maps new file path -> source file path for files git reported as
+	// copied. It is keyed by the new path because one source may be copied to
+	// several new paths.
+	Copies map[string]string
+	// Similarity maps new file path -> similarity index (0-100) for renames and copies
+	Similarity map[string]int
+	// Hunks maps file path -> hunks in the order they appear in the diff
+	Hunks map[string][]*Hunk
+	// LineMaps maps file path -> mapping between old and new line numbers
+	LineMaps map[string]*LineMap
+	// SkippedFiles maps file path -> binary, mode-only and submodule changes,
+	// which have no lines to analyze
+	SkippedFiles map[string]*SkippedFile
+	// ExcludedLines maps file path -> added lines dropped by ExcludeTrivialChanges
+	// because they are blank, comment-only or formatting-only
+	ExcludedLines map[string]*ExcludedLines
 }
 
 // ParseGitDiff parses git diff output and returns a map of changed lines
 // The output format is: map[filepath]map[lineNumber]bool
+// Each hunk body is validated against the line counts in its header, so a
+// truncated or corrupt diff returns an error instead of wrong line numbers.
 func ParseGitDiff(diffOutput string) (*ParseResult, error) {
-	result := &ParseResult{
+	return ParseGitDiffReader(strings.NewReader(diffOutput))
+}
+
+// ParseGitDiffReader parses git diff output read from r, streaming it file by file.
+// Lines of any length are supported.
+func ParseGitDiffReader(r io.Reader) (*ParseResult, error) {
+	return collect(NewReader(r))
+}
+
+// collect merges every file returned by reader into a single ParseResult
+func collect(reader *Reader) (*ParseResult, error) {
+	result := newParseResult()
+	for {
+		file, err := reader.Next()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.merge(file)
+	}
+}
+
+// newParseResult creates an empty ParseResult with all maps initialized
+func newParseResult() *ParseResult {
+	return &ParseResult{
 		ChangedLines:  make(map[string]map[int]bool),
 		AddedLines:    make(map[string]map[int]bool),
 		RemovedLines:  make(map[string]map[int]bool),
 		NewFiles:      make(map[string]bool),
 		ModifiedFiles: make(map[string]bool),
+		DeletedFiles:  make(map[string]bool),
+		Renames:       make(map[string]string),
+		Copies:        make(map[string]string),
+		Similarity:    make(map[string]int),
+		Hunks:         make(map[string][]*Hunk),
+		LineMaps:      make(map[string]*LineMap),
+		SkippedFiles:  make(map[string]*SkippedFile),
+		ExcludedLines: make(map[string]*ExcludedLines),
+	}
+}
+
+// diffParser holds the state of a line-by-line diff parse
+type diffParser struct {
+	format  Format         // Dialects recognized when starting a file section
+	result  *ParseResult   // Changes collected since the last flush
+	pending []*ParseResult // Completed files not yet returned by the Reader
+	lineNo  int            // Line number in the diff itself, for error messages
+
+	// Per-file header state
+	header             *gitHeader // Parsed section header, nil outside a file section
+	headerLine         string     // Text after "diff --git ", kept to resolve rename prefixes
+	currentFile        string
+	currentFileOldPath string // Track the old path to detect new files
+	oldName            string // Name from the "---" line, before prefixes are removed
+	oldLabel           string // Label after the "---" name, such as a timestamp
+	renameFrom         string // Source path from rename headers
+	copyFrom           string // Source path from copy headers
+	similarity         int    // Similarity index from the extended header, -1 if absent
+	sectionPath        string // New path of the file named by the "diff --git" header
+	fileStarted        bool   // A "+++" header was seen for the current file
+	deletedFile        bool   // The file was deleted ("deleted file mode")
+	binary             bool   // Git reported the file as binary
+	submodule          bool   // The file is a gitlink (mode 160000)
+	submoduleDetail    string // Old and new submodule commits from the index line
+	oldMode            string // Mode from "old mode"
+	newMode            string // Mode from "new mode"
+
+	// Per-hunk state
+	currentHunk    *Hunk  // Hunk being parsed, nil for files that are not tracked
+	hunkHeader     string // Header of the hunk being parsed, for error messages
+	currentLine    int    // Line number in the new file version
+	currentOldLine int    // Line number in the old file version
+	remainingOld   int    // Old-side lines still expected in the current hunk
+	remainingNew   int    // New-side lines still expected in the current hunk
+	parents        int    // Number of parents of a combined diff hunk, 0 for ordinary hunks
+	remainingOther []int  // Lines still expected from the second and later parents of a combined hunk
+	afterHunk      bool   // The previous diff line completed a hunk
+}
+
+// newDiffParser creates a parser with an empty result
+func newDiffParser(format Format) *diffParser {
+	return &diffParser{
+		format:     format,
+		result:     newParseResult(),
+		similarity: -1,
+	}
+}
+
+// inHunk returns true while the current hunk still expects body lines
+func (p *diffParser) inHunk() bool {
+	if p.remainingOld > 0 || p.remainingNew > 0 {
+		return true
+	}
+	for _, remaining := range p.remainingOther {
+		if remaining > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// inFileHeader returns true between the line that starts a file section and the
+// "+++" line that ends its file header
+func (p *diffParser) inFileHeader() bool {
+	return p.header != nil && !p.fileStarted
+}
+
+// parseLine processes a single line of diff output
+func (p *diffParser) parseLine(line string) error {
+	p.lineNo++
+
+	// Inside a hunk every line belongs to the hunk body, even if it looks like a header
+	if p.inHunk() {
+		return p.parseHunkLine(line)
+	}
+
+	afterHunk := p.afterHunk
+	p.afterHunk = false
+
+	// "\ No newline at end of file" applies to the preceding line and is not counted
+	if strings.HasPrefix(line, "\\") {
+		p.afterHunk = afterHunk
+		return nil
+	}
+
+	// Start of a new file section resets all per-file header state
+	// Format: diff --git a/path/to/file.go b/path/to/file.go
+	if rest, ok := strings.CutPrefix(line, "diff --git "); ok && p.format.acceptsGit() {
+		p.startSection(parseGitHeader(rest), rest)
+		return nil
+	}
+
+	// Combined diff of a merge commit names a single path
+	// Format: diff --cc path/to/file.go
+	if rest, ok := cutCombinedHeader(line); ok {
+		if !p.format.acceptsGit() {
+			return fmt.Errorf("unsupported diff at line %d: combined diff %q can only be read as a git diff, not as %s", p.lineNo, line, p.format)
+		}
+		header, err := parseCombinedHeader(rest)
+		if err != nil {
+			return fmt.Errorf("corrupt diff at line %d: %w", p.lineNo, err)
+		}
+		p.startSection(header, rest)
+		return nil
+	}
+
+	// Sections of other tools: Mercurial, Subversion and GNU diff
+	if p.format.acceptsHg() {
+		if header, ok := parseHgHeader(line); ok {
+			p.startSection(header, "")
+			return nil
+		}
+	}
+	if p.format.acceptsSVN() {
+		if header, ok := parseSVNHeader(line); ok {
+			p.startSection(header, "")
+			return nil
+		}
+	}
+	if p.format.acceptsUnified() && isUnifiedHeader(line) {
+		p.startSection(gitHeader{kind: sectionUnified}, "")
+		return nil
+	}
+	// GNU diff of two binary files prints only this line
+	if p.format.acceptsUnified() && !p.inFileHeader() && strings.HasPrefix(line, "Binary files ") {
+		p.startSection(gitHeader{kind: sectionUnified}, "")
+	}
+
+	// Headers describing changes without line hunks: binary files, mode
+	// changes and submodules. They are only meaningful before "+++".
+	if p.inFileHeader() && p.parseExtendedHeader(line) {
+		return nil
+	}
+
+	// Extended headers for renames and copies (git diff -M / -C)
+	// Format: similarity index 87%
+	//         rename from old/path.go
+	//         rename to new/path.go
+	if strings.HasPrefix(line, "similarity index ") {
+		value := strings.TrimSuffix(strings.TrimPrefix(line, "similarity index "), "%")
+		if pct, err := strconv.Atoi(value); err == nil {
+			p.similarity = pct
+		}
+		return nil
+	}
+	if value, ok := strings.CutPrefix(line, "rename from "); ok {
+		return p.setSourcePath(&p.renameFrom, value)
+	}
+	if value, ok := strings.CutPrefix(line, "rename to "); ok {
+		return p.recordTarget(p.renameFrom, value, p.result.Renames)
+	}
+	if value, ok := strings.CutPrefix(line, "copy from "); ok {
+		return p.setSourcePath(&p.copyFrom, value)
+	}
+	if value, ok := strings.CutPrefix(line, "copy to "); ok {
+		return p.recordTarget(p.copyFrom, value, p.result.Copies)
+	}
+
+	// Track the old file path
+	// Format: --- a/path/to/file.go
+	if value, ok := strings.CutPrefix(line, "--- "); ok && p.isFileHeader(value, "a/") {
+		if !p.inFileHeader() {
+			// A plain unified diff has no section header
+			p.startSection(gitHeader{kind: sectionUnified}, "")
+		}
+		name, label, err := parseFileHeaderPath(value)
+		if err != nil {
+			return fmt.Errorf("corrupt diff at line %d: %w", p.lineNo, err)
+		}
+		p.oldName = name
+		p.oldLabel = label
+		return nil
+	}
+
+	// Track the file being modified
+	// Format: +++ b/path/to/file.go
+	if value, ok := strings.CutPrefix(line, "+++ "); ok && p.isFileHeader(value, "b/") {
+		if !p.inFileHeader() {
+			p.startSection(gitHeader{kind: sectionUnified}, "")
+		}
+		name, label, err := parseFileHeaderPath(value)
+		if err != nil {
+			return fmt.Errorf("corrupt diff at line %d: %w", p.lineNo, err)
+		}
+		p.startFile(p.filePaths(name, label))
+		return nil
+	}
+
+	// Parse hunk header
+	// Format: @@ -oldStart,oldCount +newStart,newCount @@ section
+	// Example: @@ -10,5 +15,7 @@ func main() {
+	if strings.HasPrefix(line, "@@ ") {
+		return p.startHunk(line)
+	}
+
+	// Combined diffs have one "@" more than parents and a range per parent
+	// Format: @@@ -10,5 -10,6 +10,7 @@@ section
+	if strings.HasPrefix(line, "@@@") {
+		return p.startCombinedHunk(line)
+	}
+
+	// A +/- line right after a complete hunk means the hunk body is longer
+	// than its header declares. "-- " is the signature separator that
+	// git format-patch appends after the last hunk.
+	if afterHunk && line != "-- " && (strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-")) {
+		return fmt.Errorf("corrupt diff at line %d: hunk %q in %s has more lines than its header declares",
+			p.lineNo, p.hunkHeader, p.describeFile())
+	}
+
+	// Anything else outside a hunk (index lines, mode lines, commit messages) is ignored
+	return nil
+}
+
+// startSection resets all per-file header state for a new file section
+func (p *diffParser) startSection(header gitHeader, headerLine string) {
+	p.finishSection()
+	p.flush()
+	p.header = &header
+	p.headerLine = headerLine
+	p.sectionPath = header.NewPath
+	p.currentFile = ""
+	p.currentFileOldPath = ""
+	p.oldName = ""
+	p.oldLabel = ""
+	p.currentHunk = nil
+	p.renameFrom = ""
+	p.copyFrom = ""
+	p.similarity = -1
+	p.fileStarted = false
+	p.deletedFile = false
+	p.binary = false
+	p.submodule = false
+	p.submoduleDetail = ""
+	p.oldMode = ""
+	p.newMode = ""
+	p.parents = 0
+}
+
+// setSourcePath records the source path of a rename or copy
+func (p *diffParser) setSourcePath(target *string, value string) error {
+	path, err := unquotePath(value)
+	if err != nil {
+		return fmt.Errorf("corrupt diff at line %d: %w", p.lineNo, err)
+	}
+	*target = path
+	return nil
+}
+
+// recordTarget records the destination of a rename or copy from the given source
+// in targets, which is keyed by destination
+func (p *diffParser) recordTarget(source, value string, targets map[string]string) error {
+	target, err := unquotePath(value)
+	if err != nil {
+		return fmt.Errorf("corrupt diff at line %d: %w", p.lineNo, err)
+	}
+	if source == "" {
+		return nil
+	}
+	targets[target] = source
+	p.sectionPath = target
+	if p.similarity >= 0 {
+		p.result.Similarity[target] = p.similarity
+	}
+	if p.header != nil {
+		p.header.resolvePrefixes(p.headerLine, source, target)
 	}
+	return nil
+}
 
-	scanner := bufio.NewScanner(strings.NewReader(diffOutput))
-	var currentFile string
-	var currentFileOldPath string // Track the old path to detect new files
-	var currentLine int           // Line number in the new file version
-
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// Track the old file path
-		// Format: --- a/path/to/file.go
-		if strings.HasPrefix(line, "--- a/") {
-			currentFileOldPath = strings.TrimPrefix(line, "--- a/")
-			continue
-		}
-
-		// Track the file being modified
-		// Format: +++ b/path/to/file.go
-		if strings.HasPrefix(line, "+++ b/") {
-			currentFile = strings.TrimPrefix(line, "+++ b/")
-			if currentFile == "/dev/null" {
-				// File was deleted, skip
-				currentFile = ""
-				currentFileOldPath = ""
-				continue
-			}
-
-			result.ChangedLines[currentFile] = make(map[int]bool)
-			result.AddedLines[currentFile] = make(map[int]bool)
-			result.RemovedLines[currentFile] = make(map[int]bool)
-
-			// Detect if this is a new file
-			// New files have old path as /dev/null or empty
-			if currentFileOldPath == "/dev/null" || currentFileOldPath == "" {
-				result.NewFiles[currentFile] = true
-			} else {
-				result.ModifiedFiles[currentFile] = true
-			}
-
-			currentFileOldPath = "" // Reset for next file
-			continue
-		}
-
-		// Parse hunk header
-		// Format: @@ -oldStart,oldCount +newStart,newCount @@
-		// Example: @@ -10,5 +15,7 @@
-		if strings.HasPrefix(line, "@@") {
-			parts := strings.Fields(line)
-			if len(parts) < 3 {
-				continue
-			}
-
-			// Extract the new file line number
-			newFilePart := parts[2]
-			if !strings.HasPrefix(newFilePart, "+") {
-				continue
-			}
-
-			// Handle both formats: +15,7 and +15
-			newFilePart = strings.TrimPrefix(newFilePart, "+")
-			lineParts := strings.Split(newFilePart, ",")
-			startLine, err := strconv.Atoi(lineParts[0])
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse line number in hunk header: %w", err)
-			}
-
-			// Line numbers in git diff are 1-indexed
-			// The startLine is the first line number shown in the hunk
-			// We'll increment before processing each line, so start one before
-			currentLine = startLine - 1
-			continue
-		}
-
-		// Skip if we don't have a current file
-		if currentFile == "" {
-			continue
-		}
-
-		// Process diff lines
-		// Note: We increment the line counter BEFORE processing, so the first
-		// line after a hunk header gets the correct line number
-		if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
-			// Added line
-			currentLine++
-			result.ChangedLines[currentFile][currentLine] = true
-			result.AddedLines[currentFile][currentLine] = true
-		} else if strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---") {
-			// Removed line (tracked for context, but not counted in new file)
-			// Don't increment currentLine for removed lines in the new file
-		} else if strings.HasPrefix(line, " ") || (!strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "-")) {
-			// Context line (unchanged) - starts with space or is not a +/- line
-			// Increment line counter for context lines
-			currentLine++
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading diff: %w", err)
-	}
-
-	return result, nil
+// isFileHeader reports whether the value of a "---" or "+++" line names a file.
+// Inside a file section any name is accepted, since the header says how to read it,
+// and so is any name in formats that allow plain unified diffs. Elsewhere only the
+// default prefix or /dev/null is recognized.
+func (p *diffParser) isFileHeader(value, defaultPrefix string) bool {
+	if p.inFileHeader() || p.format.acceptsUnified() {
+		return true
+	}
+	return strings.HasPrefix(value, defaultPrefix) || value == devNull
+}
+
+// filePaths works out the old and new path of the file from the "---" name seen
+// earlier and the "+++" name, stores the old path and returns the new one
+func (p *diffParser) filePaths(newName, newLabel string) string {
+	oldName := p.oldName
+	if isMissingLabel(p.oldLabel) {
+		oldName = devNull
+	}
+	if isMissingLabel(newLabel) {
+		newName = devNull
+	}
+
+	var oldPath, newPath string
+	switch p.header.kind {
+	case sectionExact:
+		// The section header names the file exactly
+		oldPath, newPath = p.header.OldPath, p.header.NewPath
+		if oldName == devNull {
+			oldPath = devNull
+		}
+		if newName == devNull {
+			newPath = devNull
+		}
+	case sectionUnified:
+		oldPath, newPath = unifiedPaths(oldName, newName)
+	default:
+		oldPath = p.header.stripPrefix(oldName, p.header.SrcPrefix, "a/")
+		newPath = p.header.stripPrefix(newName, p.header.DstPrefix, "b/")
+	}
+	p.currentFileOldPath = oldPath
+	return newPath
+}
+
+// parseExtendedHeader handles the git extended header lines that mark a file as
+// binary, a mode change or a submodule. It returns false for any other line.
+func (p *diffParser) parseExtendedHeader(line string) bool {
+	switch {
+	case strings.HasPrefix(line, "old mode "):
+		p.oldMode = strings.TrimPrefix(line, "old mode ")
+	case strings.HasPrefix(line, "new mode "):
+		p.newMode = strings.TrimPrefix(line, "new mode ")
+	case strings.HasPrefix(line, "new file mode "):
+		p.submodule = strings.TrimPrefix(line, "new file mode ") == gitlinkMode
+	case strings.HasPrefix(line, "deleted file mode "):
+		p.deletedFile = true
+	case strings.HasPrefix(line, "index "):
+		// Format: index 1234567..abcdefg 160000
+		fields := strings.Fields(strings.TrimPrefix(line, "index "))
+		if len(fields) == 2 && fields[1] == gitlinkMode {
+			p.submodule = true
+		}
+		if p.submodule && len(fields) > 0 {
+			p.submoduleDetail = strings.Replace(fields[0], "..", " → ", 1)
+		}
+	case strings.HasPrefix(line, "Binary files ") && strings.HasSuffix(line, " differ"):
+		// Format: Binary files a/logo.png and b/logo.png differ
+		p.binary = true
+		if p.sectionPath == "" || p.header.kind == sectionUnified {
+			p.sectionPath = parseBinaryFilesPath(line)
+		}
+	case line == "GIT binary patch":
+		p.binary = true
+	case line == "Cannot display: file marked as a binary type.":
+		// Subversion's marker for binary files
+		p.binary = true
+	default:
+		return false
+	}
+	return true
+}
+
+// gitlinkMode is the file mode git uses for submodule entries
+const gitlinkMode = "160000"
+
+// parseBinaryFilesPath extracts the new path from a "Binary files X and Y differ" line
+// when the section header does not name the file
+func parseBinaryFilesPath(line string) string {
+	names := strings.TrimSuffix(strings.TrimPrefix(line, "Binary files "), " differ")
+	idx := strings.LastIndex(names, " and ")
+	if idx == -1 {
+		return ""
+	}
+	oldName, err := unquotePath(names[:idx])
+	if err != nil {
+		return ""
+	}
+	newName, err := unquotePath(names[idx+len(" and "):])
+	if err != nil {
+		return ""
+	}
+	_, newPath := unifiedPaths(oldName, newName)
+	if newPath == devNull {
+		return ""
+	}
+	return newPath
+}
+
+// finishSection records the file of a "diff --git" section as skipped when
+// it changed without any line hunks to analyze
+func (p *diffParser) finishSection() {
+	if p.header != nil && !p.fileStarted && p.deletedFile && p.header.OldPath != "" {
+		// Deleted binary files have no "---" line
+		p.result.DeletedFiles[p.header.OldPath] = true
+	}
+	if p.header == nil || p.fileStarted || p.deletedFile || p.sectionPath == "" {
+		return
+	}
+
+	switch {
+	case p.binary:
+		p.skipFile(p.sectionPath, SkipBinary, "")
+	case p.submodule:
+		p.skipFile(p.sectionPath, SkipSubmodule, p.submoduleDetail)
+	case p.oldMode != "" && p.newMode != "":
+		p.skipFile(p.sectionPath, SkipModeChange, p.oldMode+" → "+p.newMode)
+	}
+}
+
+// skipFile records a changed file that cannot be analyzed
+func (p *diffParser) skipFile(path string, reason SkipReason, detail string) {
+	p.result.SkippedFiles[path] = &SkippedFile{Path: path, Reason: reason, Detail: detail}
+}
+
+// startFile records the file named by a "+++" header
+func (p *diffParser) startFile(path string) {
+	if !p.inFileHeader() {
+		// Without a "diff --git" header, each "+++" line starts a new file
+		p.flush()
+	}
+	p.fileStarted = true
+	p.currentFile = path
+	if p.currentFile == devNull {
+		// File was deleted, skip its lines
+		if p.currentFileOldPath != "" && p.currentFileOldPath != devNull {
+			p.result.DeletedFiles[p.currentFileOldPath] = true
+		}
+		p.currentFile = ""
+		p.currentFileOldPath = ""
+		return
+	}
+
+	if p.submodule {
+		// Submodule hunks only contain "Subproject commit" lines
+		p.skipFile(path, SkipSubmodule, p.submoduleDetail)
+		p.sectionPath = path
+		p.currentFile = ""
+		p.currentFileOldPath = ""
+		return
+	}
+
+	p.result.ChangedLines[p.currentFile] = make(map[int]bool)
+	p.result.AddedLines[p.currentFile] = make(map[int]bool)
+	p.result.RemovedLines[p.currentFile] = make(map[int]bool)
+	p.result.Hunks[p.currentFile] = make([]*Hunk, 0)
+
+	// Detect if this is a new file
+	// New files have old path as /dev/null or empty. Renamed and copied
+	// files existed in base under another path, so they count as modified.
+	if p.renameFrom == "" && p.copyFrom == "" && (p.currentFileOldPath == devNull || p.currentFileOldPath == "") {
+		p.result.NewFiles[p.currentFile] = true
+	} else {
+		p.result.ModifiedFiles[p.currentFile] = true
+	}
+
+	p.currentFileOldPath = "" // Reset for next file
+}
+
+// startHunk parses a hunk header and prepares to read its body
+func (p *diffParser) startHunk(line string) error {
+	parts := strings.Fields(line)
+	if len(parts) < 4 || !strings.HasPrefix(parts[1], "-") || !strings.HasPrefix(parts[2], "+") || parts[3] != "@@" {
+		return fmt.Errorf("corrupt diff at line %d: malformed hunk header %q", p.lineNo, line)
+	}
+
+	// Handle both formats: +15,7 and +15
+	oldStart, oldCount, err := parseRange(strings.TrimPrefix(parts[1], "-"))
+	if err != nil {
+		return fmt.Errorf("failed to parse old range in hunk header at line %d: %w", p.lineNo, err)
+	}
+	startLine, newCount, err := parseRange(strings.TrimPrefix(parts[2], "+"))
+	if err != nil {
+		return fmt.Errorf("failed to parse line number in hunk header at line %d: %w", p.lineNo, err)
+	}
+
+	// Line numbers in git diff are 1-indexed
+	// The startLine is the first line number shown in the hunk
+	// We'll increment before processing each line, so start one before
+	p.currentLine = startLine - 1
+	p.currentOldLine = oldStart - 1
+	p.remainingOld = oldCount
+	p.remainingNew = newCount
+	p.parents = 0
+	p.remainingOther = nil
+	p.hunkHeader = line
+
+	p.currentHunk = nil
+	if p.currentFile != "" && p.header.kind != sectionGit && len(p.result.Hunks[p.currentFile]) == 0 {
+		p.inferFileStatus(oldStart, oldCount, startLine, newCount)
+	}
+	if p.currentFile != "" {
+		p.currentHunk = &Hunk{
+			File:     p.currentFile,
+			OldStart: oldStart,
+			OldCount: oldCount,
+			NewStart: startLine,
+			NewCount: newCount,
+			Section:  parseSection(line),
+		}
+		p.result.Hunks[p.currentFile] = append(p.result.Hunks[p.currentFile], p.currentHunk)
+	}
+	p.afterHunk = !p.inHunk()
+	return nil
+}
+
+// inferFileStatus detects added and deleted files from the first hunk of a file.
+// Tools other than git don't always mark them with /dev/null: GNU "diff -N" uses
+// the epoch as timestamp and "svn diff" labels the missing side "(working copy)"
+// in some versions. An empty old side means an added file, an empty new side a
+// deleted one.
+func (p *diffParser) inferFileStatus(oldStart, oldCount, newStart, newCount int) {
+	switch {
+	case oldStart == 0 && oldCount == 0 && p.result.ModifiedFiles[p.currentFile] && p.renameFrom == "" && p.copyFrom == "":
+		delete(p.result.ModifiedFiles, p.currentFile)
+		p.result.NewFiles[p.currentFile] = true
+	case newStart == 0 && newCount == 0:
+		delete(p.result.ChangedLines, p.currentFile)
+		delete(p.result.AddedLines, p.currentFile)
+		delete(p.result.RemovedLines, p.currentFile)
+		delete(p.result.Hunks, p.currentFile)
+		delete(p.result.NewFiles, p.currentFile)
+		delete(p.result.ModifiedFiles, p.currentFile)
+		p.result.DeletedFiles[p.currentFile] = true
+		p.currentFile = ""
+	}
+}
+
+// parseHunkLine processes a single line of a hunk body
+func (p *diffParser) parseHunkLine(line string) error {
+	if p.parents > 0 {
+		return p.parseCombinedHunkLine(line)
+	}
+
+	// Process diff lines
+	// Note: We increment the line counter BEFORE processing, so the first
+	// line after a hunk header gets the correct line number
+	switch {
+	case strings.HasPrefix(line, "+"):
+		// Added line
+		if p.remainingNew == 0 {
+			return p.hunkMismatch("added")
+		}
+		p.remainingNew--
+		p.currentLine++
+		if p.currentFile != "" {
+			p.result.ChangedLines[p.currentFile][p.currentLine] = true
+			p.result.AddedLines[p.currentFile][p.currentLine] = true
+		}
+		p.currentHunk.addLine(Line{Kind: LineAdded, NewLine: p.currentLine, Content: line[1:]})
+	case strings.HasPrefix(line, "-"):
+		// Removed line (tracked by its old line number, not counted in new file)
+		// Don't increment currentLine for removed lines in the new file
+		if p.remainingOld == 0 {
+			return p.hunkMismatch("removed")
+		}
+		p.remainingOld--
+		p.currentOldLine++
+		if p.currentFile != "" {
+			p.result.RemovedLines[p.currentFile][p.currentOldLine] = true
+		}
+		p.currentHunk.addLine(Line{Kind: LineRemoved, OldLine: p.currentOldLine, Content: line[1:]})
+	case strings.HasPrefix(line, " ") || line == "":
+		// Context line (unchanged); some tools strip the space from blank context lines
+		// Increment line counters for context lines
+		if p.remainingOld == 0 || p.remainingNew == 0 {
+			return p.hunkMismatch("context")
+		}
+		p.remainingOld--
+		p.remainingNew--
+		p.currentLine++
+		p.currentOldLine++
+		p.currentHunk.addLine(Line{Kind: LineContext, OldLine: p.currentOldLine, NewLine: p.currentLine, Content: strings.TrimPrefix(line, " ")})
+	case strings.HasPrefix(line, "\\"):
+		// "\ No newline at end of file" describes the previous line and is not counted
+		return nil
+	default:
+		return p.hunkEnded()
+	}
+
+	p.afterHunk = !p.inHunk()
+	return nil
+}
+
+// hunkEnded reports a line that is not part of a hunk body before the hunk is complete
+func (p *diffParser) hunkEnded() error {
+	return fmt.Errorf("truncated diff at line %d: hunk %q in %s ended early, expected %d more old and %d more new lines",
+		p.lineNo, p.hunkHeader, p.describeFile(), p.remainingOld, p.remainingNew)
+}
+
+// hunkMismatch reports a hunk body line that does not fit the counts in the header
+func (p *diffParser) hunkMismatch(kind string) error {
+	return fmt.Errorf("corrupt diff at line %d: unexpected %s line in hunk %q in %s (%d old and %d new lines remaining)",
+		p.lineNo, kind, p.hunkHeader, p.describeFile(), p.remainingOld, p.remainingNew)
+}
+
+// describeFile names the current file for error messages
+func (p *diffParser) describeFile() string {
+	if p.currentFile != "" {
+		return p.currentFile
+	}
+	if p.submodule && p.sectionPath != "" {
+		return p.sectionPath
+	}
+	return "deleted file"
+}
+
+// finish validates the end of input and flushes the last file
+func (p *diffParser) finish() error {
+	if p.inHunk() {
+		return fmt.Errorf("truncated diff: input ended inside hunk %q in %s, expected %d more old and %d more new lines",
+			p.hunkHeader, p.describeFile(), p.remainingOld, p.remainingNew)
+	}
+	p.finishSection()
+	p.flush()
+	return nil
+}
+
+// flush completes the changes collected since the last flush, builds their
+// line mappings and queues them for the Reader
+func (p *diffParser) flush() {
+	if p.result.isEmpty() {
+		return
+	}
+
+	// Build old/new line mappings from the collected hunks
+	for file, hunks := range p.result.Hunks {
+		p.result.LineMaps[file] = NewLineMap(hunks)
+	}
+
+	p.pending = append(p.pending, p.result)
+	p.result = newParseResult()
+}
+
+// parseSection extracts the section heading that follows the closing "@@" of a hunk
+// header, or the closing "@@@" (one "@" per parent plus one) of a combined hunk header
+func parseSection(header string) string {
+	rest := strings.TrimLeft(header, "@")
+	marker := header[:len(header)-len(rest)]
+	idx := strings.Index(rest, " "+marker)
+	if idx == -1 {
+		return ""
+	}
+	return strings.TrimSpace(rest[idx+1+len(marker):])
+}
+
+// parseRange parses a hunk header range in the form "start,count" or "start".
+// A missing count defaults to 1.
+func parseRange(value string) (int, int, error) {
+	startStr, countStr, hasCount := strings.Cut(value, ",")
+	start, err := strconv.Atoi(startStr)
+	if err != nil {
+		return 0, 0, err
+	}
+	count := 1
+	if hasCount {
+		count, err = strconv.Atoi(countStr)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return start, count, nil
+}
+
+// isEmpty returns true if nothing has been recorded in the result
+func (r *ParseResult) isEmpty() bool {
+	return len(r.ChangedLines) == 0 && len(r.NewFiles) == 0 && len(r.ModifiedFiles) == 0 && len(r.DeletedFiles) == 0 &&
+		len(r.Renames) == 0 && len(r.Copies) == 0 && len(r.SkippedFiles) == 0
+}
+
+// merge adds the changes recorded in other to the result
+func (r *ParseResult) merge(other *ParseResult) {
+	for file, lines := range other.ChangedLines {
+		r.ChangedLines[file] = lines
+	}
+	for file, lines := range other.AddedLines {
+		r.AddedLines[file] = lines
+	}
+	for file, lines := range other.RemovedLines {
+		r.RemovedLines[file] = lines
+	}
+	for file := range other.NewFiles {
+		r.NewFiles[file] = true
+	}
+	for file := range other.ModifiedFiles {
+		r.ModifiedFiles[file] = true
+	}
+	for file := range other.DeletedFiles {
+		r.DeletedFiles[file] = true
+	}
+	for newPath, oldPath := range other.Renames {
+		r.Renames[newPath] = oldPath
+	}
+	for newPath, oldPath := range other.Copies {
+		r.Copies[newPath] = oldPath
+	}
+	for file, similarity := range other.Similarity {
+		r.Similarity[file] = similarity
+	}
+	for file, hunks := range other.Hunks {
+		r.Hunks[file] = hunks
+	}
+	for file, lineMap := range other.LineMaps {
+		r.LineMaps[file] = lineMap
+	}
+	for file, skipped := range other.SkippedFiles {
+		r.SkippedFiles[file] = skipped
+	}
+	for file, excluded := range other.ExcludedLines {
+		r.ExcludedLines[file] = excluded
+	}
+}
+
+// addFile records the hunks of a file and derives its changed, added and removed
+// lines and its line map from them, as the parser does for a file in a diff
+func (r *ParseResult) addFile(path string, hunks []*Hunk, isNew bool) {
+	r.ChangedLines[path] = make(map[int]bool)
+	r.AddedLines[path] = make(map[int]bool)
+	r.RemovedLines[path] = make(map[int]bool)
+	for _, h := range hunks {
+		for _, line := range h.AddedLines() {
+			r.ChangedLines[path][line] = true
+			r.AddedLines[path][line] = true
+		}
+		for _, line := range h.RemovedLines() {
+			r.RemovedLines[path][line] = true
+		}
+	}
+	r.Hunks[path] = hunks
+	r.LineMaps[path] = NewLineMap(hunks)
+
+	if isNew {
+		r.NewFiles[path] = true
+	} else {
+		r.ModifiedFiles[path] = true
+	}
 }
 
 // GetChangedFiles returns a list of all files that have changes
@@ -161,6 +836,32 @@ func (r *ParseResult) GetAddedLinesForFile(file string) map[int]bool {
 	return r.AddedLines[file]
 }
 
+// GetHunksForFile returns the hunks for a file in diff order
+func (r *ParseResult) GetHunksForFile(file string) []*Hunk {
+	return r.Hunks[file]
+}
+
+// GetRemovedLinesForFile returns the set of removed line numbers for a file
+// Line numbers refer to the old (base) version of the file
+func (r *ParseResult) GetRemovedLinesForFile(file string) map[int]bool {
+	return r.RemovedLines[file]
+}
+
+// GetLineMapForFile returns the old/new line mapping for a file, or nil if unknown
+func (r *ParseResult) GetLineMapForFile(file string) *LineMap {
+	return r.LineMaps[file]
+}
+
+// OldLineNumber returns the base line number that corresponds to a line in the new
+// version of a file. It returns false for added lines and for files without a mapping.
+func (r *ParseResult) OldLineNumber(file string, newLine int) (int, bool) {
+	lineMap := r.GetLineMapForFile(file)
+	if lineMap == nil {
+		return 0, false
+	}
+	return lineMap.OldLine(newLine)
+}
+
 // IsNewFile returns true if the file is new (didn't exist in base)
 func (r *ParseResult) IsNewFile(file string) bool {
 	return r.NewFiles[file]
@@ -171,6 +872,46 @@ func (r *ParseResult) IsModifiedFile(file string) bool {
 	return r.ModifiedFiles[file]
 }
 
+// IsDeletedFile returns true if the base file at the given path was deleted
+func (r *ParseResult) IsDeletedFile(file string) bool {
+	return r.DeletedFiles[file]
+}
+
+// IsRenamedFile returns true if the file was renamed or copied from another path
+func (r *ParseResult) IsRenamedFile(file string) bool {
+	return r.OldPath(file) != file
+}
+
+// OldPath returns the path a file had in the base version.
+// For renamed or copied files this is the source path; otherwise it is the file itself.
+func (r *ParseResult) OldPath(file string) string {
+	if oldPath, ok := r.Renames[file]; ok {
+		return oldPath
+	}
+	if oldPath, ok := r.Copies[file]; ok {
+		return oldPath
+	}
+	return file
+}
+
+// NewPaths returns the paths an old file was renamed or copied to, sorted, or
+// nil if it was neither
+func (r *ParseResult) NewPaths(oldPath string) []string {
+	var newPaths []string
+	for newPath, source := range r.Renames {
+		if source == oldPath {
+			newPaths = append(newPaths, newPath)
+		}
+	}
+	for newPath, source := range r.Copies {
+		if source == oldPath {
+			newPaths = append(newPaths, newPath)
+		}
+	}
+	sort.Strings(newPaths)
+	return newPaths
+}
+
 // HasChanges returns true if there are any changes
 func (r *ParseResult) HasChanges() bool {
 	return len(r.ChangedLines) > 0
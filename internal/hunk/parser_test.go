@@ -1,6 +1,8 @@
 package hunk
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -19,7 +21,7 @@ func TestParseGitDiff(t *testing.T) {
 index 1234567..abcdefg 100644
 --- a/file.go
 +++ b/file.go
-@@ -5,3 +5,5 @@ func main() {
+@@ -5,2 +5,4 @@ func main() {
  	fmt.Println("hello")
 +	fmt.Println("new line 1")
 +	fmt.Println("new line 2")
@@ -80,7 +82,7 @@ index 123..123 100644
 index 123..456 100644
 --- a/file.go
 +++ b/file.go
-@@ -10,5 +10,7 @@ func test() {
+@@ -10,4 +10,6 @@ func test() {
  	line1
  	line2
 +	new1
@@ -206,7 +208,7 @@ func TestParseGitDiff_ModifiedFile(t *testing.T) {
 index 1234567..abcdefg 100644
 --- a/file.go
 +++ b/file.go
-@@ -5,3 +5,5 @@ func main() {
+@@ -5,2 +5,4 @@ func main() {
  	fmt.Println("hello")
 +	fmt.Println("new line")
 +	fmt.Println("another line")
@@ -241,7 +243,7 @@ diff --git a/existing.go b/existing.go
 index 1111111..2222222 100644
 --- a/existing.go
 +++ b/existing.go
-@@ -5,1 +5,2 @@
+@@ -5,0 +6,1 @@
 +	new line
 `
 
@@ -266,3 +268,795 @@ index 1111111..2222222 100644
 		t.Error("expected existing.go to be detected as modified file")
 	}
 }
+
+func TestParseGitDiff_Rename(t *testing.T) {
+	diffOutput := `diff --git a/old/name.go b/new/name.go
+similarity index 87%
+rename from old/name.go
+rename to new/name.go
+index 1234567..abcdefg 100644
+--- a/old/name.go
++++ b/new/name.go
+@@ -5,2 +5,3 @@ func main() {
+ 	fmt.Println("hello")
++	fmt.Println("new line")
+ 	fmt.Println("world")
+`
+
+	result, err := ParseGitDiff(diffOutput)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Renames["new/name.go"] != "old/name.go" {
+		t.Errorf("expected rename old/name.go -> new/name.go, got %v", result.Renames)
+	}
+	if result.Similarity["new/name.go"] != 87 {
+		t.Errorf("expected similarity 87, got %d", result.Similarity["new/name.go"])
+	}
+	if result.IsNewFile("new/name.go") {
+		t.Error("expected renamed file not to be detected as new file")
+	}
+	if !result.IsModifiedFile("new/name.go") {
+		t.Error("expected renamed file to be detected as modified file")
+	}
+	if !result.IsRenamedFile("new/name.go") {
+		t.Error("expected new/name.go to be detected as renamed")
+	}
+	if got := result.OldPath("new/name.go"); got != "old/name.go" {
+		t.Errorf("expected old path old/name.go, got %q", got)
+	}
+	if !result.GetChangedLinesForFile("new/name.go")[6] {
+		t.Error("expected line 6 to be changed in renamed file")
+	}
+}
+
+func TestParseGitDiff_PureRenameAndCopy(t *testing.T) {
+	diffOutput := `diff --git a/a.go b/b.go
+similarity index 100%
+rename from a.go
+rename to b.go
+diff --git a/src.go b/dst.go
+similarity index 90%
+copy from src.go
+copy to dst.go
+index 1111111..2222222 100644
+--- a/src.go
++++ b/dst.go
+@@ -1,1 +1,2 @@
+ package main
++var copied = true
+`
+
+	result, err := ParseGitDiff(diffOutput)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Renames["b.go"] != "a.go" {
+		t.Errorf("expected rename a.go -> b.go, got %v", result.Renames)
+	}
+	if _, ok := result.ChangedLines["b.go"]; ok {
+		t.Error("expected pure rename not to produce changed lines")
+	}
+	if result.Copies["dst.go"] != "src.go" {
+		t.Errorf("expected copy src.go -> dst.go, got %v", result.Copies)
+	}
+	if result.OldPath("dst.go") != "src.go" {
+		t.Errorf("expected old path src.go, got %q", result.OldPath("dst.go"))
+	}
+	if result.IsNewFile("dst.go") || !result.IsModifiedFile("dst.go") {
+		t.Error("expected copied file to be detected as modified file")
+	}
+	if result.OldPath("unrelated.go") != "unrelated.go" {
+		t.Error("expected OldPath to return the file itself when not renamed")
+	}
+}
+
+func TestParseGitDiff_CopiesFromOneSource(t *testing.T) {
+	diffOutput := `diff --git a/tmpl.go b/first.go
+similarity index 100%
+copy from tmpl.go
+copy to first.go
+diff --git a/tmpl.go b/second.go
+similarity index 95%
+copy from tmpl.go
+copy to second.go
+index 1111111..2222222 100644
+--- a/tmpl.go
++++ b/second.go
+@@ -1,1 +1,2 @@
+ package main
++var second = true
+`
+
+	result, err := ParseGitDiff(diffOutput)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, path := range []string{"first.go", "second.go"} {
+		if got := result.OldPath(path); got != "tmpl.go" {
+			t.Errorf("expected old path of %s to be tmpl.go, got %q", path, got)
+		}
+	}
+	if len(result.Copies) != 2 {
+		t.Errorf("expected both copies to be recorded, got %v", result.Copies)
+	}
+	if got := result.NewPaths("tmpl.go"); !reflect.DeepEqual(got, []string{"first.go", "second.go"}) {
+		t.Errorf("expected tmpl.go to map to first.go and second.go, got %v", got)
+	}
+	if got := result.NewPaths("first.go"); got != nil {
+		t.Errorf("expected no new paths for a copy destination, got %v", got)
+	}
+	if result.Similarity["first.go"] != 100 || result.Similarity["second.go"] != 95 {
+		t.Errorf("expected similarities 100 and 95, got %v", result.Similarity)
+	}
+}
+
+func TestParseGitDiff_RemovedLinesAndLineMap(t *testing.T) {
+	diffOutput := `diff --git a/file.go b/file.go
+index 1234567..abcdefg 100644
+--- a/file.go
++++ b/file.go
+@@ -2,3 +2,4 @@ package main
+ line2
+-removed3
++added3
++added4
+ line4
+@@ -10,3 +11,2 @@ func main() {
+ line10
+-removed11
+ line12
+`
+
+	result, err := ParseGitDiff(diffOutput)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	removed := result.GetRemovedLinesForFile("file.go")
+	if len(removed) != 2 || !removed[3] || !removed[11] {
+		t.Errorf("expected removed old lines 3 and 11, got %v", removed)
+	}
+
+	tests := []struct {
+		newLine int
+		oldLine int
+		mapped  bool
+	}{
+		{1, 1, true},   // before first hunk
+		{2, 2, true},   // context
+		{3, 3, true},   // modified, replaced old line 3
+		{4, 0, false},  // purely added
+		{5, 4, true},   // context after change block
+		{8, 7, true},   // between hunks, shifted by +1
+		{11, 10, true}, // context in second hunk
+		{12, 12, true}, // context after removed line
+		{20, 20, true}, // after last hunk, net shift is zero
+	}
+
+	for _, tt := range tests {
+		oldLine, ok := result.OldLineNumber("file.go", tt.newLine)
+		if ok != tt.mapped || oldLine != tt.oldLine {
+			t.Errorf("OldLineNumber(%d) = (%d, %v), want (%d, %v)", tt.newLine, oldLine, ok, tt.oldLine, tt.mapped)
+		}
+	}
+
+	lineMap := result.GetLineMapForFile("file.go")
+	if newLine, ok := lineMap.NewLine(11); ok {
+		t.Errorf("expected removed old line 11 to have no new counterpart, got %d", newLine)
+	}
+	if newLine, ok := lineMap.NewLine(7); !ok || newLine != 8 {
+		t.Errorf("NewLine(7) = (%d, %v), want (8, true)", newLine, ok)
+	}
+}
+
+func TestParseGitDiff_ReplacedLinesMapToOld(t *testing.T) {
+	diffOutput := `diff --git a/file.go b/file.go
+index 1234567..abcdefg 100644
+--- a/file.go
++++ b/file.go
+@@ -5,4 +5,5 @@ func main() {
+ 	a()
+-	b()
+-	c()
++	b2()
++	c2()
++	d2()
+ 	e()
+`
+
+	result, err := ParseGitDiff(diffOutput)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for newLine, wantOld := range map[int]int{6: 6, 7: 7} {
+		oldLine, ok := result.OldLineNumber("file.go", newLine)
+		if !ok || oldLine != wantOld {
+			t.Errorf("OldLineNumber(%d) = (%d, %v), want (%d, true)", newLine, oldLine, ok, wantOld)
+		}
+	}
+	if _, ok := result.OldLineNumber("file.go", 8); ok {
+		t.Error("expected extra added line 8 to have no old counterpart")
+	}
+	if _, ok := result.OldLineNumber("missing.go", 1); ok {
+		t.Error("expected no mapping for a file that is not in the diff")
+	}
+}
+
+func TestParseGitDiff_Hunks(t *testing.T) {
+	diffOutput := `diff --git a/file.go b/file.go
+index 1234567..abcdefg 100644
+--- a/file.go
++++ b/file.go
+@@ -5,3 +5,4 @@ func ParseLCOV(filePath string) (*Report, error) {
+ 	a()
++	b()
+ 	c()
+ 	d()
+@@ -20,2 +21,1 @@
+ 	e()
+-	f()
+`
+
+	result, err := ParseGitDiff(diffOutput)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hunks := result.GetHunksForFile("file.go")
+	if len(hunks) != 2 {
+		t.Fatalf("expected 2 hunks, got %d", len(hunks))
+	}
+
+	first := hunks[0]
+	if first.OldStart != 5 || first.OldCount != 3 || first.NewStart != 5 || first.NewCount != 4 {
+		t.Errorf("unexpected first hunk ranges: -%d,%d +%d,%d", first.OldStart, first.OldCount, first.NewStart, first.NewCount)
+	}
+	if first.Section != "func ParseLCOV(filePath string) (*Report, error) {" {
+		t.Errorf("unexpected section: %q", first.Section)
+	}
+	if first.File != "file.go" {
+		t.Errorf("expected hunk file file.go, got %q", first.File)
+	}
+	if added := first.AddedLines(); len(added) != 1 || added[0] != 6 {
+		t.Errorf("expected added line 6, got %v", added)
+	}
+	if context := first.ContextLines(); len(context) != 3 || context[0] != 5 || context[2] != 8 {
+		t.Errorf("expected context lines [5 7 8], got %v", context)
+	}
+	if first.Lines[1].Kind != LineAdded || first.Lines[1].Content != "\tb()" {
+		t.Errorf("unexpected added line: %+v", first.Lines[1])
+	}
+
+	second := hunks[1]
+	if second.Section != "" {
+		t.Errorf("expected empty section, got %q", second.Section)
+	}
+	if removed := second.RemovedLines(); len(removed) != 1 || removed[0] != 21 {
+		t.Errorf("expected removed old line 21, got %v", removed)
+	}
+}
+
+func TestParseGitDiff_NoNewlineAtEndOfFile(t *testing.T) {
+	diffOutput := `diff --git a/file.go b/file.go
+index 1234567..abcdefg 100644
+--- a/file.go
++++ b/file.go
+@@ -1,2 +1,3 @@
+ package main
+-var x = 1
+\ No newline at end of file
++var x = 2
++var y = 3
+\ No newline at end of file
+diff --git a/other.go b/other.go
+index 1111111..2222222 100644
+--- a/other.go
++++ b/other.go
+@@ -3,2 +3,3 @@
+ a
++b
+ c
+`
+
+	result, err := ParseGitDiff(diffOutput)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	added := result.GetAddedLinesForFile("file.go")
+	if len(added) != 2 || !added[2] || !added[3] {
+		t.Errorf("expected added lines 2 and 3 in file.go, got %v", added)
+	}
+	if removed := result.GetRemovedLinesForFile("file.go"); len(removed) != 1 || !removed[2] {
+		t.Errorf("expected removed line 2 in file.go, got %v", removed)
+	}
+	if added := result.GetAddedLinesForFile("other.go"); len(added) != 1 || !added[4] {
+		t.Errorf("expected added line 4 in other.go, got %v", added)
+	}
+}
+
+func TestParseGitDiff_HunkLooksLikeHeader(t *testing.T) {
+	// A removed SQL comment starting with "--" must not be mistaken for a file header
+	diffOutput := `diff --git a/schema.sql b/schema.sql
+index 1234567..abcdefg 100644
+--- a/schema.sql
++++ b/schema.sql
+@@ -1,2 +1,2 @@
+--- old comment
++-- new comment
+ SELECT 1;
+`
+
+	result, err := ParseGitDiff(diffOutput)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if added := result.GetAddedLinesForFile("schema.sql"); len(added) != 1 || !added[1] {
+		t.Errorf("expected added line 1, got %v", added)
+	}
+	if removed := result.GetRemovedLinesForFile("schema.sql"); len(removed) != 1 || !removed[1] {
+		t.Errorf("expected removed line 1, got %v", removed)
+	}
+}
+
+func TestParseGitDiff_InvalidHunks(t *testing.T) {
+	tests := []struct {
+		name       string
+		diffOutput string
+		wantErr    string
+	}{
+		{
+			name: "truncated at end of input",
+			diffOutput: `diff --git a/file.go b/file.go
+--- a/file.go
++++ b/file.go
+@@ -1,3 +1,4 @@
+ a
++b
+`,
+			wantErr: "truncated diff",
+		},
+		{
+			name: "truncated by next file",
+			diffOutput: `diff --git a/file.go b/file.go
+--- a/file.go
++++ b/file.go
+@@ -1,3 +1,4 @@
+ a
++b
+diff --git a/other.go b/other.go
+--- a/other.go
++++ b/other.go
+@@ -1,1 +1,2 @@
+ a
++b
+`,
+			wantErr: "truncated diff at line 7",
+		},
+		{
+			name: "too many added lines",
+			diffOutput: `diff --git a/file.go b/file.go
+--- a/file.go
++++ b/file.go
+@@ -1,1 +1,2 @@
+ a
++b
++c
+`,
+			wantErr: "more lines than its header declares",
+		},
+		{
+			name: "too many removed lines",
+			diffOutput: `diff --git a/file.go b/file.go
+--- a/file.go
++++ b/file.go
+@@ -1,1 +1,1 @@
+-a
+-b
++c
+`,
+			wantErr: "unexpected removed line",
+		},
+		{
+			name: "malformed hunk header",
+			diffOutput: `diff --git a/file.go b/file.go
+--- a/file.go
++++ b/file.go
+@@ -1,x +1,2 @@
+`,
+			wantErr: "failed to parse old range",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseGitDiff(tt.diffOutput)
+			if err == nil {
+				t.Fatal("expected error but got none")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseGitDiff_FormatPatchSignature(t *testing.T) {
+	diffOutput := `diff --git a/file.go b/file.go
+index 1234567..abcdefg 100644
+--- a/file.go
++++ b/file.go
+@@ -1,1 +1,2 @@
+ a
++b
+-- 
+2.43.0
+`
+
+	result, err := ParseGitDiff(diffOutput)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if added := result.GetAddedLinesForFile("file.go"); len(added) != 1 || !added[2] {
+		t.Errorf("expected added line 2, got %v", added)
+	}
+}
+
+func TestParseGitDiff_PathPrefixes(t *testing.T) {
+	tests := []struct {
+		name       string
+		diffOutput string
+		wantFile   string
+		wantNew    bool
+	}{
+		{
+			name: "mnemonic prefixes",
+			diffOutput: `diff --git i/internal/app.go w/internal/app.go
+index 1234567..abcdefg 100644
+--- i/internal/app.go
++++ w/internal/app.go
+@@ -1,1 +1,2 @@
+ package app
++var x = 1
+`,
+			wantFile: "internal/app.go",
+		},
+		{
+			name: "no prefix",
+			diffOutput: `diff --git internal/app.go internal/app.go
+index 1234567..abcdefg 100644
+--- internal/app.go
++++ internal/app.go
+@@ -1,1 +1,2 @@
+ package app
++var x = 1
+`,
+			wantFile: "internal/app.go",
+		},
+		{
+			name: "custom prefixes on new file",
+			diffOutput: `diff --git before/app.go after/app.go
+new file mode 100644
+index 0000000..abcdefg
+--- /dev/null
++++ after/app.go
+@@ -0,0 +1,2 @@
++package app
++var x = 1
+`,
+			wantFile: "app.go",
+			wantNew:  true,
+		},
+		{
+			name: "quoted non-ASCII path",
+			diffOutput: `diff --git "a/dir/na\303\257ve.go" "b/dir/na\303\257ve.go"
+index 1234567..abcdefg 100644
+--- "a/dir/na\303\257ve.go"
++++ "b/dir/na\303\257ve.go"
+@@ -1,1 +1,2 @@
+ package dir
++var x = 1
+`,
+			wantFile: "dir/naïve.go",
+		},
+		{
+			name: "unquoted path with spaces and trailing tab",
+			diffOutput: "diff --git a/my dir/app.go b/my dir/app.go\n" +
+				"index 1234567..abcdefg 100644\n" +
+				"--- a/my dir/app.go\t\n" +
+				"+++ b/my dir/app.go\t\n" +
+				"@@ -1,1 +1,2 @@\n" +
+				" package app\n" +
+				"+var x = 1\n",
+			wantFile: "my dir/app.go",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseGitDiff(tt.diffOutput)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			files := result.GetChangedFiles()
+			if len(files) != 1 || files[0] != tt.wantFile {
+				t.Fatalf("expected file %q, got %v", tt.wantFile, files)
+			}
+			if result.IsNewFile(tt.wantFile) != tt.wantNew {
+				t.Errorf("expected IsNewFile=%v", tt.wantNew)
+			}
+			if len(result.GetAddedLinesForFile(tt.wantFile)) == 0 {
+				t.Errorf("expected added lines for %s", tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestParseGitDiff_QuotedRenameWithMnemonicPrefixes(t *testing.T) {
+	diffOutput := `diff --git c/old name.go i/new name.go
+similarity index 90%
+rename from old name.go
+rename to new name.go
+index 1234567..abcdefg 100644
+--- c/old name.go	
++++ i/new name.go	
+@@ -1,2 +1,2 @@
+ package app
+-var x = 1
++var x = 2
+diff --git "c/caf\303\251.go" "i/caf\303\251 2.go"
+similarity index 100%
+rename from "caf\303\251.go"
+rename to "caf\303\251 2.go"
+`
+
+	result, err := ParseGitDiff(diffOutput)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsModifiedFile("new name.go") {
+		t.Errorf("expected 'new name.go' to be tracked as modified, got files %v", result.GetChangedFiles())
+	}
+	if got := result.OldPath("new name.go"); got != "old name.go" {
+		t.Errorf("expected old path 'old name.go', got %q", got)
+	}
+	if got := result.Renames["café 2.go"]; got != "café.go" {
+		t.Errorf("expected quoted rename to be unescaped, got %v", result.Renames)
+	}
+}
+
+func TestParseGitDiff_SkippedFiles(t *testing.T) {
+	diffOutput := `diff --git a/assets/logo.png b/assets/logo.png
+index 1234567..abcdefg 100644
+Binary files a/assets/logo.png and b/assets/logo.png differ
+diff --git a/scripts/run.sh b/scripts/run.sh
+old mode 100644
+new mode 100755
+diff --git a/vendor/lib b/vendor/lib
+index 1111111..2222222 160000
+--- a/vendor/lib
++++ b/vendor/lib
+@@ -1 +1 @@
+-Subproject commit 1111111111111111111111111111111111111111
++Subproject commit 2222222222222222222222222222222222222222
+diff --git a/tools/build.sh b/tools/build.sh
+old mode 100644
+new mode 100755
+index 3333333..4444444
+--- a/tools/build.sh
++++ b/tools/build.sh
+@@ -1,1 +1,2 @@
+ #!/bin/sh
++make
+diff --git a/old.bin b/old.bin
+deleted file mode 100644
+index 5555555..0000000
+Binary files a/old.bin and /dev/null differ
+diff --git a/assets/icon.png b/assets/icon.png
+new file mode 100644
+index 0000000..6666666
+GIT binary patch
+literal 4
+LcmZQzWMT#Y01f~L
+
+literal 0
+HcmV?d00001
+
+`
+
+	result, err := ParseGitDiff(diffOutput)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]SkippedFile{
+		"assets/logo.png": {Path: "assets/logo.png", Reason: SkipBinary},
+		"assets/icon.png": {Path: "assets/icon.png", Reason: SkipBinary},
+		"scripts/run.sh":  {Path: "scripts/run.sh", Reason: SkipModeChange, Detail: "100644 → 100755"},
+		"vendor/lib":      {Path: "vendor/lib", Reason: SkipSubmodule, Detail: "1111111 → 2222222"},
+	}
+	if len(result.SkippedFiles) != len(want) {
+		t.Errorf("expected %d skipped files, got %d: %v", len(want), len(result.SkippedFiles), result.GetSkippedFiles())
+	}
+	for path, expected := range want {
+		got, ok := result.SkippedFiles[path]
+		if !ok {
+			t.Errorf("expected %s to be skipped", path)
+			continue
+		}
+		if *got != expected {
+			t.Errorf("expected %+v for %s, got %+v", expected, path, *got)
+		}
+	}
+
+	// Only the file with real content changes has changed lines
+	files := result.GetChangedFiles()
+	if len(files) != 1 || files[0] != "tools/build.sh" {
+		t.Errorf("expected only tools/build.sh to have changed lines, got %v", files)
+	}
+	if result.IsSkippedFile("tools/build.sh") {
+		t.Error("expected a mode change with content changes not to be skipped")
+	}
+
+	skipped := result.GetSkippedFiles()
+	if len(skipped) == 0 || skipped[0].Path != "assets/icon.png" {
+		t.Errorf("expected skipped files sorted by path, got %v", skipped)
+	}
+}
+
+func TestSkippedFile_Description(t *testing.T) {
+	tests := []struct {
+		file SkippedFile
+		want string
+	}{
+		{SkippedFile{Reason: SkipBinary}, "binary file"},
+		{SkippedFile{Reason: SkipModeChange, Detail: "100644 → 100755"}, "mode change only (100644 → 100755)"},
+		{SkippedFile{Reason: SkipSubmodule, Detail: "1111111 → 2222222"}, "submodule update (1111111 → 2222222)"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.file.Description(); got != tt.want {
+			t.Errorf("expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func TestParseGitDiff_CombinedDiff(t *testing.T) {
+	diffOutput := `commit 3333333333333333333333333333333333333333
+Merge: 1111111 2222222
+Author: Dev <dev@example.com>
+
+    Merge branch 'feature'
+
+diff --cc app.go
+index 1111111,2222222..3333333
+--- a/app.go
++++ b/app.go
+@@@ -1,4 -1,3 +1,4 @@@ package app
+  func a() {}
+- func b() { return 1 }
+ -func b() { return 2 }
+++func b() { return 3 }
+ +func c() {}
+  func d() {}
+`
+
+	result, err := ParseGitDiff(diffOutput)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Only the conflict resolution is new relative to both parents
+	changed := result.GetChangedLinesForFile("app.go")
+	if len(changed) != 1 || !changed[2] {
+		t.Errorf("expected only line 2 to be changed, got %v", changed)
+	}
+	if removed := result.GetRemovedLinesForFile("app.go"); len(removed) != 1 || !removed[2] {
+		t.Errorf("expected first-parent line 2 to be removed, got %v", removed)
+	}
+	if !result.IsModifiedFile("app.go") {
+		t.Error("expected app.go to be a modified file")
+	}
+
+	hunks := result.GetHunksForFile("app.go")
+	if len(hunks) != 1 {
+		t.Fatalf("expected 1 hunk, got %d", len(hunks))
+	}
+	h := hunks[0]
+	if h.Section != "package app" {
+		t.Errorf("expected section 'package app', got %q", h.Section)
+	}
+	wantParents := []Range{{Start: 1, Count: 4}, {Start: 1, Count: 3}}
+	if len(h.Parents) != 2 || h.Parents[0] != wantParents[0] || h.Parents[1] != wantParents[1] {
+		t.Errorf("expected parent ranges %v, got %v", wantParents, h.Parents)
+	}
+	if added := h.AddedLines(); len(added) != 1 || added[0] != 2 {
+		t.Errorf("expected hunk added lines [2], got %v", added)
+	}
+
+	// Line numbers map through the first parent
+	for newLine, wantOld := range map[int]int{1: 1, 2: 2, 3: 3, 4: 4} {
+		if oldLine, ok := result.OldLineNumber("app.go", newLine); !ok || oldLine != wantOld {
+			t.Errorf("expected new line %d to map to old line %d, got %d (ok=%v)", newLine, wantOld, oldLine, ok)
+		}
+	}
+}
+
+func TestParseGitDiff_CombinedDiffOctopus(t *testing.T) {
+	diffOutput := `diff --combined "na\303\257ve.go"
+index 1111111,2222222,3333333..4444444
+--- a/naïve.go
++++ b/naïve.go
+@@@@ -1,2 -1,1 -1,1 +1,3 @@@@
+   package main
++++var merged = true
+ ++var fromFirst = true
+`
+
+	result, err := ParseGitDiff(diffOutput)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	changed := result.GetChangedLinesForFile("naïve.go")
+	if len(changed) != 1 || !changed[2] {
+		t.Errorf("expected only line 2 to be changed, got %v", changed)
+	}
+}
+
+func TestParseGitDiff_InvalidCombinedHunks(t *testing.T) {
+	tests := []struct {
+		name       string
+		diffOutput string
+		wantErr    string
+	}{
+		{
+			name: "too many result lines",
+			diffOutput: `diff --cc app.go
+--- a/app.go
++++ b/app.go
+@@@ -1,1 -1,1 +1,2 @@@
+  a
+++b
+++c
+`,
+			wantErr: "corrupt diff at line 7",
+		},
+		{
+			name: "truncated",
+			diffOutput: `diff --cc app.go
+--- a/app.go
++++ b/app.go
+@@@ -1,2 -1,2 +1,3 @@@
+  a
+++b
+`,
+			wantErr: "truncated diff",
+		},
+		{
+			name: "missing parent range",
+			diffOutput: `diff --cc app.go
+--- a/app.go
++++ b/app.go
+@@@ -1,2 +1,3 @@@
+`,
+			wantErr: "malformed combined hunk header",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseGitDiff(tt.diffOutput)
+			if err == nil {
+				t.Fatal("expected error but got none")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package hunk
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// devNull is the path git uses for the missing side of an added or deleted file
+const devNull = "/dev/null"
+
+// sectionKind tells how the paths of a file section are determined
+type sectionKind int
+
+const (
+	// sectionGit is a "diff --git" section; paths come from the header and the
+	// prefixed "---"/"+++" lines
+	sectionGit sectionKind = iota
+	// sectionExact is a section whose header names the file exactly:
+	// "diff --cc", Mercurial's "diff -r" and Subversion's "Index:"
+	sectionExact
+	// sectionUnified is a plain unified diff section; paths come from the
+	// "---"/"+++" names alone
+	sectionUnified
+)
+
+// gitHeader holds the paths and prefixes parsed from the line that starts a file section
+type gitHeader struct {
+	kind sectionKind
+	// OldPath and NewPath are the file paths with their prefixes removed;
+	// they are empty when the header could not be split unambiguously
+	OldPath string
+	NewPath string
+	// SrcPrefix and DstPrefix are the prefixes in use, e.g. "a/" and "b/",
+	// "i/" and "w/" for mnemonic prefixes, or empty for --no-prefix
+	SrcPrefix string
+	DstPrefix string
+	// known is true when the prefixes were worked out from the header
+	known bool
+}
+
+// parseGitHeader parses the part of a "diff --git" line after "diff --git ".
+// Either name may be C-quoted. Unquoted names may contain spaces, so the line is
+// split where both halves name the same file, which is always the case unless
+// the file was renamed or copied.
+func parseGitHeader(rest string) gitHeader {
+	oldName, newName, ok := splitGitHeaderNames(rest)
+	if ok {
+		return headerFromNames(oldName, newName)
+	}
+
+	// Unquoted names that differ can't be split on their own; try every space
+	// and accept the first split where both halves agree once their prefixes
+	// are removed
+	for i := 0; i < len(rest); i++ {
+		if rest[i] != ' ' {
+			continue
+		}
+		left, right := rest[:i], rest[i+1:]
+		if left == right {
+			return gitHeader{OldPath: left, NewPath: right, known: true}
+		}
+		leftPrefix, leftPath := splitPrefix(left)
+		rightPrefix, rightPath := splitPrefix(right)
+		if leftPath != "" && leftPath == rightPath {
+			return gitHeader{OldPath: leftPath, NewPath: rightPath, SrcPrefix: leftPrefix, DstPrefix: rightPrefix, known: true}
+		}
+	}
+
+	return gitHeader{}
+}
+
+// splitGitHeaderNames splits a header in which at least one name is quoted
+func splitGitHeaderNames(rest string) (string, string, bool) {
+	if strings.HasPrefix(rest, `"`) {
+		oldName, remainder, err := cutQuoted(rest)
+		if err != nil || !strings.HasPrefix(remainder, " ") {
+			return "", "", false
+		}
+		newName, err := unquotePath(remainder[1:])
+		if err != nil {
+			return "", "", false
+		}
+		return oldName, newName, true
+	}
+
+	if strings.HasSuffix(rest, `"`) {
+		// Find the space that starts the quoted new name
+		for i := strings.Index(rest, ` "`); i != -1; {
+			newName, remainder, err := cutQuoted(rest[i+1:])
+			if err == nil && remainder == "" {
+				return rest[:i], newName, true
+			}
+			next := strings.Index(rest[i+1:], ` "`)
+			if next == -1 {
+				break
+			}
+			i += next + 1
+		}
+	}
+
+	return "", "", false
+}
+
+// headerFromNames builds a header from two complete names whose prefixes are not yet known
+func headerFromNames(oldName, newName string) gitHeader {
+	if oldName == newName {
+		return gitHeader{OldPath: oldName, NewPath: newName, known: true}
+	}
+	oldPrefix, oldPath := splitPrefix(oldName)
+	newPrefix, newPath := splitPrefix(newName)
+	if oldPath != "" && oldPath == newPath {
+		return gitHeader{OldPath: oldPath, NewPath: newPath, SrcPrefix: oldPrefix, DstPrefix: newPrefix, known: true}
+	}
+	// A rename with different names; the prefixes are resolved from the
+	// rename/copy headers once the real paths are known
+	return gitHeader{OldPath: oldName, NewPath: newName}
+}
+
+// resolvePrefixes works out the prefixes of a rename or copy header from the exact
+// paths given by the "rename from"/"rename to" (or copy) lines
+func (h *gitHeader) resolvePrefixes(rest, from, to string) {
+	if h.known {
+		return
+	}
+
+	var oldName, newName string
+	if quotedOld, quotedNew, ok := splitGitHeaderNames(rest); ok {
+		oldName, newName = quotedOld, quotedNew
+	} else {
+		// The new name is "<prefix><to>" and the prefix contains no spaces
+		before, ok := strings.CutSuffix(rest, to)
+		if !ok {
+			return
+		}
+		idx := strings.LastIndex(before, " ")
+		if idx == -1 {
+			return
+		}
+		oldName, newName = before[:idx], before[idx+1:]+to
+	}
+
+	srcPrefix, ok := strings.CutSuffix(oldName, from)
+	if !ok {
+		return
+	}
+	dstPrefix, ok := strings.CutSuffix(newName, to)
+	if !ok {
+		return
+	}
+	*h = gitHeader{OldPath: from, NewPath: to, SrcPrefix: srcPrefix, DstPrefix: dstPrefix, known: true}
+}
+
+// stripPrefix removes the header's prefix from a path in a "---" or "+++" line.
+// Without a known prefix it falls back to the default "a/" and "b/" prefixes.
+func (h *gitHeader) stripPrefix(name, prefix, defaultPrefix string) string {
+	if name == devNull {
+		return name
+	}
+	if h.known {
+		return strings.TrimPrefix(name, prefix)
+	}
+	return strings.TrimPrefix(name, defaultPrefix)
+}
+
+// unifiedPaths works out the old and new path of a plain unified diff from the
+// "---" and "+++" names. Like "patch -p1", a leading directory is removed when
+// both names share the rest of the path, e.g. "a/x.go" and "b/x.go" or
+// "orig/x.go" and "new/x.go".
+func unifiedPaths(oldName, newName string) (string, string) {
+	switch {
+	case newName == devNull:
+		return strings.TrimPrefix(oldName, "a/"), devNull
+	case oldName == devNull || oldName == "":
+		return oldName, strings.TrimPrefix(newName, "b/")
+	case oldName == newName:
+		return oldName, newName
+	}
+
+	_, oldPath := splitPrefix(oldName)
+	_, newPath := splitPrefix(newName)
+	if oldPath != "" && oldPath == newPath {
+		return oldPath, newPath
+	}
+	return oldName, newName
+}
+
+// splitPrefix splits a name into its first path component (including the slash) and the rest
+func splitPrefix(name string) (string, string) {
+	idx := strings.Index(name, "/")
+	if idx == -1 {
+		return "", ""
+	}
+	return name[:idx+1], name[idx+1:]
+}
+
+// parseFileHeaderPath extracts the path and label from the value of a "---" or "+++" line.
+// Git appends a tab to names that contain spaces, and other tools add a label after
+// a tab, such as a timestamp or Subversion's "(revision 12)", so an unquoted name
+// ends at the first tab.
+func parseFileHeaderPath(value string) (string, string, error) {
+	if strings.HasPrefix(value, `"`) {
+		name, rest, err := cutQuoted(value)
+		return name, strings.TrimSpace(rest), err
+	}
+	name, label, _ := strings.Cut(value, "\t")
+	return name, strings.TrimSpace(label), nil
+}
+
+// isMissingLabel returns true for the labels Subversion gives the missing side
+// of an added or deleted file
+func isMissingLabel(label string) bool {
+	return label == "(nonexistent)" || label == "(revision 0)"
+}
+
+// unquotePath returns a path from a git header, removing C-style quoting if present.
+// Git quotes names containing spaces at the end, control characters, quotes,
+// backslashes or (with core.quotePath) non-ASCII bytes, which it writes as octal escapes.
+func unquotePath(value string) (string, error) {
+	if !strings.HasPrefix(value, `"`) {
+		return value, nil
+	}
+	name, remainder, err := cutQuoted(value)
+	if err != nil {
+		return "", err
+	}
+	if remainder != "" {
+		return "", fmt.Errorf("unexpected text after quoted path %q", value)
+	}
+	return name, nil
+}
+
+// cutQuoted unquotes the C-quoted string at the start of value and returns it
+// together with the text that follows the closing quote
+func cutQuoted(value string) (string, string, error) {
+	for i := 1; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++ // Skip the escaped character
+		case '"':
+			name, err := strconv.Unquote(value[:i+1])
+			if err != nil {
+				return "", "", fmt.Errorf("invalid quoted path %q: %w", value[:i+1], err)
+			}
+			return name, value[i+1:], nil
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted path %q", value)
+}
@@ -0,0 +1,113 @@
+package hunk
+
+import "testing"
+
+func TestUnquotePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "unquoted", value: "dir/file.go", want: "dir/file.go"},
+		{name: "octal escapes", value: `"b/dir/na\303\257ve.go"`, want: "b/dir/naïve.go"},
+		{name: "escaped quote and backslash", value: `"a/say \"hi\"\\.txt"`, want: `a/say "hi"\.txt`},
+		{name: "control characters", value: `"a/tab\there\n"`, want: "a/tab\there\n"},
+		{name: "unterminated", value: `"a/file.go`, wantErr: true},
+		{name: "trailing text", value: `"a/file.go" extra`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := unquotePath(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error but got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseGitHeader(t *testing.T) {
+	tests := []struct {
+		name      string
+		rest      string
+		want      gitHeader
+		wantKnown bool
+	}{
+		{
+			name:      "default prefixes",
+			rest:      "a/dir/file.go b/dir/file.go",
+			want:      gitHeader{OldPath: "dir/file.go", NewPath: "dir/file.go", SrcPrefix: "a/", DstPrefix: "b/"},
+			wantKnown: true,
+		},
+		{
+			name:      "mnemonic prefixes",
+			rest:      "i/file.go w/file.go",
+			want:      gitHeader{OldPath: "file.go", NewPath: "file.go", SrcPrefix: "i/", DstPrefix: "w/"},
+			wantKnown: true,
+		},
+		{
+			name:      "no prefix",
+			rest:      "dir/file.go dir/file.go",
+			want:      gitHeader{OldPath: "dir/file.go", NewPath: "dir/file.go"},
+			wantKnown: true,
+		},
+		{
+			name:      "spaces in unquoted path",
+			rest:      "a/my dir/my file.go b/my dir/my file.go",
+			want:      gitHeader{OldPath: "my dir/my file.go", NewPath: "my dir/my file.go", SrcPrefix: "a/", DstPrefix: "b/"},
+			wantKnown: true,
+		},
+		{
+			name:      "both quoted",
+			rest:      `"a/na\303\257ve.go" "b/na\303\257ve.go"`,
+			want:      gitHeader{OldPath: "naïve.go", NewPath: "naïve.go", SrcPrefix: "a/", DstPrefix: "b/"},
+			wantKnown: true,
+		},
+		{
+			name:      "only new name quoted",
+			rest:      `a/old.go "b/new \"name\".go"`,
+			want:      gitHeader{OldPath: "a/old.go", NewPath: `b/new "name".go`},
+			wantKnown: false,
+		},
+		{
+			name:      "unquoted rename",
+			rest:      "a/old.go b/new.go",
+			want:      gitHeader{},
+			wantKnown: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseGitHeader(tt.rest)
+			if got.known != tt.wantKnown {
+				t.Errorf("expected known=%v, got %v", tt.wantKnown, got.known)
+			}
+			got.known = false
+			if got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGitHeader_ResolvePrefixes(t *testing.T) {
+	rest := "src/old name.go dst/new name.go"
+	header := parseGitHeader(rest)
+	header.resolvePrefixes(rest, "old name.go", "new name.go")
+
+	want := gitHeader{OldPath: "old name.go", NewPath: "new name.go", SrcPrefix: "src/", DstPrefix: "dst/", known: true}
+	if header != want {
+		t.Errorf("expected %+v, got %+v", want, header)
+	}
+}
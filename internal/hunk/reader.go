@@ -0,0 +1,79 @@
+package hunk
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Reader parses a git diff from an io.Reader one file at a time.
+// Lines are read without a length limit, and the diff is never held in memory as a whole.
+type Reader struct {
+	reader *bufio.Reader
+	parser *diffParser
+	done   bool
+	err    error
+}
+
+// NewReader creates a Reader that parses the git diff read from r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		reader: bufio.NewReader(r),
+		parser: newDiffParser(FormatGit),
+	}
+}
+
+// NewFormatReader creates a Reader that parses a diff in the given format read from r
+func NewFormatReader(r io.Reader, format Format) (*Reader, error) {
+	if _, err := ParseFormat(string(format)); err != nil {
+		return nil, err
+	}
+	return &Reader{
+		reader: bufio.NewReader(r),
+		parser: newDiffParser(format),
+	}, nil
+}
+
+// Next returns a ParseResult holding the changes of the next file in the diff.
+// A renamed file's result also holds its rename, and a binary, mode-only or
+// submodule change holds only its skipped file entry.
+// Next returns io.EOF once the whole diff has been read.
+func (r *Reader) Next() (*ParseResult, error) {
+	for len(r.parser.pending) == 0 {
+		if r.err != nil {
+			return nil, r.err
+		}
+		if r.done {
+			return nil, io.EOF
+		}
+		r.err = r.readLine()
+	}
+
+	file := r.parser.pending[0]
+	r.parser.pending = r.parser.pending[1:]
+	return file, nil
+}
+
+// readLine feeds the next line of input to the parser, finishing the parse at end of input
+func (r *Reader) readLine() error {
+	line, err := r.reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("error reading diff: %w", err)
+	}
+
+	if line != "" {
+		// Strip the line ending, including the carriage return of CRLF diffs
+		line = strings.TrimSuffix(line, "\n")
+		line = strings.TrimSuffix(line, "\r")
+		if parseErr := r.parser.parseLine(line); parseErr != nil {
+			return parseErr
+		}
+	}
+
+	if err == io.EOF {
+		r.done = true
+		return r.parser.finish()
+	}
+	return nil
+}
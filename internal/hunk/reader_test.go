@@ -0,0 +1,132 @@
+package hunk
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReader_Next(t *testing.T) {
+	diffOutput := `diff --git a/first.go b/first.go
+index 1234567..abcdefg 100644
+--- a/first.go
++++ b/first.go
+@@ -1,1 +1,2 @@
+ package first
++var x = 1
+diff --git a/logo.png b/logo.png
+index 1111111..2222222 100644
+Binary files a/logo.png and b/logo.png differ
+diff --git a/old.go b/second.go
+similarity index 90%
+rename from old.go
+rename to second.go
+index 3333333..4444444 100644
+--- a/old.go
++++ b/second.go
+@@ -1,1 +1,1 @@
+-package old
++package second
+`
+
+	reader := NewReader(strings.NewReader(diffOutput))
+
+	first, err := reader.Next()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if files := first.GetChangedFiles(); len(files) != 1 || files[0] != "first.go" {
+		t.Errorf("expected first result to hold first.go, got %v", files)
+	}
+	if first.GetLineMapForFile("first.go") == nil {
+		t.Error("expected first result to have a line map")
+	}
+
+	skipped, err := reader.Next()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !skipped.IsSkippedFile("logo.png") || skipped.HasChanges() {
+		t.Errorf("expected second result to hold only the skipped binary file")
+	}
+
+	renamed, err := reader.Next()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if renamed.OldPath("second.go") != "old.go" {
+		t.Errorf("expected third result to hold the rename, got %v", renamed.Renames)
+	}
+
+	if _, err := reader.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestReader_Error(t *testing.T) {
+	diffOutput := `diff --git a/first.go b/first.go
+--- a/first.go
++++ b/first.go
+@@ -1,1 +1,3 @@
+ package first
++var x = 1
+diff --git a/second.go b/second.go
+`
+
+	reader := NewReader(strings.NewReader(diffOutput))
+	_, err := reader.Next()
+	if err == nil || !strings.Contains(err.Error(), "truncated diff") {
+		t.Fatalf("expected truncated diff error, got %v", err)
+	}
+	if _, again := reader.Next(); !errors.Is(again, err) {
+		t.Errorf("expected the same error on later calls, got %v", again)
+	}
+}
+
+func TestParseGitDiffReader_LongLines(t *testing.T) {
+	// A minified bundle line far beyond bufio.Scanner's 64KB default
+	longLine := strings.Repeat("x", 1<<20)
+	diffOutput := "diff --git a/bundle.js b/bundle.js\r\n" +
+		"index 1234567..abcdefg 100644\r\n" +
+		"--- a/bundle.js\r\n" +
+		"+++ b/bundle.js\r\n" +
+		"@@ -1,1 +1,2 @@\r\n" +
+		" // header\r\n" +
+		"+" + longLine + "\r\n"
+
+	result, err := ParseGitDiffReader(strings.NewReader(diffOutput))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if added := result.GetAddedLinesForFile("bundle.js"); len(added) != 1 || !added[2] {
+		t.Errorf("expected added line 2, got %v", added)
+	}
+	hunks := result.GetHunksForFile("bundle.js")
+	if len(hunks) != 1 || hunks[0].Lines[1].Content != longLine {
+		t.Error("expected the long line to be kept intact without its line ending")
+	}
+}
+
+func TestParseGitDiffReader_NoTrailingNewline(t *testing.T) {
+	diffOutput := `--- a/first.go
++++ b/first.go
+@@ -1,1 +1,2 @@
+ package first
++var x = 1
+--- a/second.go
++++ b/second.go
+@@ -1,1 +1,2 @@
+ package second
++var y = 2`
+
+	result, err := ParseGitDiffReader(strings.NewReader(diffOutput))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, file := range []string{"first.go", "second.go"} {
+		if added := result.GetAddedLinesForFile(file); len(added) != 1 || !added[2] {
+			t.Errorf("expected added line 2 in %s, got %v", file, added)
+		}
+	}
+}
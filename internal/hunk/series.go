@@ -0,0 +1,548 @@
+package hunk
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Patch is one patch of a series, e.g. one message of a "git format-patch" mbox
+type Patch struct {
+	// Commit is the commit ID from the mbox "From " line, empty for plain diffs
+	Commit string
+	// Author is the "From:" header of the message
+	Author string
+	// Subject is the commit subject with the "[PATCH n/m]" tag removed,
+	// or the file name for a patch without mail headers
+	Subject string
+	// Result holds the changes of this patch alone, relative to the tree the
+	// previous patches produced
+	Result *ParseResult
+}
+
+// Series is a sequence of patches applied one after another
+type Series struct {
+	Patches []*Patch
+	// Result holds the net change of the whole series relative to the tree the
+	// first patch applies to, with line numbers of the tree the last patch produces
+	Result *ParseResult
+	// origins maps file path -> new line number -> index of the patch that added the line
+	origins map[string]map[int]int
+}
+
+// PatchForLine returns the index of the patch that added a line of the net result
+func (s *Series) PatchForLine(file string, line int) (int, bool) {
+	index, ok := s.origins[file][line]
+	return index, ok
+}
+
+// IsMbox returns true if the input starts like an mbox, as written by "git format-patch"
+func IsMbox(r *bufio.Reader) bool {
+	// The envelope line is short; Peek returns what there is if the input is shorter
+	head, _ := r.Peek(256)
+	line, _, _ := strings.Cut(string(head), "\n")
+	return isMboxEnvelope(strings.TrimSuffix(line, "\r"))
+}
+
+// isMboxEnvelope returns true if line is the "From " line that starts a message
+// written by "git format-patch": the commit ID and an asctime date.
+// Format: From 1a2b3c4d... Mon Sep 17 00:00:00 2001
+func isMboxEnvelope(line string) bool {
+	rest, ok := strings.CutPrefix(line, "From ")
+	if !ok {
+		return false
+	}
+	commit, date, ok := strings.Cut(rest, " ")
+	if !ok || (len(commit) != 40 && len(commit) != 64) || strings.Trim(commit, "0123456789abcdef") != "" {
+		return false
+	}
+	_, err := time.Parse(time.ANSIC, strings.TrimSpace(date))
+	return err == nil
+}
+
+// unescapeMboxLine undoes the mboxrd quoting of body lines: a line starting
+// with one or more ">" followed by "From " loses one ">"
+func unescapeMboxLine(line string) string {
+	if strings.HasPrefix(line, ">") && strings.HasPrefix(strings.TrimLeft(line, ">"), "From ") {
+		return line[1:]
+	}
+	return line
+}
+
+// ParseMbox parses a patch series stored as an mbox, such as the output of
+// "git format-patch --stdout", and composes the patches in order
+func ParseMbox(r io.Reader, format Format) (*Series, error) {
+	patches, err := readMbox(r, format, 0)
+	if err != nil {
+		return nil, err
+	}
+	return NewSeries(patches)
+}
+
+// ParsePatchDir parses the ".patch" files of a directory, in file name order as
+// "git format-patch" numbers them, and composes them into a series. Each file
+// may be a plain diff or a mail message.
+func ParsePatchDir(dir string, format Format) (*Series, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read patch directory: %w", err)
+	}
+
+	patches := make([]*Patch, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".patch" {
+			continue
+		}
+		filePatches, err := readPatchFile(filepath.Join(dir, entry.Name()), format, len(patches))
+		if err != nil {
+			return nil, err
+		}
+		patches = append(patches, filePatches...)
+	}
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("no .patch files found in %s", dir)
+	}
+	return NewSeries(patches)
+}
+
+// readPatchFile reads the patches of a single file; first is the index of its first patch in the series
+func readPatchFile(path string, format Format, first int) ([]*Patch, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open patch: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	if IsMbox(reader) {
+		return readMbox(reader, format, first)
+	}
+
+	result, err := ParseDiffReader(reader, format)
+	if err != nil {
+		return nil, fmt.Errorf("patch %d (%s): %w", first+1, filepath.Base(path), err)
+	}
+	return []*Patch{{Subject: filepath.Base(path), Result: result}}, nil
+}
+
+// readMbox splits an mbox into messages and parses the diff in each message body;
+// first is the index of the first message in the series, for error messages
+func readMbox(r io.Reader, format Format, first int) ([]*Patch, error) {
+	reader := bufio.NewReader(r)
+	patches := make([]*Patch, 0)
+
+	var patch *Patch
+	var parser *diffParser
+	var inHeaders bool
+	var lastHeader *string
+
+	// finishPatch completes the parse of the current message
+	finishPatch := func() error {
+		if patch == nil {
+			return nil
+		}
+		if err := parser.finish(); err != nil {
+			return fmt.Errorf("patch %d (%s): %w", first+len(patches), patch.Subject, err)
+		}
+		patch.Result = parser.drain()
+		return nil
+	}
+
+	for {
+		line, readErr := reader.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, fmt.Errorf("error reading mbox: %w", readErr)
+		}
+		if line == "" && readErr == io.EOF {
+			break
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		switch {
+		case isMboxEnvelope(line) && (parser == nil || !parser.inHunk()):
+			// Only a complete envelope starts a message, so that a commit message
+			// line starting with "From " does not split the patch
+			if err := finishPatch(); err != nil {
+				return nil, err
+			}
+			patch = &Patch{}
+			if fields := strings.Fields(line); len(fields) > 1 {
+				patch.Commit = fields[1]
+			}
+			patches = append(patches, patch)
+			parser = newDiffParser(format)
+			inHeaders = true
+			lastHeader = nil
+		case patch == nil:
+			return nil, fmt.Errorf("not an mbox: expected a \"From \" line, got %q", line)
+		case inHeaders:
+			if line == "" {
+				inHeaders = false
+				patch.Subject = cleanSubject(patch.Subject)
+				patch.Author = decodeHeader(patch.Author)
+				continue
+			}
+			if (line[0] == ' ' || line[0] == '\t') && lastHeader != nil {
+				// Folded header continuation
+				*lastHeader += " " + strings.TrimSpace(line)
+				continue
+			}
+			lastHeader = nil
+			if value, ok := strings.CutPrefix(line, "Subject: "); ok {
+				patch.Subject = value
+				lastHeader = &patch.Subject
+			} else if value, ok := strings.CutPrefix(line, "From: "); ok {
+				patch.Author = value
+				lastHeader = &patch.Author
+			}
+		default:
+			if err := parser.parseLine(unescapeMboxLine(line)); err != nil {
+				return nil, fmt.Errorf("patch %d (%s): %w", first+len(patches), patch.Subject, err)
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	if patch == nil {
+		return nil, fmt.Errorf("not an mbox: no messages found")
+	}
+	if err := finishPatch(); err != nil {
+		return nil, err
+	}
+	return patches, nil
+}
+
+// cleanSubject decodes a Subject header and removes the "[PATCH n/m]" tag
+func cleanSubject(subject string) string {
+	subject = decodeHeader(subject)
+	if strings.HasPrefix(subject, "[") {
+		if _, rest, ok := strings.Cut(subject, "]"); ok {
+			subject = rest
+		}
+	}
+	return strings.TrimSpace(subject)
+}
+
+// decodeHeader decodes MIME encoded-words such as "=?UTF-8?q?Ren=C3=A9?="
+func decodeHeader(value string) string {
+	decoded, err := new(mime.WordDecoder).DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// drain returns every change parsed so far as a single result
+func (p *diffParser) drain() *ParseResult {
+	result := newParseResult()
+	for _, file := range p.pending {
+		result.merge(file)
+	}
+	p.pending = nil
+	return result
+}
+
+// seriesLine is a line of a file as the patches of a series leave it
+type seriesLine struct {
+	patch   int    // Index of the patch that added the line, -1 for base lines
+	base    int    // Line number in the base version, for base lines
+	content string // Line text, if a patch showed it
+	known   bool   // content is set
+	section string // Section heading of the hunk that added or removed the line
+}
+
+// seriesFile tracks a file through the patches of a series.
+// Lines past the end of lines are base lines that no patch has touched yet.
+type seriesFile struct {
+	basePath string // Path in the base version, empty for files the series created
+	copied   bool   // The file was copied from basePath rather than renamed
+	lines    []seriesLine
+	tailBase int                // Base line number of the first line after lines
+	removed  map[int]seriesLine // Base line number -> removed base line
+}
+
+// newSeriesFile creates the state of a file; basePath is empty for files the series created
+func newSeriesFile(basePath string) *seriesFile {
+	return &seriesFile{
+		basePath: basePath,
+		tailBase: 1,
+		removed:  make(map[int]seriesLine),
+	}
+}
+
+// clone copies the state of a file, for copies made by a patch
+func (f *seriesFile) clone() *seriesFile {
+	c := &seriesFile{
+		basePath: f.basePath,
+		lines:    append([]seriesLine(nil), f.lines...),
+		tailBase: f.tailBase,
+		removed:  make(map[int]seriesLine),
+	}
+	for line, removed := range f.removed {
+		c.removed[line] = removed
+	}
+	return c
+}
+
+// ensure makes sure the first n lines are held explicitly
+func (f *seriesFile) ensure(n int) bool {
+	for len(f.lines) < n {
+		if f.basePath == "" {
+			// A file created by the series has no base lines to extend into
+			return false
+		}
+		f.lines = append(f.lines, seriesLine{patch: -1, base: f.tailBase})
+		f.tailBase++
+	}
+	return true
+}
+
+// apply applies the hunks of a patch, whose line numbers refer to the current state of the file
+func (f *seriesFile) apply(patch int, path string, hunks []*Hunk) error {
+	lines := make([]seriesLine, 0, len(f.lines))
+	cursor := 0 // Index in f.lines of the next line of the current version
+
+	for _, h := range hunks {
+		// A hunk that removes nothing is placed after line OldStart
+		start := h.OldStart - 1
+		if h.OldCount == 0 {
+			start = h.OldStart
+		}
+		if start < cursor || !f.ensure(start) {
+			return fmt.Errorf("patch %d does not apply to %s: hunk at line %d is out of place", patch+1, path, h.OldStart)
+		}
+		lines = append(lines, f.lines[cursor:start]...)
+		cursor = start
+
+		for _, line := range h.Lines {
+			if line.OldLine == 0 {
+				// Added line
+				lines = append(lines, seriesLine{patch: patch, content: line.Content, known: true, section: h.Section})
+				continue
+			}
+
+			if !f.ensure(cursor + 1) {
+				return fmt.Errorf("patch %d does not apply to %s: line %d is past the end of the file", patch+1, path, cursor+1)
+			}
+			current := f.lines[cursor]
+			if current.known && current.content != line.Content {
+				return fmt.Errorf("patch %d does not apply to %s: line %d is %q, expected %q",
+					patch+1, path, cursor+1, current.content, line.Content)
+			}
+			current.content = line.Content
+			current.known = true
+			cursor++
+
+			if line.NewLine != 0 {
+				// Context line
+				lines = append(lines, current)
+				continue
+			}
+			// Removed line; lines added by earlier patches simply disappear
+			if current.patch < 0 {
+				current.section = h.Section
+				f.removed[current.base] = current
+			}
+		}
+	}
+
+	f.lines = append(lines, f.lines[cursor:]...)
+	return nil
+}
+
+// hunks builds the net hunks of the file without context lines. A change block
+// lies between two consecutive unchanged base lines and holds the base lines
+// removed between them followed by the lines the series added there.
+func (f *seriesFile) hunks(path string) []*Hunk {
+	removed := make([]int, 0, len(f.removed))
+	for line := range f.removed {
+		removed = append(removed, line)
+	}
+	sort.Ints(removed)
+
+	hunks := make([]*Hunk, 0)
+	var added []int // Indexes in f.lines of the added lines of the current block
+	prevBase := 0   // Base line number of the last unchanged line
+	prevNew := 0    // New line number of the last unchanged line
+
+	closeBlock := func(nextBase int) {
+		var blockRemoved []int
+		for len(removed) > 0 && removed[0] < nextBase {
+			blockRemoved = append(blockRemoved, removed[0])
+			removed = removed[1:]
+		}
+		if len(blockRemoved) == 0 && len(added) == 0 {
+			return
+		}
+
+		h := &Hunk{
+			File:     path,
+			OldStart: prevBase,
+			OldCount: len(blockRemoved),
+			NewStart: prevNew,
+			NewCount: len(added),
+		}
+		if len(blockRemoved) > 0 {
+			h.OldStart = blockRemoved[0]
+			h.Section = f.removed[blockRemoved[0]].section
+		}
+		if len(added) > 0 {
+			h.NewStart = added[0] + 1
+			if h.Section == "" {
+				h.Section = f.lines[added[0]].section
+			}
+		}
+		for _, line := range blockRemoved {
+			h.addLine(Line{Kind: LineRemoved, OldLine: line, Content: f.removed[line].content})
+		}
+		for _, i := range added {
+			h.addLine(Line{Kind: LineAdded, NewLine: i + 1, Content: f.lines[i].content})
+		}
+		hunks = append(hunks, h)
+		added = added[:0]
+	}
+
+	for i, line := range f.lines {
+		if line.patch >= 0 {
+			added = append(added, i)
+			continue
+		}
+		closeBlock(line.base)
+		prevBase = line.base
+		prevNew = i + 1
+	}
+	// Removed lines always lie before tailBase, which no patch has reached
+	closeBlock(f.tailBase)
+
+	return hunks
+}
+
+// NewSeries applies the patches in order and computes their net result. Line
+// numbers in each patch refer to the tree left by the patches before it, so the
+// lines of later patches are mapped back through the earlier ones.
+func NewSeries(patches []*Patch) (*Series, error) {
+	files := make(map[string]*seriesFile)
+	deleted := make(map[string]bool)
+	skipped := make(map[string]*SkippedFile)
+
+	for i, patch := range patches {
+		r := patch.Result
+
+		// Follow renames and copies before applying the hunks, which use the new path
+		for newPath, oldPath := range r.Copies {
+			source, ok := files[oldPath]
+			if !ok {
+				source = newSeriesFile(oldPath)
+				files[oldPath] = source
+			}
+			files[newPath] = source.clone()
+			files[newPath].copied = true
+		}
+		for newPath, oldPath := range r.Renames {
+			file, ok := files[oldPath]
+			if !ok {
+				file = newSeriesFile(oldPath)
+			}
+			delete(files, oldPath)
+			files[newPath] = file
+		}
+		for path := range r.DeletedFiles {
+			if file, ok := files[path]; ok {
+				delete(files, path)
+				if file.basePath != "" && !file.copied {
+					deleted[file.basePath] = true
+				}
+			} else {
+				deleted[path] = true
+			}
+			delete(skipped, path)
+		}
+
+		for _, path := range sortedFiles(r) {
+			file, ok := files[path]
+			if !ok {
+				if r.NewFiles[path] {
+					file = newSeriesFile("")
+				} else {
+					file = newSeriesFile(path)
+				}
+				files[path] = file
+			}
+			if err := file.apply(i, path, r.Hunks[path]); err != nil {
+				return nil, err
+			}
+		}
+
+		for path, skip := range r.SkippedFiles {
+			skipped[path] = skip
+		}
+	}
+
+	series := &Series{
+		Patches: patches,
+		Result:  newParseResult(),
+		origins: make(map[string]map[int]int),
+	}
+	result := series.Result
+
+	for path, file := range files {
+		hunks := file.hunks(path)
+		moved := file.basePath != "" && file.basePath != path
+		if len(hunks) == 0 && !moved && file.basePath != "" {
+			// The series left the file as it was
+			continue
+		}
+
+		result.addFile(path, hunks, file.basePath == "")
+		series.origins[path] = make(map[int]int)
+		for i, line := range file.lines {
+			if line.patch >= 0 {
+				series.origins[path][i+1] = line.patch
+			}
+		}
+
+		switch {
+		case file.copied:
+			result.Copies[path] = file.basePath
+		case moved:
+			result.Renames[path] = file.basePath
+		}
+	}
+
+	for path := range deleted {
+		if _, exists := files[path]; !exists {
+			result.DeletedFiles[path] = true
+		}
+	}
+	for path, skip := range skipped {
+		if _, changed := result.ChangedLines[path]; !changed {
+			result.SkippedFiles[path] = skip
+		}
+	}
+
+	return series, nil
+}
+
+// sortedFiles returns the files with hunks or created by a patch, sorted by path
+func sortedFiles(r *ParseResult) []string {
+	files := make([]string, 0, len(r.Hunks))
+	for path := range r.Hunks {
+		files = append(files, path)
+	}
+	for path := range r.NewFiles {
+		if _, ok := r.Hunks[path]; !ok {
+			files = append(files, path)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
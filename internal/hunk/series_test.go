@@ -0,0 +1,362 @@
+package hunk
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// seriesMbox is a three patch series as written by git format-patch --stdout.
+// The second patch removes a line the first one added and shifts the lines
+// below it, and the third renames the file the first one created.
+const seriesMbox = `From 1111111111111111111111111111111111111111 Mon Sep 17 00:00:00 2001
+From: =?UTF-8?q?Ren=C3=A9e?= <renee@example.com>
+Date: Thu, 1 Oct 2026 12:00:00 +0000
+Subject: [PATCH 1/3] Add helpers for the app and a
+ much longer subject
+
+Body of the first commit.
+---
+ app.go  | 2 ++
+ util.go | 2 ++
+ 2 files changed, 4 insertions(+)
+
+diff --git a/app.go b/app.go
+index 1234567..2345678 100644
+--- a/app.go
++++ b/app.go
+@@ -2,1 +2,3 @@ func a() {
+ l2
++p1a
++p1b
+diff --git a/util.go b/util.go
+new file mode 100644
+index 0000000..3456789
+--- /dev/null
++++ b/util.go
+@@ -0,0 +1,2 @@
++u1
++u2
+-- 
+2.44.0
+
+From 2222222222222222222222222222222222222222 Mon Sep 17 00:00:00 2001
+From: Ada <ada@example.com>
+Date: Fri, 2 Oct 2026 12:00:00 +0000
+Subject: [PATCH 2/3] Rework app
+
+---
+ app.go | 3 +--
+ 1 file changed, 1 insertion(+), 2 deletions(-)
+
+diff --git a/app.go b/app.go
+index 2345678..4567890 100644
+--- a/app.go
++++ b/app.go
+@@ -3,2 +3,1 @@ func a() {
+-p1a
+ p1b
+@@ -9,3 +8,3 @@ func b() {
+ l7
+-l8
++p2
+ l9
+-- 
+2.44.0
+
+From 3333333333333333333333333333333333333333 Mon Sep 17 00:00:00 2001
+From: Ada <ada@example.com>
+Date: Sat, 3 Oct 2026 12:00:00 +0000
+Subject: [PATCH 3/3] Rename util
+
+---
+diff --git a/util.go b/helpers.go
+similarity index 80%
+rename from util.go
+rename to helpers.go
+index 3456789..5678901 100644
+--- a/util.go
++++ b/helpers.go
+@@ -2,1 +2,2 @@
+ u2
++p3
+-- 
+2.44.0
+`
+
+func TestParseMbox(t *testing.T) {
+	series, err := ParseMbox(strings.NewReader(seriesMbox), FormatGit)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(series.Patches) != 3 {
+		t.Fatalf("expected 3 patches, got %d", len(series.Patches))
+	}
+	first := series.Patches[0]
+	if first.Commit != "1111111111111111111111111111111111111111" {
+		t.Errorf("unexpected commit %q", first.Commit)
+	}
+	if first.Subject != "Add helpers for the app and a much longer subject" {
+		t.Errorf("unexpected subject %q", first.Subject)
+	}
+	if first.Author != "Renée <renee@example.com>" {
+		t.Errorf("unexpected author %q", first.Author)
+	}
+	if !first.Result.IsNewFile("util.go") {
+		t.Error("expected the first patch on its own to add util.go")
+	}
+
+	result := series.Result
+	if got := result.GetAddedLinesForFile("app.go"); !reflect.DeepEqual(got, map[int]bool{3: true, 9: true}) {
+		t.Errorf("expected net added lines 3 and 9 in app.go, got %v", got)
+	}
+	if got := result.GetRemovedLinesForFile("app.go"); !reflect.DeepEqual(got, map[int]bool{8: true}) {
+		t.Errorf("expected base line 8 removed from app.go, got %v", got)
+	}
+	if !result.IsModifiedFile("app.go") {
+		t.Error("expected app.go to be modified")
+	}
+
+	// The renamed file is still new relative to the base of the series
+	if _, ok := result.ChangedLines["util.go"]; ok {
+		t.Error("expected util.go to be reported under its final name")
+	}
+	if !result.IsNewFile("helpers.go") {
+		t.Error("expected helpers.go to be new")
+	}
+	if got := result.GetAddedLinesForFile("helpers.go"); !reflect.DeepEqual(got, map[int]bool{1: true, 2: true, 3: true}) {
+		t.Errorf("expected lines 1-3 added to helpers.go, got %v", got)
+	}
+
+	hunks := result.GetHunksForFile("app.go")
+	if len(hunks) != 2 {
+		t.Fatalf("expected 2 net hunks in app.go, got %d", len(hunks))
+	}
+	if h := hunks[0]; h.OldStart != 2 || h.OldCount != 0 || h.NewStart != 3 || h.NewCount != 1 || h.Section != "func a() {" {
+		t.Errorf("unexpected first hunk %+v", h)
+	}
+	if h := hunks[1]; h.OldStart != 8 || h.OldCount != 1 || h.NewStart != 9 || h.NewCount != 1 || h.Section != "func b() {" {
+		t.Errorf("unexpected second hunk %+v", h)
+	}
+	if old, ok := result.OldLineNumber("app.go", 9); !ok || old != 8 {
+		t.Errorf("expected new line 9 to replace base line 8, got %d, %v", old, ok)
+	}
+	if old, ok := result.OldLineNumber("app.go", 10); !ok || old != 9 {
+		t.Errorf("expected new line 10 to map to base line 9, got %d, %v", old, ok)
+	}
+
+	origins := map[string]map[int]int{
+		"app.go":     {3: 0, 9: 1},
+		"helpers.go": {1: 0, 2: 0, 3: 2},
+	}
+	for file, lines := range origins {
+		for line, want := range lines {
+			if got, ok := series.PatchForLine(file, line); !ok || got != want {
+				t.Errorf("expected %s:%d to come from patch %d, got %d (%v)", file, line, want, got, ok)
+			}
+		}
+	}
+	if _, ok := series.PatchForLine("app.go", 4); ok {
+		t.Error("expected unchanged lines to have no patch")
+	}
+}
+
+func TestNewSeries_DeletedAndRevertedFiles(t *testing.T) {
+	patches := []*Patch{
+		{Subject: "add and modify", Result: mustParse(t, `diff --git a/tmp.go b/tmp.go
+new file mode 100644
+--- /dev/null
++++ b/tmp.go
+@@ -0,0 +1,1 @@
++t1
+diff --git a/app.go b/app.go
+--- a/app.go
++++ b/app.go
+@@ -1,1 +1,2 @@
+ l1
++x
+`)},
+		{Subject: "drop both again", Result: mustParse(t, `diff --git a/tmp.go b/tmp.go
+deleted file mode 100644
+--- a/tmp.go
++++ /dev/null
+@@ -1,1 +0,0 @@
+-t1
+diff --git a/app.go b/app.go
+--- a/app.go
++++ b/app.go
+@@ -1,2 +1,1 @@
+ l1
+-x
+diff --git a/old.go b/old.go
+deleted file mode 100644
+--- a/old.go
++++ /dev/null
+@@ -1,1 +0,0 @@
+-o1
+`)},
+	}
+
+	series, err := NewSeries(patches)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if files := series.Result.GetChangedFiles(); len(files) != 0 {
+		t.Errorf("expected no net changed files, got %v", files)
+	}
+	if !series.Result.IsDeletedFile("old.go") {
+		t.Error("expected old.go to be deleted")
+	}
+	if series.Result.IsDeletedFile("tmp.go") {
+		t.Error("expected tmp.go, which the series created, not to count as deleted")
+	}
+}
+
+func TestNewSeries_DoesNotApply(t *testing.T) {
+	patches := []*Patch{
+		{Subject: "first", Result: mustParse(t, `diff --git a/app.go b/app.go
+--- a/app.go
++++ b/app.go
+@@ -1,1 +1,2 @@
+ l1
++added
+`)},
+		{Subject: "second", Result: mustParse(t, `diff --git a/app.go b/app.go
+--- a/app.go
++++ b/app.go
+@@ -2,1 +2,1 @@
+-something else
++replacement
+`)},
+	}
+
+	_, err := NewSeries(patches)
+	if err == nil || !strings.Contains(err.Error(), "patch 2 does not apply to app.go") {
+		t.Errorf("expected an apply error, got %v", err)
+	}
+}
+
+func TestParsePatchDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		// Mail messages from git format-patch -o
+		"0001-first.patch": `From 1111111111111111111111111111111111111111 Mon Sep 17 00:00:00 2001
+From: Ada <ada@example.com>
+Subject: [PATCH 1/2] First
+
+---
+diff --git a/app.go b/app.go
+--- a/app.go
++++ b/app.go
+@@ -1,1 +1,2 @@
+ l1
++first
+`,
+		// A plain diff without mail headers
+		"0002-second.patch": `--- a/app.go
++++ b/app.go
+@@ -2,1 +2,2 @@
+ first
++second
+`,
+		"README": "not a patch\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	series, err := ParsePatchDir(dir, FormatAuto)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(series.Patches) != 2 || series.Patches[0].Subject != "First" || series.Patches[1].Subject != "0002-second.patch" {
+		t.Fatalf("unexpected patches %+v", series.Patches)
+	}
+	if got := series.Result.GetAddedLinesForFile("app.go"); !reflect.DeepEqual(got, map[int]bool{2: true, 3: true}) {
+		t.Errorf("expected lines 2-3 added to app.go, got %v", got)
+	}
+	if got, _ := series.PatchForLine("app.go", 3); got != 1 {
+		t.Errorf("expected line 3 to come from the second patch, got %d", got)
+	}
+
+	if _, err := ParsePatchDir(t.TempDir(), FormatAuto); err == nil {
+		t.Error("expected an error for a directory without patches")
+	}
+}
+
+func TestParseMbox_NotAnMbox(t *testing.T) {
+	_, err := ParseMbox(strings.NewReader("diff --git a/x b/x\n"), FormatGit)
+	if err == nil || !strings.Contains(err.Error(), "not an mbox") {
+		t.Errorf("expected a not an mbox error, got %v", err)
+	}
+}
+
+func TestParseMbox_FromLineInMessage(t *testing.T) {
+	mbox := `From 1111111111111111111111111111111111111111 Mon Sep 17 00:00:00 2001
+From: Dev <dev@example.com>
+Subject: [PATCH] Quote the release notes
+
+From the release notes: keep the old behavior.
+>From an mboxrd writer, escaped once
+>>From an mboxrd writer, escaped twice
+---
+diff --git a/app.go b/app.go
+index 1111111..2222222 100644
+--- a/app.go
++++ b/app.go
+@@ -1,1 +1,2 @@
+ package app
++var quoted = true
+-- 
+2.42.0
+`
+
+	series, err := ParseMbox(strings.NewReader(mbox), FormatGit)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(series.Patches) != 1 {
+		t.Fatalf("expected the message body not to start new patches, got %d patches", len(series.Patches))
+	}
+	if got := series.Result.GetAddedLinesForFile("app.go"); !reflect.DeepEqual(got, map[int]bool{2: true}) {
+		t.Errorf("expected added line 2 in app.go, got %v", got)
+	}
+
+	if !IsMbox(bufio.NewReader(strings.NewReader(mbox))) {
+		t.Error("expected a format-patch envelope to be recognized as an mbox")
+	}
+	if IsMbox(bufio.NewReader(strings.NewReader("From the release notes\n"))) {
+		t.Error("expected a line without commit and date not to be taken for an mbox")
+	}
+}
+
+func TestUnescapeMboxLine(t *testing.T) {
+	tests := map[string]string{
+		">From the notes":  "From the notes",
+		">>From the notes": ">From the notes",
+		"> quoted reply":   "> quoted reply",
+		"From the notes":   "From the notes",
+		"+>From added":     "+>From added",
+	}
+	for line, expected := range tests {
+		if got := unescapeMboxLine(line); got != expected {
+			t.Errorf("unescapeMboxLine(%q) = %q, want %q", line, got, expected)
+		}
+	}
+}
+
+// mustParse parses a git diff or fails the test
+func mustParse(t *testing.T, diffOutput string) *ParseResult {
+	t.Helper()
+	result, err := ParseGitDiff(diffOutput)
+	if err != nil {
+		t.Fatalf("failed to parse diff: %v", err)
+	}
+	return result
+}
@@ -0,0 +1,64 @@
+package hunk
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SkipReason explains why a changed file has no lines to analyze
+type SkipReason string
+
+const (
+	// SkipBinary is a binary file; git reports it as changed without line hunks
+	SkipBinary SkipReason = "binary"
+	// SkipModeChange is a file whose permissions changed but whose content did not
+	SkipModeChange SkipReason = "mode-change"
+	// SkipSubmodule is a submodule whose recorded commit changed
+	SkipSubmodule SkipReason = "submodule"
+)
+
+// SkippedFile records a changed file that cannot be analyzed for line coverage
+type SkippedFile struct {
+	Path   string
+	Reason SkipReason
+	// Detail describes the change, e.g. "100644 → 100755" for a mode change
+	// or the old and new commits of a submodule
+	Detail string
+}
+
+// Description returns a short human-readable explanation of why the file was skipped
+func (f *SkippedFile) Description() string {
+	var description string
+	switch f.Reason {
+	case SkipBinary:
+		description = "binary file"
+	case SkipModeChange:
+		description = "mode change only"
+	case SkipSubmodule:
+		description = "submodule update"
+	default:
+		description = string(f.Reason)
+	}
+	if f.Detail == "" {
+		return description
+	}
+	return fmt.Sprintf("%s (%s)", description, f.Detail)
+}
+
+// GetSkippedFiles returns the files that changed but have no lines to analyze, sorted by path
+func (r *ParseResult) GetSkippedFiles() []*SkippedFile {
+	files := make([]*SkippedFile, 0, len(r.SkippedFiles))
+	for _, file := range r.SkippedFiles {
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files
+}
+
+// IsSkippedFile returns true if the file changed but could not be analyzed
+func (r *ParseResult) IsSkippedFile(file string) bool {
+	_, skipped := r.SkippedFiles[file]
+	return skipped
+}
@@ -0,0 +1,159 @@
+package hunk
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// binaryCheckSize is how much of a file is checked for NUL bytes, as git does
+const binaryCheckSize = 8000
+
+// treeFile is a regular file or symlink found while walking a tree
+type treeFile struct {
+	path string // Absolute or tree-relative path on disk
+	mode string // git file mode: 100644, 100755 or 120000
+}
+
+// DiffTrees compares two directory trees, such as checkouts of the base and head
+// sources, without git. Files are compared line by line with Myers' algorithm
+// and the result is the same as parsing "git diff --no-renames" between the trees.
+// Version control metadata (.git, .hg, .svn) is ignored.
+func DiffTrees(oldDir, newDir string) (*ParseResult, error) {
+	oldFiles, err := walkTree(oldDir)
+	if err != nil {
+		return nil, err
+	}
+	newFiles, err := walkTree(newDir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := newParseResult()
+	for _, path := range sortedPaths(oldFiles, newFiles) {
+		oldFile, inOld := oldFiles[path]
+		newFile, inNew := newFiles[path]
+		if !inNew {
+			result.DeletedFiles[path] = true
+			continue
+		}
+
+		var oldContent []byte
+		if inOld {
+			oldContent, err = readTreeFile(oldFile)
+			if err != nil {
+				return nil, err
+			}
+		}
+		newContent, err := readTreeFile(newFile)
+		if err != nil {
+			return nil, err
+		}
+
+		if inOld && bytes.Equal(oldContent, newContent) {
+			if oldFile.mode != newFile.mode {
+				result.SkippedFiles[path] = &SkippedFile{Path: path, Reason: SkipModeChange, Detail: oldFile.mode + " → " + newFile.mode}
+			}
+			continue
+		}
+		if isBinary(oldContent) || isBinary(newContent) {
+			result.SkippedFiles[path] = &SkippedFile{Path: path, Reason: SkipBinary}
+			continue
+		}
+
+		result.addFile(path, diffHunks(path, string(oldContent), string(newContent)), !inOld)
+	}
+
+	return result, nil
+}
+
+// walkTree lists the files of a tree by slash-separated path relative to its root
+func walkTree(root string) (map[string]treeFile, error) {
+	files := make(map[string]treeFile)
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != root && isVCSDir(entry.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		var mode string
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			mode = "120000"
+		case info.Mode().IsRegular() && info.Mode()&0o111 != 0:
+			mode = "100755"
+		case info.Mode().IsRegular():
+			mode = "100644"
+		default:
+			// Sockets, devices and other special files have no content to compare
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = treeFile{path: path, mode: mode}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", root, err)
+	}
+	return files, nil
+}
+
+// isVCSDir returns true for version control metadata directories
+func isVCSDir(name string) bool {
+	return name == ".git" || name == ".hg" || name == ".svn"
+}
+
+// readTreeFile returns the content of a file, or the target of a symlink as git stores it
+func readTreeFile(file treeFile) ([]byte, error) {
+	if file.mode == "120000" {
+		target, err := os.Readlink(file.path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read link %s: %w", file.path, err)
+		}
+		return []byte(target), nil
+	}
+	content, err := os.ReadFile(file.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file.path, err)
+	}
+	return content, nil
+}
+
+// isBinary returns true if the content has a NUL byte near the start, git's binary heuristic
+func isBinary(content []byte) bool {
+	if len(content) > binaryCheckSize {
+		content = content[:binaryCheckSize]
+	}
+	return bytes.IndexByte(content, 0) != -1
+}
+
+// sortedPaths returns the union of the paths of both trees in sorted order
+func sortedPaths(oldFiles, newFiles map[string]treeFile) []string {
+	paths := make([]string, 0, len(newFiles))
+	for path := range newFiles {
+		paths = append(paths, path)
+	}
+	for path := range oldFiles {
+		if _, ok := newFiles[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,101 @@
+package hunk
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"testing"
+)
+
+func TestDiffTrees(t *testing.T) {
+	oldDir := t.TempDir()
+	newDir := t.TempDir()
+
+	writeTree(t, oldDir, map[string]string{
+		"main.go":          "package main\n\nfunc main() {\n\tprintln(\"a\")\n\tprintln(\"b\")\n\tprintln(\"c\")\n\tprintln(\"old\")\n}\n",
+		"pkg/same.go":      "package pkg\n",
+		"pkg/removed.go":   "package pkg\n\nvar x = 1\n",
+		"assets/logo.png":  "\x89PNG\x00\x01",
+		"scripts/run.sh":   "#!/bin/sh\n",
+		".git/config":      "[core]\n",
+		"pkg/unchanged.go": "package pkg\n\nvar y = 2\n",
+	})
+	writeTree(t, newDir, map[string]string{
+		"main.go":          "package main\n\nfunc main() {\n\tprintln(\"a\")\n\tprintln(\"b\")\n\tprintln(\"c\")\n\tprintln(\"new\")\n\tprintln(\"more\")\n}\n",
+		"pkg/same.go":      "package pkg\n",
+		"pkg/added.go":     "package pkg\n\nvar z = 3\n",
+		"assets/logo.png":  "\x89PNG\x00\x02",
+		"scripts/run.sh":   "#!/bin/sh\n",
+		".git/config":      "[core]\n\tbare = false\n",
+		"pkg/unchanged.go": "package pkg\n\nvar y = 2\n",
+	})
+	if runtime.GOOS != "windows" {
+		if err := os.Chmod(filepath.Join(newDir, "scripts/run.sh"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := DiffTrees(oldDir, newDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	files := result.GetChangedFiles()
+	sort.Strings(files)
+	if !reflect.DeepEqual(files, []string{"main.go", "pkg/added.go"}) {
+		t.Fatalf("expected main.go and pkg/added.go to change, got %v", files)
+	}
+	if !result.IsModifiedFile("main.go") || !result.IsNewFile("pkg/added.go") {
+		t.Error("expected main.go to be modified and pkg/added.go to be new")
+	}
+	if !result.IsDeletedFile("pkg/removed.go") {
+		t.Error("expected pkg/removed.go to be deleted")
+	}
+	if got := result.GetAddedLinesForFile("main.go"); !reflect.DeepEqual(got, map[int]bool{7: true, 8: true}) {
+		t.Errorf("expected lines 7-8 added to main.go, got %v", got)
+	}
+	if got := result.GetRemovedLinesForFile("main.go"); !reflect.DeepEqual(got, map[int]bool{7: true}) {
+		t.Errorf("expected line 7 removed from main.go, got %v", got)
+	}
+	if old, ok := result.OldLineNumber("main.go", 7); !ok || old != 7 {
+		t.Errorf("expected new line 7 to replace old line 7, got %d, %v", old, ok)
+	}
+	if hunks := result.GetHunksForFile("main.go"); len(hunks) != 1 || hunks[0].Section != "func main() {" {
+		t.Errorf("expected one hunk in func main, got %+v", hunks)
+	}
+
+	if skip := result.SkippedFiles["assets/logo.png"]; skip == nil || skip.Reason != SkipBinary {
+		t.Errorf("expected the logo to be skipped as binary, got %+v", skip)
+	}
+	if runtime.GOOS != "windows" {
+		skip := result.SkippedFiles["scripts/run.sh"]
+		if skip == nil || skip.Reason != SkipModeChange || skip.Detail != "100644 → 100755" {
+			t.Errorf("expected run.sh to be a mode change, got %+v", skip)
+		}
+	}
+	if _, ok := result.ChangedLines[".git/config"]; ok {
+		t.Error("expected .git to be ignored")
+	}
+}
+
+func TestDiffTrees_MissingDir(t *testing.T) {
+	if _, err := DiffTrees(filepath.Join(t.TempDir(), "missing"), t.TempDir()); err == nil {
+		t.Error("expected an error for a missing tree")
+	}
+}
+
+// writeTree creates files under root from a map of slash-separated path -> content
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		full := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
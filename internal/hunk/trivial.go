@@ -0,0 +1,257 @@
+package hunk
+
+import (
+	"bytes"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ExcludedLines counts the added lines of a file that ExcludeTrivialChanges dropped
+type ExcludedLines struct {
+	// Blank lines hold only whitespace
+	Blank int
+	// Comment lines hold only comments, per the comment syntax of the file's language
+	Comment int
+	// Formatting lines belong to Go changes that only reformat code, such as a gofmt run
+	Formatting int
+}
+
+// Total returns the number of excluded lines
+func (e *ExcludedLines) Total() int {
+	return e.Blank + e.Comment + e.Formatting
+}
+
+// Add adds the counts of other to e
+func (e *ExcludedLines) Add(other *ExcludedLines) {
+	e.Blank += other.Blank
+	e.Comment += other.Comment
+	e.Formatting += other.Formatting
+}
+
+// ExcludeTrivialChanges drops added lines that cannot hold executable code from
+// ChangedLines and AddedLines and counts them in ExcludedLines: blank lines,
+// comment-only lines, and in Go files the lines of hunks that only reformat code.
+//
+// root is the directory holding the new versions of the changed files, such as
+// the repository checkout. The new version is used to track block comments across
+// the whole file and, for Go files, to rebuild the old version by reverting the
+// hunks. A file that is missing or does not match the diff is classified from the
+// hunk lines alone, and its formatting changes are kept. Files whose lines are all
+// dropped are removed from ChangedLines and AddedLines; their hunks are kept.
+func (r *ParseResult) ExcludeTrivialChanges(root string) {
+	if r.ExcludedLines == nil {
+		r.ExcludedLines = make(map[string]*ExcludedLines)
+	}
+
+	for file, changed := range r.ChangedLines {
+		hunks := r.Hunks[file]
+		newLines := readNewVersion(root, file, hunks)
+
+		excluded := &ExcludedLines{}
+		drop := func(line int, count *int) {
+			if !changed[line] {
+				return
+			}
+			delete(changed, line)
+			delete(r.AddedLines[file], line)
+			*count++
+		}
+
+		for line, comment := range classifyAddedLines(file, hunks, newLines) {
+			if comment {
+				drop(line, &excluded.Comment)
+			} else {
+				drop(line, &excluded.Blank)
+			}
+		}
+		if newLines != nil && isGoFile(file) {
+			for _, line := range formattingOnlyLines(hunks, newLines) {
+				drop(line, &excluded.Formatting)
+			}
+		}
+
+		if excluded.Total() == 0 {
+			continue
+		}
+		r.ExcludedLines[file] = excluded
+		if len(changed) == 0 {
+			delete(r.ChangedLines, file)
+			delete(r.AddedLines, file)
+		}
+	}
+}
+
+// GetExcludedLines returns the total number of lines dropped by ExcludeTrivialChanges
+func (r *ParseResult) GetExcludedLines() *ExcludedLines {
+	total := &ExcludedLines{}
+	for _, excluded := range r.ExcludedLines {
+		total.Add(excluded)
+	}
+	return total
+}
+
+// readNewVersion reads the new version of a file below root as lines without line
+// endings. It returns nil if the file cannot be read, if the hunks are from a
+// combined diff, or if the file does not contain the lines the hunks say it does.
+func readNewVersion(root, file string, hunks []*Hunk) []string {
+	if root == "" {
+		return nil
+	}
+	content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(file)))
+	if err != nil {
+		return nil
+	}
+	lines := splitLines(string(content))
+	for i, line := range lines {
+		lines[i] = lineContent(line)
+	}
+
+	for _, h := range hunks {
+		if h.Parents != nil {
+			return nil
+		}
+		for _, line := range h.Lines {
+			if line.NewLine == 0 {
+				continue
+			}
+			if line.NewLine > len(lines) || lines[line.NewLine-1] != strings.TrimSuffix(line.Content, "\r") {
+				return nil
+			}
+		}
+	}
+	return lines
+}
+
+// classifyAddedLines finds the blank and comment-only added lines of a file. The
+// result maps line number -> true for comment lines and false for blank lines.
+// Block comments are tracked through the whole new version when it is known, and
+// otherwise through the new-side lines of each hunk.
+func classifyAddedLines(file string, hunks []*Hunk, newLines []string) map[int]bool {
+	syntax := commentSyntaxFor(file)
+	added := make(map[int]bool)
+	for _, h := range hunks {
+		for _, line := range h.AddedLines() {
+			added[line] = true
+		}
+	}
+
+	trivial := make(map[int]bool)
+	classify := func(number int, content string, inBlock bool) bool {
+		if strings.TrimSpace(content) == "" {
+			if added[number] {
+				trivial[number] = false
+			}
+			return inBlock
+		}
+		if syntax == nil {
+			return false
+		}
+		commentOnly, nextInBlock := syntax.classify(content, inBlock)
+		if commentOnly && added[number] {
+			trivial[number] = true
+		}
+		return nextInBlock
+	}
+
+	if newLines != nil {
+		inBlock := false
+		for i, content := range newLines {
+			inBlock = classify(i+1, content, inBlock)
+		}
+		return trivial
+	}
+
+	for _, h := range hunks {
+		inBlock := false
+		for _, line := range h.Lines {
+			if line.NewLine != 0 {
+				inBlock = classify(line.NewLine, line.Content, inBlock)
+			}
+		}
+	}
+	return trivial
+}
+
+// formattingOnlyLines returns the added lines of the Go hunks that only change
+// formatting: reverting such a hunk leaves the file's code as go/printer prints
+// it unchanged. Comments are not compared; comment-only lines are handled separately.
+// If the whole file prints the same before and after, every added line qualifies,
+// which covers reformatting that spans several hunks.
+func formattingOnlyLines(hunks []*Hunk, newLines []string) []int {
+	newCode, ok := printGoCode(newLines)
+	if !ok {
+		return nil
+	}
+
+	var lines []int
+	if oldCode, ok := printGoCode(revertHunks(newLines, hunks)); ok && oldCode == newCode {
+		for _, h := range hunks {
+			lines = append(lines, h.AddedLines()...)
+		}
+		return lines
+	}
+
+	for _, h := range hunks {
+		if len(h.AddedLines()) == 0 {
+			continue
+		}
+		if code, ok := printGoCode(revertHunks(newLines, []*Hunk{h})); ok && code == newCode {
+			lines = append(lines, h.AddedLines()...)
+		}
+	}
+	return lines
+}
+
+// revertHunks rebuilds a version of a file from its new lines with the given
+// hunks undone: their added lines are removed and their removed lines restored
+func revertHunks(newLines []string, hunks []*Hunk) []string {
+	lines := make([]string, 0, len(newLines))
+	next := 1 // Next new line number to copy
+	for _, h := range hunks {
+		start := h.NewStart
+		if h.NewCount == 0 {
+			// An empty new side starts at the line before the hunk
+			start++
+		}
+		for ; next < start && next <= len(newLines); next++ {
+			lines = append(lines, newLines[next-1])
+		}
+		for _, line := range h.Lines {
+			if line.Kind != LineAdded {
+				lines = append(lines, strings.TrimSuffix(line.Content, "\r"))
+			}
+		}
+		next = start + h.NewCount
+	}
+	for ; next <= len(newLines); next++ {
+		lines = append(lines, newLines[next-1])
+	}
+	return lines
+}
+
+// printGoCode parses Go source without its comments and prints it as gofmt
+// would. It returns false if the source does not parse.
+func printGoCode(lines []string) (string, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", strings.Join(lines, "\n")+"\n", parser.SkipObjectResolution)
+	if err != nil {
+		return "", false
+	}
+
+	var buf bytes.Buffer
+	config := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+	if err := config.Fprint(&buf, fset, file); err != nil {
+		return "", false
+	}
+	return buf.String(), true
+}
+
+// isGoFile returns true if the path names a Go source file
+func isGoFile(file string) bool {
+	return path.Ext(file) == ".go"
+}
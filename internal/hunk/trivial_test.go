@@ -0,0 +1,206 @@
+package hunk
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExcludeTrivialChanges_BlankAndComments(t *testing.T) {
+	diff := `diff --git a/app.py b/app.py
+index 1111111..2222222 100644
+--- a/app.py
++++ b/app.py
+@@ -1,2 +1,6 @@
+ def run():
++    # explain the call
++
++    value = compute()  # inline comments keep the line
+     return 1
++
+diff --git a/lib.js b/lib.js
+index 3333333..4444444 100644
+--- a/lib.js
++++ b/lib.js
+@@ -1,1 +1,5 @@
++/*
++ * Block comment
++ */
++let x = 1; /* trailing */
+ export default x;
+diff --git a/notes.unknown b/notes.unknown
+index 5555555..6666666 100644
+--- a/notes.unknown
++++ b/notes.unknown
+@@ -1,1 +1,3 @@
+ a
++# not a known comment syntax
++
+`
+	result, err := ParseGitDiff(diff)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result.ExcludeTrivialChanges("")
+
+	if got := result.GetChangedLinesForFile("app.py"); !reflect.DeepEqual(got, map[int]bool{4: true}) {
+		t.Errorf("expected only line 4 of app.py to remain, got %v", got)
+	}
+	if got := result.ExcludedLines["app.py"]; got == nil || *got != (ExcludedLines{Blank: 2, Comment: 1}) {
+		t.Errorf("expected 2 blank and 1 comment line excluded from app.py, got %+v", got)
+	}
+	if got := result.GetChangedLinesForFile("lib.js"); !reflect.DeepEqual(got, map[int]bool{4: true}) {
+		t.Errorf("expected only line 4 of lib.js to remain, got %v", got)
+	}
+	if got := result.ExcludedLines["lib.js"]; got == nil || got.Comment != 3 {
+		t.Errorf("expected the block comment excluded from lib.js, got %+v", got)
+	}
+	if got := result.GetChangedLinesForFile("notes.unknown"); !reflect.DeepEqual(got, map[int]bool{2: true}) {
+		t.Errorf("expected only the blank line of notes.unknown to be dropped, got %v", got)
+	}
+	if got := result.GetExcludedLines(); *got != (ExcludedLines{Blank: 3, Comment: 4}) {
+		t.Errorf("unexpected totals %+v", got)
+	}
+}
+
+func TestExcludeTrivialChanges_CommentDelimitersInStrings(t *testing.T) {
+	diff := `diff --git a/strip.go b/strip.go
+new file mode 100644
+--- /dev/null
++++ b/strip.go
+@@ -0,0 +1,8 @@
++func isComment(s string) bool {
++	if strings.HasPrefix(s, "/*") || strings.HasPrefix(s, "//") {
++		return true
++	}
++	// Not a comment
++	return false
++}
++
+`
+	result, err := ParseGitDiff(diff)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result.ExcludeTrivialChanges("")
+
+	expected := map[int]bool{1: true, 2: true, 3: true, 4: true, 6: true, 7: true}
+	if got := result.GetChangedLinesForFile("strip.go"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected only the comment and blank lines to be dropped, got %v", got)
+	}
+	if got := result.ExcludedLines["strip.go"]; got == nil || *got != (ExcludedLines{Blank: 1, Comment: 1}) {
+		t.Errorf("expected 1 blank and 1 comment line excluded, got %+v", got)
+	}
+}
+
+func TestExcludeTrivialChanges_GoFormatting(t *testing.T) {
+	oldDir := t.TempDir()
+	newDir := t.TempDir()
+	writeTree(t, oldDir, map[string]string{
+		"fmt.go": "package main\n\nfunc a() int {\n  return 1+2\n}\n",
+		"mixed.go": "package main\n\nfunc a() int {\n  return 1+2\n}\n\n" +
+			"func b() int {\n\treturn 3\n}\n\nfunc c() int {\n\treturn 4\n}\n\nfunc d() int {\n\treturn 5\n}\n",
+	})
+	writeTree(t, newDir, map[string]string{
+		"fmt.go": "package main\n\n// a adds\nfunc a() int {\n\treturn 1 + 2\n}\n",
+		"mixed.go": "package main\n\nfunc a() int {\n\treturn 1 + 2\n}\n\n" +
+			"func b() int {\n\treturn 3\n}\n\nfunc c() int {\n\treturn 4\n}\n\nfunc d() int {\n\treturn 6\n}\n",
+	})
+
+	result, err := DiffTrees(oldDir, newDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result.ExcludeTrivialChanges(newDir)
+
+	if _, ok := result.ChangedLines["fmt.go"]; ok {
+		t.Errorf("expected every line of fmt.go to be excluded, got %v", result.ChangedLines["fmt.go"])
+	}
+	if got := result.ExcludedLines["fmt.go"]; got == nil || *got != (ExcludedLines{Comment: 1, Formatting: 1}) {
+		t.Errorf("expected 1 comment and 1 formatting line excluded from fmt.go, got %+v", got)
+	}
+
+	// The reformatted return in a is dropped, the changed return in d is kept
+	if got := result.GetChangedLinesForFile("mixed.go"); !reflect.DeepEqual(got, map[int]bool{16: true}) {
+		t.Errorf("expected only line 16 of mixed.go to remain, got %v", got)
+	}
+	if got := result.ExcludedLines["mixed.go"]; got == nil || got.Formatting != 1 {
+		t.Errorf("expected 1 formatting line excluded from mixed.go, got %+v", got)
+	}
+}
+
+func TestExcludeTrivialChanges_StaleFile(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"main.go": "package main\n\nfunc other() {}\n"})
+
+	diff := `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ package main
+
+-func main() {  }
++func main() {}
+`
+	result, err := ParseGitDiff(diff)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result.ExcludeTrivialChanges(root)
+
+	// The file on disk does not match the diff, so formatting cannot be checked
+	if got := result.GetChangedLinesForFile("main.go"); !reflect.DeepEqual(got, map[int]bool{3: true}) {
+		t.Errorf("expected line 3 to be kept, got %v", got)
+	}
+	if len(result.ExcludedLines) != 0 {
+		t.Errorf("expected nothing excluded, got %v", result.ExcludedLines)
+	}
+}
+
+func TestCommentSyntaxClassify(t *testing.T) {
+	tests := []struct {
+		file        string
+		line        string
+		inBlock     bool
+		commentOnly bool
+		nextInBlock bool
+	}{
+		{"a.go", "// comment", false, true, false},
+		{"a.go", "x := 1 // comment", false, false, false},
+		{"a.go", "/* start", false, true, true},
+		{"a.go", "still inside", true, true, true},
+		{"a.go", "end */ x := 1", true, false, false},
+		{"a.go", "/* a */ /* b */", false, true, false},
+		{"a.go", "x := 1 /* open", false, false, true},
+		{"a.py", "# comment", false, true, false},
+		{"a.sql", "-- comment", false, true, false},
+		{"a.lua", "--[[ block", false, true, true},
+		{"a.html", "<!-- note -->", false, true, false},
+		{"a.html", "<p>text</p>", false, false, false},
+		{"Makefile", "# target docs", false, true, false},
+		// Comment delimiters inside literals
+		{"a.go", `if strings.HasPrefix(s, "/*") {`, false, false, false},
+		{"a.go", `url := "https://example.com" // docs`, false, false, false},
+		{"a.go", `"//" + x`, false, false, false},
+		{"a.go", `s := "say \"/*\" " /* open`, false, false, true},
+		{"a.go", "re := `\\` + \"/*\"", false, false, false},
+		{"a.go", `c := '"' /* open`, false, false, true},
+		{"a.go", `s := "unterminated /*`, false, false, false},
+		{"a.py", `print("# not a comment")`, false, false, false},
+		{"a.sql", `SELECT '--' FROM t /* open`, false, false, true},
+	}
+
+	for _, tt := range tests {
+		syntax := commentSyntaxFor(tt.file)
+		if syntax == nil {
+			t.Fatalf("no comment syntax for %s", tt.file)
+		}
+		commentOnly, nextInBlock := syntax.classify(tt.line, tt.inBlock)
+		if commentOnly != tt.commentOnly || nextInBlock != tt.nextInBlock {
+			t.Errorf("%s %q (in block %v): got %v, %v; want %v, %v",
+				tt.file, tt.line, tt.inBlock, commentOnly, nextInBlock, tt.commentOnly, tt.nextInBlock)
+		}
+	}
+}
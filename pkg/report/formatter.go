@@ -3,35 +3,119 @@ package report
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/swantron/difftron/internal/analyzer"
+	"github.com/swantron/difftron/internal/hunk"
 )
 
 // AnalysisReport represents the JSON output structure for analyze command
 type AnalysisReport struct {
-	TotalChangedLines  int                    `json:"total_changed_lines"`
-	CoveredLines       int                    `json:"covered_lines"`
-	UncoveredLines     int                    `json:"uncovered_lines"`
-	CoveragePercentage float64                `json:"coverage_percentage"`
-	MeetsThreshold     bool                   `json:"meets_threshold"`
-	Threshold          float64                `json:"threshold,omitempty"`
-	Files              map[string]*FileReport `json:"files"`
-	NewFiles           *FileTypeReport        `json:"new_files,omitempty"`
-	ModifiedFiles      *FileTypeReport        `json:"modified_files,omitempty"`
+	TotalChangedLines     int                    `json:"total_changed_lines"`
+	CoveredLines          int                    `json:"covered_lines"`
+	UncoveredLines        int                    `json:"uncovered_lines"`
+	CoveragePercentage    float64                `json:"coverage_percentage"`
+	MeetsThreshold        bool                   `json:"meets_threshold"`
+	Threshold             float64                `json:"threshold,omitempty"`
+	Files                 map[string]*FileReport `json:"files"`
+	TotalBranches         int                    `json:"total_branches,omitempty"`
+	CoveredBranches       int                    `json:"covered_branches,omitempty"`
+	BranchCoverage        float64                `json:"branch_coverage_percentage,omitempty"`
+	PartiallyCoveredLines int                    `json:"partially_covered_lines,omitempty"`
+	IgnoredLines          int                    `json:"ignored_lines,omitempty"`
+	ChangedFunctions      int                    `json:"changed_functions,omitempty"`
+	// UnexecutedFunctions lists the changed functions the tests never entered
+	UnexecutedFunctions []*FunctionReport    `json:"unexecuted_functions,omitempty"`
+	NewFiles            *FileTypeReport      `json:"new_files,omitempty"`
+	ModifiedFiles       *FileTypeReport      `json:"modified_files,omitempty"`
+	Skipped             []*SkippedFileReport `json:"skipped,omitempty"`
+	Patches             []*PatchReport       `json:"patches,omitempty"`
+	Excluded            *ExcludedLinesReport `json:"excluded,omitempty"`
 }
 
 // FileReport represents file-level analysis results
 type FileReport struct {
-	FilePath             string  `json:"file_path"`
+	FilePath                    string        `json:"file_path"`
+	CoveragePercentage          float64       `json:"coverage_percentage"`
+	CoveredLines                int           `json:"covered_lines"`
+	UncoveredLines              int           `json:"uncovered_lines"`
+	TotalChangedLines           int           `json:"total_changed_lines"`
+	UncoveredLineNumbers        []int         `json:"uncovered_line_numbers"`
+	CoveredLineNumbers          []int         `json:"covered_line_numbers,omitempty"`
+	IsNewFile                   bool          `json:"is_new_file"`
+	BaselineCoverage            *float64      `json:"baseline_coverage,omitempty"`
+	Hunks                       []*HunkReport `json:"hunks,omitempty"`
+	TotalBranches               int           `json:"total_branches,omitempty"`
+	CoveredBranches             int           `json:"covered_branches,omitempty"`
+	BranchCoverage              float64       `json:"branch_coverage_percentage,omitempty"`
+	PartiallyCoveredLineNumbers []int         `json:"partially_covered_line_numbers,omitempty"`
+	PartiallyCoveredLines       int           `json:"partially_covered_lines"`
+	IgnoredLineNumbers          []int         `json:"ignored_line_numbers,omitempty"`
+}
+
+// HunkReport represents hunk-level analysis results
+type HunkReport struct {
+	OldStart             int     `json:"old_start"`
+	OldCount             int     `json:"old_count"`
+	NewStart             int     `json:"new_start"`
+	NewCount             int     `json:"new_count"`
+	Section              string  `json:"section,omitempty"`
 	CoveragePercentage   float64 `json:"coverage_percentage"`
 	CoveredLines         int     `json:"covered_lines"`
 	UncoveredLines       int     `json:"uncovered_lines"`
+	PartiallyCovered     int     `json:"partially_covered_lines"`
 	TotalChangedLines    int     `json:"total_changed_lines"`
-	UncoveredLineNumbers []int   `json:"uncovered_line_numbers"`
-	CoveredLineNumbers   []int   `json:"covered_line_numbers,omitempty"`
-	IsNewFile            bool    `json:"is_new_file"`
-	BaselineCoverage     float64 `json:"baseline_coverage,omitempty"`
+	UncoveredLineNumbers []int   `json:"uncovered_line_numbers,omitempty"`
+	Untested             bool    `json:"untested"`
+}
+
+// FunctionReport represents a changed function
+type FunctionReport struct {
+	FilePath     string `json:"file_path"`
+	Name         string `json:"name"`
+	StartLine    int    `json:"start_line"`
+	EndLine      int    `json:"end_line"`
+	Hits         int    `json:"hits"`
+	ChangedLines int    `json:"changed_lines"`
+}
+
+// SkippedFileReport represents a changed file that was not evaluated
+type SkippedFileReport struct {
+	FilePath string `json:"file_path"`
+	Reason   string `json:"reason"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// PatchReport represents the lines one patch of a series introduced
+type PatchReport struct {
+	Index                int              `json:"index"`
+	Commit               string           `json:"commit,omitempty"`
+	Subject              string           `json:"subject"`
+	CoveragePercentage   float64          `json:"coverage_percentage"`
+	CoveredLines         int              `json:"covered_lines"`
+	UncoveredLines       int              `json:"uncovered_lines"`
+	TotalChangedLines    int              `json:"total_changed_lines"`
+	UncoveredLineNumbers map[string][]int `json:"uncovered_line_numbers,omitempty"`
+}
+
+// ExcludedLinesReport represents the trivial lines left out of the analysis
+type ExcludedLinesReport struct {
+	BlankLines      int                             `json:"blank_lines"`
+	CommentLines    int                             `json:"comment_lines"`
+	FormattingLines int                             `json:"formatting_lines"`
+	TotalLines      int                             `json:"total_lines"`
+	Files           map[string]*ExcludedLinesReport `json:"files,omitempty"`
+}
+
+// newExcludedLinesReport converts excluded line counts to their report form
+func newExcludedLinesReport(excluded *hunk.ExcludedLines) *ExcludedLinesReport {
+	return &ExcludedLinesReport{
+		BlankLines:      excluded.Blank,
+		CommentLines:    excluded.Comment,
+		FormattingLines: excluded.Formatting,
+		TotalLines:      excluded.Total(),
+	}
 }
 
 // FileTypeReport represents metrics for new or modified files
@@ -40,34 +124,70 @@ type FileTypeReport struct {
 	TotalChangedLines  int     `json:"total_changed_lines"`
 	CoveredLines       int     `json:"covered_lines"`
 	UncoveredLines     int     `json:"uncovered_lines"`
+	PartiallyCovered   int     `json:"partially_covered_lines"`
 	CoveragePercentage float64 `json:"coverage_percentage"`
 }
 
 // ToJSON converts an AnalysisResult to JSON format
 func ToJSON(result *analyzer.AnalysisResult, threshold float64) ([]byte, error) {
 	report := &AnalysisReport{
-		TotalChangedLines:  result.TotalChangedLines,
-		CoveredLines:       result.CoveredLines,
-		UncoveredLines:     result.UncoveredLines,
-		CoveragePercentage: result.CoveragePercentage,
-		MeetsThreshold:     result.MeetsThreshold(threshold),
-		Threshold:          threshold,
-		Files:              make(map[string]*FileReport),
+		TotalChangedLines:     result.TotalChangedLines,
+		CoveredLines:          result.CoveredLines,
+		UncoveredLines:        result.UncoveredLines,
+		CoveragePercentage:    result.CoveragePercentage,
+		MeetsThreshold:        result.MeetsThreshold(threshold),
+		Threshold:             threshold,
+		Files:                 make(map[string]*FileReport),
+		TotalBranches:         result.TotalBranches,
+		CoveredBranches:       result.CoveredBranches,
+		BranchCoverage:        result.BranchCoveragePercentage,
+		PartiallyCoveredLines: result.PartiallyCoveredLines,
+		IgnoredLines:          result.IgnoredLines,
+		ChangedFunctions:      result.ChangedFunctions,
 	}
 
 	// Convert file results
 	for filePath, fileResult := range result.FileResults {
-		report.Files[filePath] = &FileReport{
-			FilePath:             fileResult.FilePath,
-			CoveragePercentage:   fileResult.CoveragePercentage,
-			CoveredLines:         fileResult.CoveredLines,
-			UncoveredLines:       fileResult.UncoveredLines,
-			TotalChangedLines:    fileResult.TotalChangedLines,
-			UncoveredLineNumbers: fileResult.UncoveredLineNumbers,
-			CoveredLineNumbers:   fileResult.CoveredLineNumbers,
-			IsNewFile:            fileResult.IsNewFile,
-			BaselineCoverage:     fileResult.BaselineCoveragePercentage,
+		hunks := make([]*HunkReport, 0, len(fileResult.Hunks))
+		for _, h := range fileResult.Hunks {
+			hunks = append(hunks, &HunkReport{
+				OldStart:             h.OldStart,
+				OldCount:             h.OldCount,
+				NewStart:             h.NewStart,
+				NewCount:             h.NewCount,
+				Section:              h.Section,
+				CoveragePercentage:   h.CoveragePercentage,
+				CoveredLines:         h.CoveredLines,
+				UncoveredLines:       h.UncoveredLines,
+				PartiallyCovered:     h.PartiallyCoveredLines,
+				TotalChangedLines:    h.TotalChangedLines,
+				UncoveredLineNumbers: h.UncoveredLineNumbers,
+				Untested:             h.IsUntested(),
+			})
 		}
+
+		fileReport := &FileReport{
+			FilePath:                    fileResult.FilePath,
+			CoveragePercentage:          fileResult.CoveragePercentage,
+			CoveredLines:                fileResult.CoveredLines,
+			UncoveredLines:              fileResult.UncoveredLines,
+			TotalChangedLines:           fileResult.TotalChangedLines,
+			UncoveredLineNumbers:        fileResult.UncoveredLineNumbers,
+			CoveredLineNumbers:          fileResult.CoveredLineNumbers,
+			IsNewFile:                   fileResult.IsNewFile,
+			Hunks:                       hunks,
+			TotalBranches:               fileResult.TotalBranches,
+			CoveredBranches:             fileResult.CoveredBranches,
+			BranchCoverage:              fileResult.BranchCoveragePercentage,
+			PartiallyCoveredLineNumbers: fileResult.PartiallyCoveredLineNumbers,
+			PartiallyCoveredLines:       fileResult.PartiallyCoveredLines,
+			IgnoredLineNumbers:          fileResult.IgnoredLineNumbers,
+		}
+		if fileResult.HasBaseline {
+			baseline := fileResult.BaselineCoveragePercentage
+			fileReport.BaselineCoverage = &baseline
+		}
+		report.Files[filePath] = fileReport
 	}
 
 	// Add new/modified file metrics if available
@@ -77,6 +197,7 @@ func ToJSON(result *analyzer.AnalysisResult, threshold float64) ([]byte, error)
 			TotalChangedLines:  result.NewFileMetrics.TotalChangedLines,
 			CoveredLines:       result.NewFileMetrics.CoveredLines,
 			UncoveredLines:     result.NewFileMetrics.UncoveredLines,
+			PartiallyCovered:   result.NewFileMetrics.PartiallyCoveredLines,
 			CoveragePercentage: result.NewFileMetrics.CoveragePercentage,
 		}
 	}
@@ -87,10 +208,51 @@ func ToJSON(result *analyzer.AnalysisResult, threshold float64) ([]byte, error)
 			TotalChangedLines:  result.ModifiedFileMetrics.TotalChangedLines,
 			CoveredLines:       result.ModifiedFileMetrics.CoveredLines,
 			UncoveredLines:     result.ModifiedFileMetrics.UncoveredLines,
+			PartiallyCovered:   result.ModifiedFileMetrics.PartiallyCoveredLines,
 			CoveragePercentage: result.ModifiedFileMetrics.CoveragePercentage,
 		}
 	}
 
+	for _, skipped := range result.SkippedFiles {
+		report.Skipped = append(report.Skipped, &SkippedFileReport{
+			FilePath: skipped.Path,
+			Reason:   string(skipped.Reason),
+			Detail:   skipped.Detail,
+		})
+	}
+
+	for _, patch := range result.Patches {
+		report.Patches = append(report.Patches, &PatchReport{
+			Index:                patch.Index,
+			Commit:               patch.Commit,
+			Subject:              patch.Subject,
+			CoveragePercentage:   patch.CoveragePercentage,
+			CoveredLines:         patch.CoveredLines,
+			UncoveredLines:       patch.UncoveredLines,
+			TotalChangedLines:    patch.TotalChangedLines,
+			UncoveredLineNumbers: patch.UncoveredLineNumbers,
+		})
+	}
+
+	for _, function := range result.UnexecutedFunctions() {
+		report.UnexecutedFunctions = append(report.UnexecutedFunctions, &FunctionReport{
+			FilePath:     function.FilePath,
+			Name:         function.Name,
+			StartLine:    function.StartLine,
+			EndLine:      function.EndLine,
+			Hits:         function.Hits,
+			ChangedLines: function.ChangedLines,
+		})
+	}
+
+	if len(result.ExcludedLines) > 0 {
+		report.Excluded = newExcludedLinesReport(result.TotalExcludedLines())
+		report.Excluded.Files = make(map[string]*ExcludedLinesReport)
+		for filePath, excluded := range result.ExcludedLines {
+			report.Excluded.Files[filePath] = newExcludedLinesReport(excluded)
+		}
+	}
+
 	return json.MarshalIndent(report, "", "  ")
 }
 
@@ -106,6 +268,20 @@ func ToMarkdown(result *analyzer.AnalysisResult, threshold float64) string {
 	sb.WriteString(fmt.Sprintf("- **Changed Lines**: %d\n", result.TotalChangedLines))
 	sb.WriteString(fmt.Sprintf("- **Covered Lines**: %d\n", result.CoveredLines))
 	sb.WriteString(fmt.Sprintf("- **Uncovered Lines**: %d\n", result.UncoveredLines))
+	if result.PartiallyCoveredLines > 0 {
+		sb.WriteString(fmt.Sprintf("- **Partly Covered Lines**: %d (ran without taking all branches)\n", result.PartiallyCoveredLines))
+	}
+	if result.IgnoredLines > 0 {
+		sb.WriteString(fmt.Sprintf("- **Ignored Lines**: %d (excluded from coverage measurement)\n", result.IgnoredLines))
+	}
+	if result.TotalBranches > 0 {
+		sb.WriteString(fmt.Sprintf("- **Branch Coverage**: %.1f%% (%d/%d branches taken)\n",
+			result.BranchCoveragePercentage, result.CoveredBranches, result.TotalBranches))
+	}
+	if result.ChangedFunctions > 0 {
+		sb.WriteString(fmt.Sprintf("- **Changed Functions Never Executed**: %d of %d\n",
+			result.UnexecutedChangedFunctions, result.ChangedFunctions))
+	}
 	sb.WriteString(fmt.Sprintf("- **Threshold**: %.1f%%\n", threshold))
 
 	meetsThreshold := result.MeetsThreshold(threshold)
@@ -157,9 +333,86 @@ func ToMarkdown(result *analyzer.AnalysisResult, threshold float64) string {
 			if len(fileResult.UncoveredLineNumbers) > 0 {
 				sb.WriteString(fmt.Sprintf("  - Uncovered lines: %v\n", fileResult.UncoveredLineNumbers))
 			}
+			if len(fileResult.PartiallyCoveredLineNumbers) > 0 {
+				sb.WriteString(fmt.Sprintf("  - Partly covered lines: %v\n", fileResult.PartiallyCoveredLineNumbers))
+			}
+
+			// Summarize untested hunks per enclosing section
+			for _, summary := range fileResult.SectionSummaries() {
+				if summary.UntestedHunks > 0 {
+					sb.WriteString(fmt.Sprintf("  - %s\n", summary))
+				}
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	// Changed functions no test entered
+	if unexecuted := result.UnexecutedFunctions(); len(unexecuted) > 0 {
+		sb.WriteString("## Changed Functions Never Executed\n\n")
+		sb.WriteString("| File | Function | Lines | Changed Lines |\n")
+		sb.WriteString("|------|----------|-------|---------------|\n")
+		for _, function := range unexecuted {
+			sb.WriteString(fmt.Sprintf("| `%s` | `%s` | %d-%d | %d |\n",
+				function.FilePath, function.Name, function.StartLine, function.EndLine, function.ChangedLines))
+		}
+		sb.WriteString("\n")
+	}
+
+	// Which patch of a series introduced the changed lines
+	if len(result.Patches) > 0 {
+		sb.WriteString("## Patches\n\n")
+		sb.WriteString("| # | Patch | Coverage | Changed | Covered | Uncovered |\n")
+		sb.WriteString("|---|-------|----------|---------|---------|-----------|\n")
+		for _, patch := range result.Patches {
+			sb.WriteString(fmt.Sprintf("| %d | %s | %.1f%% | %d | %d | %d |\n",
+				patch.Index, patch.Subject, patch.CoveragePercentage,
+				patch.TotalChangedLines, patch.CoveredLines, patch.UncoveredLines))
+			for _, filePath := range sortedKeys(patch.UncoveredLineNumbers) {
+				sb.WriteString(fmt.Sprintf("  - `%s` uncovered lines: %v\n", filePath, patch.UncoveredLineNumbers[filePath]))
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	// Trivial lines left out of the numbers above
+	if len(result.ExcludedLines) > 0 {
+		total := result.TotalExcludedLines()
+		sb.WriteString("## Excluded Lines\n\n")
+		sb.WriteString(fmt.Sprintf("%d changed lines were left out as blank, comment-only or formatting-only:\n\n", total.Total()))
+		sb.WriteString("| File | Blank | Comment | Formatting |\n")
+		sb.WriteString("|------|-------|---------|------------|\n")
+		files := make([]string, 0, len(result.ExcludedLines))
+		for filePath := range result.ExcludedLines {
+			files = append(files, filePath)
+		}
+		sort.Strings(files)
+		for _, filePath := range files {
+			excluded := result.ExcludedLines[filePath]
+			sb.WriteString(fmt.Sprintf("| `%s` | %d | %d | %d |\n", filePath, excluded.Blank, excluded.Comment, excluded.Formatting))
+		}
+		sb.WriteString("\n")
+	}
+
+	// Files the gate could not evaluate
+	if len(result.SkippedFiles) > 0 {
+		sb.WriteString("## Skipped Files\n\n")
+		sb.WriteString("These files changed but have no lines to analyze:\n\n")
+		for _, skipped := range result.SkippedFiles {
+			sb.WriteString(fmt.Sprintf("- `%s`: %s\n", skipped.Path, skipped.Description()))
 		}
 		sb.WriteString("\n")
 	}
 
 	return sb.String()
 }
+
+// sortedKeys returns the keys of a map of line numbers in sorted order
+func sortedKeys(m map[string][]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}